@@ -0,0 +1,287 @@
+// Package cache stores GitHub API responses on disk, so that repeated runs
+// against the same pull request do not fetch them again. Entries are files
+// named by the SHA-256 of their key; the least recently used are removed
+// once the cache outgrows its size cap.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSize is the default size cap of a cache, in bytes.
+const DefaultMaxSize = 512 << 20
+
+// Cache is a directory of cached entries. A nil *Cache caches nothing.
+// Its methods are safe for use by several goroutines and processes at
+// once; failing to read or write an entry only loses the cached copy.
+type Cache struct {
+	dir     string
+	maxSize int64
+}
+
+// New returns a cache in dir, which is created when the first entry is
+// stored. Trim keeps it within maxSize bytes; zero means no limit.
+func New(dir string, maxSize int64) *Cache {
+	return &Cache{dir: dir, maxSize: maxSize}
+}
+
+// DefaultDir returns the directory of the user's cache: gh-pr-todo under
+// the user cache directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gh-pr-todo"), nil
+}
+
+// Dir returns the directory of c.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// path returns the file of the entry for key. Entries are spread over
+// subdirectories by the first byte of their hash.
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// Get returns the entry for key, marking it as recently used.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	touch(path)
+	return data, true
+}
+
+// Open returns a reader of the entry for key, marking it as recently used.
+func (c *Cache) Open(key string) (io.ReadCloser, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := c.path(key)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	touch(path)
+	return f, true
+}
+
+// Put stores data as the entry for key, replacing any previous entry.
+func (c *Cache) Put(key string, data []byte) {
+	w := c.Create(key)
+	w.Write(data)
+	w.Commit()
+}
+
+// Writer writes an entry to a cache. The entry is stored by Commit, and
+// not at all if Abort is called first. Writes never fail: a failure to
+// write the entry only keeps it from being stored. A nil *Writer discards
+// what is written to it.
+type Writer struct {
+	cache *Cache
+	key   string
+	file  *os.File
+	err   error
+}
+
+// Create returns a Writer of the entry for key. It returns nil if c is nil.
+func (c *Cache) Create(key string) *Writer {
+	if c == nil {
+		return nil
+	}
+	w := &Writer{cache: c, key: key}
+	w.file, w.err = c.create()
+	return w
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w != nil && w.err == nil {
+		_, w.err = w.file.Write(p)
+	}
+	return len(p), nil
+}
+
+// Commit stores the entry, replacing any previous entry for its key.
+func (w *Writer) Commit() {
+	if w != nil && w.file != nil {
+		w.cache.commit(w.file, w.key, w.err)
+		w.file = nil
+	}
+}
+
+// Abort discards the entry.
+func (w *Writer) Abort() {
+	if w != nil && w.err == nil {
+		w.err = errors.New("aborted")
+	}
+	w.Commit()
+}
+
+// Tee returns a reader of body that stores what it reads as the entry for
+// key once body has been read to the end and closed. An entry is not
+// stored if reading body fails or stops early.
+func (c *Cache) Tee(key string, body io.ReadCloser) io.ReadCloser {
+	if c == nil {
+		return body
+	}
+	return &tee{body: body, entry: c.Create(key)}
+}
+
+type tee struct {
+	body  io.ReadCloser
+	entry *Writer
+	eof   bool
+	err   error
+}
+
+func (t *tee) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	t.entry.Write(p[:n])
+	switch {
+	case err == io.EOF:
+		t.eof = true
+	case err != nil:
+		t.err = err
+	}
+	return n, err
+}
+
+func (t *tee) Close() error {
+	err := t.body.Close()
+	if t.eof && t.err == nil && err == nil {
+		t.entry.Commit()
+	} else {
+		t.entry.Abort()
+	}
+	return err
+}
+
+// create returns a new temporary file in c, to be passed to commit.
+func (c *Cache) create() (*os.File, error) {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(c.dir, "tmp-*")
+}
+
+// commit closes f, a file returned by create, and moves it into place as
+// the entry for key, unless writing it failed with err.
+func (c *Cache) commit(f *os.File, key string, err error) {
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		path := c.path(key)
+		if err = os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			err = os.Rename(f.Name(), path)
+		}
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+// touch marks the file at path as recently used.
+func touch(path string) {
+	t := time.Now()
+	_ = os.Chtimes(path, t, t)
+}
+
+// entry is a file in the cache directory.
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries returns the files in c, including temporary files left behind by
+// interrupted runs.
+func (c *Cache) entries() ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return entries, err
+}
+
+// Trim removes the least recently used entries until c is within its size
+// cap, along with temporary files more than an hour old.
+func (c *Cache) Trim() error {
+	if c == nil {
+		return nil
+	}
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return a.modTime.Compare(b.modTime) })
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	stale := time.Now().Add(-time.Hour)
+	for _, e := range entries {
+		tmp := strings.HasPrefix(filepath.Base(e.path), "tmp-")
+		if tmp && e.modTime.After(stale) {
+			continue
+		}
+		if !tmp && (c.maxSize == 0 || total <= c.maxSize) {
+			continue
+		}
+		if err := os.Remove(e.path); err == nil || errors.Is(err, fs.ErrNotExist) {
+			total -= e.size
+		}
+	}
+	return nil
+}
+
+// Clean removes every entry from c, returning the number of entries and
+// bytes removed.
+func (c *Cache) Clean() (int, int64, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return 0, 0, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	if err := os.RemoveAll(c.dir); err != nil {
+		return 0, 0, err
+	}
+	return len(entries), size, nil
+}
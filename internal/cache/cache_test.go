@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetPut(t *testing.T) {
+	c := New(t.TempDir(), 0)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("Get() found an entry in an empty cache")
+	}
+	c.Put("a", []byte("first"))
+	c.Put("a", []byte("second"))
+	c.Put("b", nil)
+	if got, ok := c.Get("a"); !ok || string(got) != "second" {
+		t.Errorf("Get(a) = %q, %v, expected the last entry stored", got, ok)
+	}
+	if got, ok := c.Get("b"); !ok || len(got) != 0 {
+		t.Errorf("Get(b) = %q, %v, expected an empty entry", got, ok)
+	}
+
+	var nilCache *Cache
+	nilCache.Put("a", []byte("x"))
+	if _, ok := nilCache.Get("a"); ok {
+		t.Error("nil cache returned an entry")
+	}
+}
+
+// errReader returns its data, then err.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err == io.EOF {
+		err = r.err
+	}
+	return n, err
+}
+
+func TestTee(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    io.Reader
+		readAll bool
+		want    bool
+	}{
+		{name: "read to the end", body: strings.NewReader("diff"), readAll: true, want: true},
+		{name: "closed early", body: strings.NewReader("diff"), readAll: false},
+		{name: "read error", body: &errReader{strings.NewReader("di"), errors.New("reset")}, readAll: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(t.TempDir(), 0)
+			body := c.Tee("k", io.NopCloser(tt.body))
+			if tt.readAll {
+				_, _ = io.ReadAll(body)
+			} else {
+				_, _ = body.Read(make([]byte, 2))
+			}
+			if err := body.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			r, ok := c.Open("k")
+			if ok != tt.want {
+				t.Fatalf("Open() found = %v, expected %v", ok, tt.want)
+			}
+			if ok {
+				defer r.Close()
+				if got, _ := io.ReadAll(r); string(got) != "diff" {
+					t.Errorf("entry = %q, expected %q", got, "diff")
+				}
+			}
+			entries, _ := c.entries()
+			if len(entries) != map[bool]int{true: 1}[tt.want] {
+				t.Errorf("cache holds %d files, expected no temporary files left", len(entries))
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	c := New(t.TempDir(), 10)
+	base := time.Now().Add(-2 * time.Hour)
+	for i, key := range []string{"old", "mid", "new"} {
+		c.Put(key, []byte("12345"))
+		at := base.Add(time.Duration(i) * time.Minute)
+		_ = os.Chtimes(c.path(key), at, at)
+	}
+	// Reading marks "old" as the most recently used.
+	c.Get("old")
+	stale, _ := os.CreateTemp(c.dir, "tmp-*")
+	stale.Close()
+	_ = os.Chtimes(stale.Name(), base, base)
+
+	if err := c.Trim(); err != nil {
+		t.Fatalf("Trim() error = %v", err)
+	}
+	for key, want := range map[string]bool{"old": true, "mid": false, "new": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("Get(%s) found = %v, expected %v", key, ok, want)
+		}
+	}
+	if _, err := os.Stat(stale.Name()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale temporary file was not removed: %v", err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	c := New(t.TempDir()+"/cache", 0)
+	if n, size, err := c.Clean(); n != 0 || size != 0 || err != nil {
+		t.Fatalf("Clean() of a missing cache = %d, %d, %v", n, size, err)
+	}
+	c.Put("a", []byte("123"))
+	c.Put("b", []byte("45"))
+	n, size, err := c.Clean()
+	if n != 2 || size != 5 || err != nil {
+		t.Fatalf("Clean() = %d, %d, %v, expected 2, 5, nil", n, size, err)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() found an entry after Clean()")
+	}
+}
@@ -0,0 +1,193 @@
+// Package charset converts source files in legacy encodings, such as
+// Shift_JIS, Latin-1, or UTF-16, to UTF-8 before they are parsed.
+package charset
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+
+	utf16LE = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	utf16BE = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+)
+
+// Mapping assigns an encoding to the files whose path matches Pattern, a glob
+// in path.Match syntax. A pattern without a slash matches the file name in
+// any directory.
+type Mapping struct {
+	Pattern  string
+	Encoding string
+}
+
+// Matches reports whether filename matches the mapping's pattern.
+func (m Mapping) Matches(filename string) bool {
+	target := filename
+	if !strings.Contains(m.Pattern, "/") {
+		target = path.Base(filename)
+	}
+	ok, err := path.Match(m.Pattern, target)
+	return err == nil && ok
+}
+
+// Lookup returns the encoding with the given WHATWG name or label, such as
+// "shift_jis", "latin1", or "utf-16be", ignoring case.
+func Lookup(name string) (encoding.Encoding, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "utf-16", "utf16", "utf-16le", "utf16le":
+		return utf16LE, true
+	case "utf-16be", "utf16be":
+		return utf16BE, true
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, false
+	}
+	return enc, true
+}
+
+// DecodeFiles returns a copy of files with every file converted to UTF-8 by
+// ToUTF8.
+func DecodeFiles(files map[string][]byte, mappings []Mapping) map[string][]byte {
+	decoded := make(map[string][]byte, len(files))
+	for name, content := range files {
+		decoded[name] = ToUTF8(name, content, mappings)
+	}
+	return decoded
+}
+
+// ToUTF8 converts content to UTF-8. A byte order mark decides the encoding
+// first, then the first mapping matching filename. Otherwise valid UTF-8 is
+// returned unchanged unless it looks like UTF-16, and anything else is
+// decoded as Shift_JIS or Windows-1252 (a superset of Latin-1), whichever is
+// more likely.
+func ToUTF8(filename string, content []byte, mappings []Mapping) []byte {
+	enc, bom := Detect(filename, content, mappings)
+	return Decode(enc, content[bom:])
+}
+
+// Detect returns the encoding ToUTF8 converts content from, nil if content
+// is valid UTF-8 already, and the length of the byte order mark content
+// starts with, if any.
+func Detect(filename string, content []byte, mappings []Mapping) (encoding.Encoding, int) {
+	switch {
+	case bytes.HasPrefix(content, utf8BOM):
+		return encoding.Nop, len(utf8BOM)
+	case bytes.HasPrefix(content, utf16LEBOM):
+		return utf16LE, len(utf16LEBOM)
+	case bytes.HasPrefix(content, utf16BEBOM):
+		return utf16BE, len(utf16BEBOM)
+	}
+
+	for _, m := range mappings {
+		if m.Matches(filename) {
+			if enc, ok := Lookup(m.Encoding); ok {
+				return enc, 0
+			}
+			break
+		}
+	}
+
+	// UTF-16 text is often valid UTF-8 too, since ASCII characters become
+	// a NUL byte and the character itself.
+	if enc := guessUTF16(content); enc != nil {
+		return enc, 0
+	}
+	if utf8.Valid(content) {
+		return nil, 0
+	}
+	return guess(content), 0
+}
+
+// IsUTF16 reports whether content is UTF-16 text, from its byte order mark
+// or, without one, the zero high bytes of ASCII characters. git takes such
+// files for binary, since they contain NUL bytes.
+func IsUTF16(content []byte) bool {
+	return bytes.HasPrefix(content, utf16LEBOM) || bytes.HasPrefix(content, utf16BEBOM) || guessUTF16(content) != nil
+}
+
+// guess returns the most likely encoding of content that is neither UTF-16
+// nor valid UTF-8.
+func guess(content []byte) encoding.Encoding {
+	if isLikelyShiftJIS(content) {
+		return japanese.ShiftJIS
+	}
+	return charmap.Windows1252
+}
+
+// guessUTF16 recognizes UTF-16 text without a byte order mark from the zero
+// high bytes of ASCII characters, returning nil if content does not look
+// like UTF-16.
+func guessUTF16(content []byte) encoding.Encoding {
+	sample := content[:min(len(content), 1024)&^1]
+	if len(sample) < 4 {
+		return nil
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenZeros++
+		}
+		if sample[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case oddZeros*3 > pairs && evenZeros*10 < pairs:
+		return utf16LE
+	case evenZeros*3 > pairs && oddZeros*10 < pairs:
+		return utf16BE
+	}
+	return nil
+}
+
+// isLikelyShiftJIS reports whether content decodes as Shift_JIS without
+// errors and reads as Japanese: it has hiragana or katakana, or two kanji
+// in a row. A single kanji is not enough, since a Latin-1 accented letter
+// and the ASCII letter after it, as in "g\xe9rer", form a valid Shift_JIS
+// character too; half-width katakana are left out for the same reason.
+func isLikelyShiftJIS(content []byte) bool {
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(content)
+	if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+		return false
+	}
+	prevKanji := false
+	for _, r := range string(decoded) {
+		if r >= 0x3040 && r <= 0x30FF {
+			return true
+		}
+		kanji := r >= 0x4E00 && r <= 0x9FFF
+		if kanji && prevKanji {
+			return true
+		}
+		prevKanji = kanji
+	}
+	return false
+}
+
+// Decode converts content from enc, as returned by Detect, to UTF-8,
+// replacing any bytes that are still invalid. A nil enc returns content
+// unchanged.
+func Decode(enc encoding.Encoding, content []byte) []byte {
+	if enc == nil {
+		return content
+	}
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		decoded = content
+	}
+	return bytes.ToValidUTF8(decoded, []byte(string(utf8.RuneError)))
+}
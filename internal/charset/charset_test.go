@@ -0,0 +1,125 @@
+package charset
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		mappings []Mapping
+		want     string
+	}{
+		{
+			name:    "UTF-8 unchanged",
+			content: []byte("// TODO: café\n"),
+			want:    "// TODO: café\n",
+		},
+		{
+			name:    "UTF-8 BOM stripped",
+			content: []byte("\xEF\xBB\xBF# TODO: bom\n"),
+			want:    "# TODO: bom\n",
+		},
+		{
+			name:    "UTF-16LE with BOM",
+			content: []byte("\xFF\xFE/\x00/\x00 \x00T\x00O\x00D\x00O\x00\n\x00"),
+			want:    "// TODO\n",
+		},
+		{
+			name:    "UTF-16BE with BOM",
+			content: []byte("\xFE\xFF\x00#\x00 \x00X\x00X\x00X\x00\n"),
+			want:    "# XXX\n",
+		},
+		{
+			name:    "UTF-16LE without BOM",
+			content: []byte("/\x00/\x00 \x00T\x00O\x00D\x00O\x00:\x00 \x00x\x00\n\x00"),
+			want:    "// TODO: x\n",
+		},
+		{
+			name:    "Shift_JIS detected",
+			content: []byte("// TODO: \x93\xfa\x96\x7b\x8c\xea\n"),
+			want:    "// TODO: 日本語\n",
+		},
+		{
+			name:    "Latin-1 detected",
+			content: []byte("# FIXME: caf\xe9 cr\xe8me\n"),
+			want:    "# FIXME: café crème\n",
+		},
+		{
+			name:    "Latin-1 letter before an ASCII letter",
+			content: []byte("// TODO: g\xe9rer le cas\n"),
+			want:    "// TODO: gérer le cas\n",
+		},
+		{
+			name:    "Latin-1 letters between ASCII letters",
+			content: []byte("// NOTE: r\xe9sum\xe9s\n"),
+			want:    "// NOTE: résumés\n",
+		},
+		{
+			name:    "Windows-1252 quote before an ASCII letter",
+			content: []byte("# TODO: don\x92t\n"),
+			want:    "# TODO: don’t\n",
+		},
+		{
+			name:     "configured encoding wins over detection",
+			filename: "legacy/old.c",
+			content:  []byte("/* NOTE: \x82\xa0 */\n"),
+			mappings: []Mapping{{Pattern: "*.h", Encoding: "shift_jis"}, {Pattern: "legacy/*.c", Encoding: "windows-1252"}},
+			want:     "/* NOTE: ‚\u00a0 */\n",
+		},
+		{
+			name:     "configured encoding for matching base name",
+			filename: "src/win/main.c",
+			content:  []byte("// TODO: \x82\xa0\n"),
+			mappings: []Mapping{{Pattern: "*.c", Encoding: "Shift_JIS"}},
+			want:     "// TODO: あ\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(ToUTF8(tt.filename, tt.content, tt.mappings))
+			if got != tt.want {
+				t.Errorf("ToUTF8() = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("ToUTF8() = %q is not valid UTF-8", got)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"shift_jis", "Shift-JIS", "sjis", "latin1", "ISO-8859-1", "windows-1252", "utf-16", "UTF-16BE", "euc-jp", "gbk"} {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("Lookup(%q) found no encoding", name)
+		}
+	}
+	if _, ok := Lookup("klingon"); ok {
+		t.Error("Lookup(klingon) found an encoding")
+	}
+}
+
+func TestIsUTF16(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{name: "LE with BOM", content: "\xFF\xFE/\x00/\x00", want: true},
+		{name: "BE with BOM", content: "\xFE\xFF\x00#", want: true},
+		{name: "LE without BOM", content: "/\x00/\x00 \x00T\x00O\x00D\x00O\x00\n\x00", want: true},
+		{name: "ASCII", content: "// TODO\n", want: false},
+		{name: "PNG", content: "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUTF16([]byte(tt.content)); got != tt.want {
+				t.Errorf("IsUTF16(%q) = %v, expected %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,12 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/Suree33/gh-pr-todo/internal/charset"
+	"github.com/Suree33/gh-pr-todo/internal/language"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 	"gopkg.in/yaml.v3"
 )
@@ -17,13 +20,77 @@ import (
 type Config struct {
 	Severities map[string]todotype.Severity
 	Ignored    map[string]bool
-	Found      bool // true if at least one config file was found and parsed
+	// MarkdownParagraphs reports plain "TODO:" lines in documentation files.
+	MarkdownParagraphs bool
+	// Docstrings reports "TODO:" lines in documentation strings.
+	Docstrings bool
+	// Languages assigns languages to files by path pattern, in file order.
+	Languages []language.Mapping
+	// Encodings assigns encodings to files by path pattern, in file order.
+	Encodings []charset.Mapping
+	Found     bool // true if at least one config file was found and parsed
 }
 
 // File represents the YAML configuration file schema.
 type File struct {
 	Severity map[string][]string `yaml:"severity"`
 	Ignore   []string            `yaml:"ignore"`
+	Markdown MarkdownFile        `yaml:"markdown"`
+	// Docstrings also searches documentation strings, such as Python
+	// docstrings, for "TODO:" lines.
+	Docstrings bool `yaml:"docstrings"`
+	// Languages maps path globs to language names, for example
+	// "Jenkinsfile: groovy". The first matching pattern wins.
+	Languages PatternMap `yaml:"languages"`
+	// Encodings maps path globs to the encodings of files that are not
+	// UTF-8, for example "legacy/*.cpp: shift_jis".
+	Encodings PatternMap `yaml:"encodings"`
+}
+
+// MarkdownFile represents the "markdown" section of the configuration file.
+type MarkdownFile struct {
+	Paragraphs bool `yaml:"paragraphs"`
+}
+
+// PatternMap is a configuration section mapping path globs to names, such
+// as "languages" or "encodings", with its entries in file order.
+type PatternMap []PatternEntry
+
+// PatternEntry is a single glob to name pair of a PatternMap.
+type PatternEntry struct {
+	Pattern string
+	Name    string
+}
+
+// UnmarshalYAML decodes a YAML mapping, keeping the order of its keys.
+func (m *PatternMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: must be a mapping of path patterns to names", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var e PatternEntry
+		if err := node.Content[i].Decode(&e.Pattern); err != nil {
+			return err
+		}
+		if err := node.Content[i+1].Decode(&e.Name); err != nil {
+			return err
+		}
+		*m = append(*m, e)
+	}
+	return nil
+}
+
+// validPattern returns the trimmed pattern of a PatternMap entry, or an error
+// naming the config section if it is empty or malformed.
+func validPattern(source, section, pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return "", fmt.Errorf("%s: path pattern is empty in %s", source, section)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", fmt.Errorf("%s: invalid path pattern %q in %s: %w", source, pattern, section, err)
+	}
+	return pattern, nil
 }
 
 // Parse parses YAML config data and validates severity values and ignore list.
@@ -34,7 +101,33 @@ func Parse(data []byte, source string) (Config, error) {
 		return Config{}, fmt.Errorf("%s: invalid YAML: %w", source, err)
 	}
 
-	cfg := Config{Found: true}
+	cfg := Config{Found: true, MarkdownParagraphs: f.Markdown.Paragraphs, Docstrings: f.Docstrings}
+
+	// Parse language mappings
+	for _, e := range f.Languages {
+		pattern, err := validPattern(source, "languages", e.Pattern)
+		if err != nil {
+			return Config{}, err
+		}
+		l, ok := language.Lookup(e.Name)
+		if !ok {
+			return Config{}, fmt.Errorf("%s: unknown language %q for %q: run 'gh pr-todo languages' to list supported languages",
+				source, e.Name, pattern)
+		}
+		cfg.Languages = append(cfg.Languages, language.Mapping{Pattern: pattern, Language: l.Name})
+	}
+
+	// Parse encoding mappings
+	for _, e := range f.Encodings {
+		pattern, err := validPattern(source, "encodings", e.Pattern)
+		if err != nil {
+			return Config{}, err
+		}
+		if _, ok := charset.Lookup(e.Name); !ok {
+			return Config{}, fmt.Errorf("%s: unknown encoding %q for %q", source, e.Name, pattern)
+		}
+		cfg.Encodings = append(cfg.Encodings, charset.Mapping{Pattern: pattern, Encoding: strings.TrimSpace(e.Name)})
+	}
 
 	// Parse severity overrides
 	if len(f.Severity) > 0 {
@@ -108,6 +201,11 @@ func DefaultConfigYAML() []byte {
     - BUG
   error: []
 ignore: []
+markdown:
+  paragraphs: false
+docstrings: false
+languages: {}
+encodings: {}
 `)
 }
 
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Suree33/gh-pr-todo/internal/charset"
+	"github.com/Suree33/gh-pr-todo/internal/language"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 )
 
@@ -277,6 +279,96 @@ func TestParse(t *testing.T) {
 			t.Fatalf("expected NOTE ignored, got %v", cfg.Ignored)
 		}
 	})
+
+	t.Run("markdown paragraphs enabled", func(t *testing.T) {
+		cfg, err := Parse([]byte("markdown:\n  paragraphs: true\n"), "test")
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		if !cfg.MarkdownParagraphs {
+			t.Fatal("expected MarkdownParagraphs=true")
+		}
+	})
+
+	t.Run("markdown paragraphs default to disabled", func(t *testing.T) {
+		cfg, err := Parse([]byte("ignore:\n  - NOTE\n"), "test")
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		if cfg.MarkdownParagraphs {
+			t.Fatal("expected MarkdownParagraphs=false")
+		}
+	})
+
+	t.Run("docstrings enabled", func(t *testing.T) {
+		cfg, err := Parse([]byte("docstrings: true\n"), "test")
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		if !cfg.Docstrings {
+			t.Fatal("expected Docstrings=true")
+		}
+	})
+
+	t.Run("languages keep file order and canonical names", func(t *testing.T) {
+		cfg, err := Parse([]byte("languages:\n  Jenkinsfile: groovy\n  \"*.tpl\": golang\n  bin/*: Python3\n"), "test")
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		want := []language.Mapping{
+			{Pattern: "Jenkinsfile", Language: "groovy"},
+			{Pattern: "*.tpl", Language: "go"},
+			{Pattern: "bin/*", Language: "python"},
+		}
+		if !reflect.DeepEqual(cfg.Languages, want) {
+			t.Fatalf("Languages = %+v, want %+v", cfg.Languages, want)
+		}
+	})
+
+	t.Run("invalid languages return errors", func(t *testing.T) {
+		tests := []struct {
+			yaml string
+			want string
+		}{
+			{yaml: "languages:\n  Jenkinsfile: cobol\n", want: `unknown language "cobol"`},
+			{yaml: "languages:\n  \"[a\": go\n", want: `invalid path pattern "[a"`},
+			{yaml: "languages:\n  - Jenkinsfile\n", want: "must be a mapping of path patterns to names"},
+		}
+		for _, tt := range tests {
+			_, err := Parse([]byte(tt.yaml), "test")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.yaml, err, tt.want)
+			}
+		}
+	})
+
+	t.Run("encodings keep file order", func(t *testing.T) {
+		cfg, err := Parse([]byte("encodings:\n  legacy/*.cpp: shift_jis\n  \"*.txt\": latin1\n"), "test")
+		if err != nil {
+			t.Fatalf("Parse() unexpected error: %v", err)
+		}
+		want := []charset.Mapping{
+			{Pattern: "legacy/*.cpp", Encoding: "shift_jis"},
+			{Pattern: "*.txt", Encoding: "latin1"},
+		}
+		if !reflect.DeepEqual(cfg.Encodings, want) {
+			t.Fatalf("Encodings = %+v, want %+v", cfg.Encodings, want)
+		}
+	})
+
+	t.Run("unknown encoding returns error", func(t *testing.T) {
+		_, err := Parse([]byte("encodings:\n  \"*.c\": ebcdic-klingon\n"), "test")
+		if err == nil || !strings.Contains(err.Error(), `unknown encoding "ebcdic-klingon"`) {
+			t.Fatalf("Parse() expected unknown encoding error, got %v", err)
+		}
+	})
+
+	t.Run("invalid markdown paragraphs value returns error", func(t *testing.T) {
+		_, err := Parse([]byte("markdown:\n  paragraphs: sometimes\n"), "test")
+		if err == nil || !strings.Contains(err.Error(), "invalid YAML") {
+			t.Fatalf("Parse() expected invalid YAML error, got %v", err)
+		}
+	})
 }
 
 func TestDefaultConfigYAMLParsesToRuntimeDefaults(t *testing.T) {
@@ -313,6 +405,13 @@ func TestDefaultConfigYAMLParsesToRuntimeDefaults(t *testing.T) {
 		if cfg.Ignored != nil {
 			t.Fatalf("expected nil Ignored for default config, got %v", cfg.Ignored)
 		}
+
+		if cfg.MarkdownParagraphs {
+			t.Fatal("expected markdown paragraphs disabled in default config")
+		}
+		if cfg.Docstrings {
+			t.Fatal("expected docstrings disabled in default config")
+		}
 	})
 }
 
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -8,10 +9,10 @@ import (
 // from a GitHub repository.
 type RemoteConfigFetcher interface {
 	// FetchRemoteConfigRefs returns the relevant refs for remote config loading.
-	FetchRemoteConfigRefs(repo, pr string) (RemoteConfigRefs, error)
+	FetchRemoteConfigRefs(ctx context.Context, repo, pr string) (RemoteConfigRefs, error)
 	// FetchFileAtRef fetches a file from the given repo at a specific ref.
 	// Returns (nil, false, nil) if the file is not found (404).
-	FetchFileAtRef(repo, path, ref string) ([]byte, bool, error)
+	FetchFileAtRef(ctx context.Context, repo, path, ref string) ([]byte, bool, error)
 }
 
 // RemoteConfigRefs holds repository references used for remote config loading.
@@ -27,8 +28,8 @@ type RemoteConfigRefs struct {
 // LoadRemote loads config files from remote repository references.
 // Precedence: PR head > PR base > default branch. Within each scope,
 // .github/gh-pr-todo.yml replaces .gh-pr-todo.yml entirely.
-func LoadRemote(fetcher RemoteConfigFetcher, repo, pr string) (Config, error) {
-	refs, err := fetcher.FetchRemoteConfigRefs(repo, pr)
+func LoadRemote(ctx context.Context, fetcher RemoteConfigFetcher, repo, pr string) (Config, error) {
+	refs, err := fetcher.FetchRemoteConfigRefs(ctx, repo, pr)
 	if err != nil {
 		return Config{}, fmt.Errorf("fetching remote refs: %w", err)
 	}
@@ -48,7 +49,7 @@ func LoadRemote(fetcher RemoteConfigFetcher, repo, pr string) (Config, error) {
 			continue
 		}
 
-		cfg, found, err := loadRemoteRef(fetcher, candidate.repo, candidate.ref, candidate.scope)
+		cfg, found, err := loadRemoteRef(ctx, fetcher, candidate.repo, candidate.ref, candidate.scope)
 		if err != nil {
 			return Config{}, err
 		}
@@ -60,9 +61,9 @@ func LoadRemote(fetcher RemoteConfigFetcher, repo, pr string) (Config, error) {
 	return Config{}, nil
 }
 
-func loadRemoteRef(fetcher RemoteConfigFetcher, repoName, ref, scope string) (Config, bool, error) {
+func loadRemoteRef(ctx context.Context, fetcher RemoteConfigFetcher, repoName, ref, scope string) (Config, bool, error) {
 	for _, path := range []string{".github/gh-pr-todo.yml", ".gh-pr-todo.yml"} {
-		data, found, err := fetcher.FetchFileAtRef(repoName, path, ref)
+		data, found, err := fetcher.FetchFileAtRef(ctx, repoName, path, ref)
 		if err != nil {
 			return Config{}, false, fmt.Errorf("fetching %s from %s at %s (%s): %w", path, repoName, ref, scope, err)
 		}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -16,11 +17,11 @@ type fakeFetcher struct {
 	fetchFileError error
 }
 
-func (f *fakeFetcher) FetchRemoteConfigRefs(repo, pr string) (RemoteConfigRefs, error) {
+func (f *fakeFetcher) FetchRemoteConfigRefs(_ context.Context, repo, pr string) (RemoteConfigRefs, error) {
 	return f.refs, f.refsErr
 }
 
-func (f *fakeFetcher) FetchFileAtRef(repo, path, ref string) ([]byte, bool, error) {
+func (f *fakeFetcher) FetchFileAtRef(_ context.Context, repo, path, ref string) ([]byte, bool, error) {
 	if f.fetchFileError != nil {
 		return nil, false, f.fetchFileError
 	}
@@ -36,7 +37,7 @@ func (f *fakeFetcher) FetchFileAtRef(repo, path, ref string) ([]byte, bool, erro
 func TestLoadRemote(t *testing.T) {
 	t.Run("fetch refs error returns error", func(t *testing.T) {
 		fetcher := &fakeFetcher{refsErr: errors.New("network error")}
-		_, err := LoadRemote(fetcher, "owner/repo", "1")
+		_, err := LoadRemote(context.Background(), fetcher, "owner/repo", "1")
 		if err == nil {
 			t.Fatal("LoadRemote() expected error, got nil")
 		}
@@ -50,7 +51,7 @@ func TestLoadRemote(t *testing.T) {
 			},
 			fileContents: make(map[string]map[string][]byte),
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
@@ -71,7 +72,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
@@ -93,7 +94,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
@@ -120,7 +121,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
@@ -151,7 +152,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "42")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "42")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
@@ -185,7 +186,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "42")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "42")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
@@ -202,7 +203,7 @@ func TestLoadRemote(t *testing.T) {
 			},
 			fetchFileError: errors.New("network error"),
 		}
-		_, err := LoadRemote(fetcher, "owner/repo", "")
+		_, err := LoadRemote(context.Background(), fetcher, "owner/repo", "")
 		if err == nil {
 			t.Fatal("LoadRemote() expected error, got nil")
 		}
@@ -220,7 +221,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		_, err := LoadRemote(fetcher, "owner/repo", "")
+		_, err := LoadRemote(context.Background(), fetcher, "owner/repo", "")
 		if err == nil {
 			t.Fatal("LoadRemote() expected error, got nil")
 		}
@@ -242,7 +243,7 @@ func TestLoadRemote(t *testing.T) {
 				},
 			},
 		}
-		cfg, err := LoadRemote(fetcher, "owner/repo", "42")
+		cfg, err := LoadRemote(context.Background(), fetcher, "owner/repo", "42")
 		if err != nil {
 			t.Fatalf("LoadRemote() unexpected error: %v", err)
 		}
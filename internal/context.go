@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// AttachContext fills in the Context of each TODO with up to n lines before
+// and after the TODO line, taken from the head file contents. Lines added in
+// the diff are flagged so they can be highlighted. TODOs whose file contents
+// are unavailable are left unchanged.
+func AttachContext(todos []types.TODO, diffOutput string, files map[string][]byte, n int) {
+	if n <= 0 || len(todos) == 0 {
+		return
+	}
+
+	addedRanges := make(map[string][]lineRange)
+	for _, fc := range extractFileChanges(diffOutput) {
+		addedRanges[fc.path] = fc.addedRanges
+	}
+
+	fileLines := make(map[string][]string)
+	for i := range todos {
+		todo := &todos[i]
+		lines, ok := fileLines[todo.Filename]
+		if !ok {
+			content, found := files[todo.Filename]
+			if !found {
+				continue
+			}
+			lines = splitContentLines(content)
+			fileLines[todo.Filename] = lines
+		}
+		todo.Context = contextLines(lines, todo.Line, n, addedRanges[todo.Filename])
+	}
+}
+
+// contextLines returns the lines within n lines of line (1-based), clamped to
+// the file bounds.
+func contextLines(lines []string, line, n int, added []lineRange) []types.ContextLine {
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+	start := max(1, line-n)
+	end := min(len(lines), line+n)
+
+	result := make([]types.ContextLine, 0, end-start+1)
+	for l := start; l <= end; l++ {
+		result = append(result, types.ContextLine{
+			Line:  l,
+			Text:  lines[l-1],
+			Added: lineInRanges(l, added),
+		})
+	}
+	return result
+}
+
+// splitContentLines splits file contents into lines, dropping carriage
+// returns and the empty element after a trailing newline.
+func splitContentLines(content []byte) []string {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestAttachContext(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1234567..abcdefg 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,6 @@
+ package main
+ 
+ func main() {
++	// TODO: implement
++	println()
+ }`
+	files := map[string][]byte{
+		"main.go": []byte("package main\n\nfunc main() {\n\t// TODO: implement\n\tprintln()\n}\n"),
+	}
+
+	tests := []struct {
+		name  string
+		todos []types.TODO
+		n     int
+		want  []types.TODO
+	}{
+		{
+			name:  "zero lines leaves context empty",
+			todos: []types.TODO{{Filename: "main.go", Line: 4, Comment: "// TODO: implement", Type: "TODO"}},
+			n:     0,
+			want:  []types.TODO{{Filename: "main.go", Line: 4, Comment: "// TODO: implement", Type: "TODO"}},
+		},
+		{
+			name:  "surrounding lines with added flags",
+			todos: []types.TODO{{Filename: "main.go", Line: 4, Comment: "// TODO: implement", Type: "TODO"}},
+			n:     1,
+			want: []types.TODO{{
+				Filename: "main.go", Line: 4, Comment: "// TODO: implement", Type: "TODO",
+				Context: []types.ContextLine{
+					{Line: 3, Text: "func main() {"},
+					{Line: 4, Text: "\t// TODO: implement", Added: true},
+					{Line: 5, Text: "\tprintln()", Added: true},
+				},
+			}},
+		},
+		{
+			name:  "clamped to file bounds",
+			todos: []types.TODO{{Filename: "main.go", Line: 4, Comment: "// TODO: implement", Type: "TODO"}},
+			n:     10,
+			want: []types.TODO{{
+				Filename: "main.go", Line: 4, Comment: "// TODO: implement", Type: "TODO",
+				Context: []types.ContextLine{
+					{Line: 1, Text: "package main"},
+					{Line: 2, Text: ""},
+					{Line: 3, Text: "func main() {"},
+					{Line: 4, Text: "\t// TODO: implement", Added: true},
+					{Line: 5, Text: "\tprintln()", Added: true},
+					{Line: 6, Text: "}"},
+				},
+			}},
+		},
+		{
+			name:  "missing file contents are skipped",
+			todos: []types.TODO{{Filename: "other.go", Line: 1, Comment: "// TODO: x", Type: "TODO"}},
+			n:     2,
+			want:  []types.TODO{{Filename: "other.go", Line: 1, Comment: "// TODO: x", Type: "TODO"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AttachContext(tt.todos, diff, files, tt.n)
+			if !reflect.DeepEqual(tt.todos, tt.want) {
+				t.Errorf("AttachContext() = %+v, expected %+v", tt.todos, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package internal
+
+import "strings"
+
+// commentDelimiter is an opening and closing comment delimiter pair, such as
+// "<!--" and "-->".
+type commentDelimiter struct {
+	open  string
+	close string
+	// altCloses lists other closing delimiters accepted for this opening
+	// delimiter, such as the whitespace-trimming "*/ -}}" in Go templates.
+	altCloses []string
+}
+
+// findClose returns the index and text of the earliest closing delimiter in
+// text, or -1 if there is none.
+func (d commentDelimiter) findClose(text string) (int, string) {
+	best, found := strings.Index(text, d.close), d.close
+	for _, alt := range d.altCloses {
+		if idx := strings.Index(text, alt); idx >= 0 && (best < 0 || idx < best) {
+			best, found = idx, alt
+		}
+	}
+	return best, found
+}
+
+// commentSegment is the part of a line that lies inside a delimited comment.
+type commentSegment struct {
+	// text is the comment text on this line, without delimiters.
+	text string
+	// open is the opening delimiter if the comment starts on this line.
+	open string
+	// close is the closing delimiter if the comment ends on this line.
+	close string
+}
+
+// display returns the segment as it appears in the source, including any
+// delimiters that occur on this line.
+func (s commentSegment) display() string {
+	return strings.TrimSpace(s.open + s.text + s.close)
+}
+
+// delimitedCommentScanner finds comments with explicit opening and closing
+// delimiters line by line, tracking comments that span multiple lines.
+type delimitedCommentScanner struct {
+	delimiters []commentDelimiter
+	// current is the delimiter of the comment currently open, or nil.
+	current *commentDelimiter
+}
+
+// inComment reports whether a comment is open at the end of the last
+// scanned line.
+func (s *delimitedCommentScanner) inComment() bool {
+	return s.current != nil
+}
+
+// scan returns the comment segments on line and updates the open-comment
+// state for the next line.
+func (s *delimitedCommentScanner) scan(line string) []commentSegment {
+	segments, _ := s.split(line)
+	return segments
+}
+
+// split returns the comment segments on line, along with the line with every
+// comment, delimiters included, replaced by spaces. It updates the
+// open-comment state for the next line.
+func (s *delimitedCommentScanner) split(line string) ([]commentSegment, string) {
+	var segments []commentSegment
+	masked := []byte(line)
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			masked[i] = ' '
+		}
+	}
+
+	pos := 0
+	open := ""
+	for pos <= len(line) {
+		if s.current != nil {
+			idx, closing := s.current.findClose(line[pos:])
+			if idx < 0 {
+				segments = append(segments, commentSegment{text: line[pos:], open: open})
+				blank(pos, len(line))
+				break
+			}
+			segments = append(segments, commentSegment{text: line[pos : pos+idx], open: open, close: closing})
+			blank(pos, pos+idx+len(closing))
+			pos += idx + len(closing)
+			s.current = nil
+			open = ""
+			continue
+		}
+
+		start, d := s.nextOpening(line[pos:])
+		if start < 0 {
+			break
+		}
+		blank(pos+start, pos+start+len(d.open))
+		pos += start + len(d.open)
+		open = d.open
+		s.current = d
+	}
+	return segments, string(masked)
+}
+
+// nextOpening returns the index and delimiter of the earliest opening
+// delimiter in text, or -1 if there is none. Longer delimiters win ties, so
+// "{{!--" is preferred over "{{!".
+func (s *delimitedCommentScanner) nextOpening(text string) (int, *commentDelimiter) {
+	best := -1
+	var found *commentDelimiter
+	for i := range s.delimiters {
+		d := &s.delimiters[i]
+		idx := strings.Index(text, d.open)
+		if idx < 0 {
+			continue
+		}
+		if best < 0 || idx < best || (idx == best && len(d.open) > len(found.open)) {
+			best = idx
+			found = d
+		}
+	}
+	return best, found
+}
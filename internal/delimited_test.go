@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDelimitedCommentScanner(t *testing.T) {
+	s := delimitedCommentScanner{delimiters: jsxDelimiters}
+	tests := []struct {
+		line      string
+		expected  []commentSegment
+		inComment bool
+	}{
+		{line: "plain text", expected: nil},
+		{
+			line: "a <!-- one --> b {/* two */} c",
+			expected: []commentSegment{
+				{text: " one ", open: "<!--", close: "-->"},
+				{text: " two ", open: "{/*", close: "*/}"},
+			},
+		},
+		{line: "text <!-- TODO: start", expected: []commentSegment{{text: " TODO: start", open: "<!--"}}, inComment: true},
+		{line: "  middle {/* not an opener", expected: []commentSegment{{text: "  middle {/* not an opener"}}, inComment: true},
+		{line: "end --> after <!-- next -->", expected: []commentSegment{{text: "end ", close: "-->"}, {text: " next ", open: "<!--", close: "-->"}}},
+	}
+
+	for _, tt := range tests {
+		got := s.scan(tt.line)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("scan(%q) = %+v, expected %+v", tt.line, got, tt.expected)
+		}
+		if s.inComment() != tt.inComment {
+			t.Errorf("after scan(%q) inComment() = %v, expected %v", tt.line, s.inComment(), tt.inComment)
+		}
+	}
+}
+
+func TestDelimitedCommentScannerSplit(t *testing.T) {
+	s := delimitedCommentScanner{delimiters: goTemplateDelimiters}
+
+	segments, masked := s.split("a: {{- /* TODO: x */}} b")
+	want := []commentSegment{{text: " TODO: x ", open: "{{- /*", close: "*/}}"}}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("split() segments = %+v, expected %+v", segments, want)
+	}
+	if masked != "a:                     b" {
+		t.Errorf("split() masked = %q", masked)
+	}
+
+	segments, masked = s.split("{{/* open")
+	if len(segments) != 1 || masked != "         " || !s.inComment() {
+		t.Errorf("split() = %+v, %q, inComment %v", segments, masked, s.inComment())
+	}
+	segments, masked = s.split("end */ -}} tail")
+	want = []commentSegment{{text: "end ", close: "*/ -}}"}}
+	if !reflect.DeepEqual(segments, want) || masked != "           tail" || s.inComment() {
+		t.Errorf("split() = %+v, %q, inComment %v", segments, masked, s.inComment())
+	}
+}
@@ -0,0 +1,297 @@
+package diff
+
+import (
+	"bytes"
+	"slices"
+	"sort"
+)
+
+// maxEdits bounds the edits searched for between two stretches of lines
+// that share no unique line. Stretches that differ by more are reported as
+// replaced outright, which finds every added line at the cost of a longer
+// hunk.
+const maxEdits = 1024
+
+// Compare returns the hunks of a line diff from old to new, with context
+// lines of unchanged text around each change, as git would show them.
+// Lines that are unique to both sides anchor the comparison, so moved
+// blocks and large rewrites are diffed in time proportional to their size.
+func Compare(old, new []byte, context int) []Hunk {
+	d := &differ{}
+	d.a, d.aText = d.split(old)
+	d.b, d.bText = d.split(new)
+	d.deleted = make([]bool, len(d.a))
+	d.added = make([]bool, len(d.b))
+	d.compare(0, len(d.a), 0, len(d.b))
+	return d.hunks(context)
+}
+
+// IsBinary reports whether data looks like the contents of a binary file:
+// git treats files with a NUL byte in their first 8000 bytes as binary.
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
+
+// differ compares two files as sequences of line IDs. Lines are equal when
+// their text and whether they end with a newline are.
+type differ struct {
+	ids            map[string]int
+	a, b           []int
+	aText, bText   []string
+	deleted, added []bool
+}
+
+// split returns the IDs and text of the lines of data.
+func (d *differ) split(data []byte) ([]int, []string) {
+	if d.ids == nil {
+		d.ids = make(map[string]int)
+	}
+	var ids []int
+	var text []string
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		data = data[len(line):]
+		key := string(line)
+		id, ok := d.ids[key]
+		if !ok {
+			id = len(d.ids)
+			d.ids[key] = id
+		}
+		ids = append(ids, id)
+		text = append(text, key)
+	}
+	return ids, text
+}
+
+// compare marks the lines that differ between a[a0:a1] and b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		a0++
+		b0++
+	}
+	for a0 < a1 && b0 < b1 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+	}
+	if a0 == a1 || b0 == b1 {
+		d.replace(a0, a1, b0, b1)
+		return
+	}
+
+	anchors := d.anchors(a0, a1, b0, b1)
+	if len(anchors) == 0 {
+		d.myers(a0, a1, b0, b1)
+		return
+	}
+	for _, anchor := range anchors {
+		d.compare(a0, anchor[0], b0, anchor[1])
+		a0, b0 = anchor[0]+1, anchor[1]+1
+	}
+	d.compare(a0, a1, b0, b1)
+}
+
+// replace marks a[a0:a1] as deleted and b[b0:b1] as added.
+func (d *differ) replace(a0, a1, b0, b1 int) {
+	for i := a0; i < a1; i++ {
+		d.deleted[i] = true
+	}
+	for j := b0; j < b1; j++ {
+		d.added[j] = true
+	}
+}
+
+// anchors returns the longest run of lines that appear exactly once in
+// both a[a0:a1] and b[b0:b1], in the same order on both sides, as pairs
+// of indexes into a and b.
+func (d *differ) anchors(a0, a1, b0, b1 int) [][2]int {
+	type count struct{ a, b, ai, bj int }
+	counts := make(map[int]*count)
+	for i := a0; i < a1; i++ {
+		c := counts[d.a[i]]
+		if c == nil {
+			c = &count{}
+			counts[d.a[i]] = c
+		}
+		c.a++
+		c.ai = i
+	}
+	for j := b0; j < b1; j++ {
+		if c := counts[d.b[j]]; c != nil {
+			c.b++
+			c.bj = j
+		}
+	}
+	var pairs [][2]int
+	for _, c := range counts {
+		if c.a == 1 && c.b == 1 {
+			pairs = append(pairs, [2]int{c.ai, c.bj})
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	slices.SortFunc(pairs, func(p, q [2]int) int { return p[0] - q[0] })
+
+	// Patience sorting: the longest increasing subsequence of the b
+	// indexes, following links back from the last pile.
+	var piles []int
+	prev := make([]int, len(pairs))
+	for i, p := range pairs {
+		n := sort.Search(len(piles), func(k int) bool { return pairs[piles[k]][1] > p[1] })
+		prev[i] = -1
+		if n > 0 {
+			prev[i] = piles[n-1]
+		}
+		if n == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[n] = i
+		}
+	}
+	anchors := make([][2]int, len(piles))
+	for i, k := len(piles)-1, piles[len(piles)-1]; i >= 0; i, k = i-1, prev[k] {
+		anchors[i] = pairs[k]
+	}
+	return anchors
+}
+
+// myers marks the lines of a shortest edit script from a[a0:a1] to
+// b[b0:b1], or replaces the whole stretch if that takes more than maxEdits
+// edits.
+func (d *differ) myers(a0, a1, b0, b1 int) {
+	n, m := a1-a0, b1-b0
+	limit := min(n+m, maxEdits)
+	off := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for e := 0; e <= limit; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || k != e && v[off+k-1] < v[off+k+1] {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				d.backtrack(trace, a0, b0, n, m)
+				return
+			}
+		}
+		trace = append(trace, slices.Clone(v[off-e:off+e+1]))
+	}
+	d.replace(a0, a1, b0, b1)
+}
+
+// backtrack follows the furthest reaching paths recorded by myers back
+// from the end, marking each edit on the way. trace[e][k+e] is how far
+// along diagonal k a path with e edits reaches.
+func (d *differ) backtrack(trace [][]int, a0, b0, x, y int) {
+	for e := len(trace); e > 0; e-- {
+		prev := trace[e-1]
+		at := func(k int) int { return prev[k+e-1] }
+		k := x - y
+		var prevK int
+		if k == -e || k != e && at(k-1) < at(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		if prevK == k+1 {
+			d.added[b0+prevY] = true
+		} else {
+			d.deleted[a0+prevX] = true
+		}
+		x, y = prevX, prevY
+	}
+}
+
+// op is a line of the diff with its position in both files.
+type op struct {
+	kind     LineKind
+	text     string
+	old, new int // lines of each file before this one
+}
+
+// hunks groups the marked lines into hunks with context lines around them.
+func (d *differ) hunks(context int) []Hunk {
+	var ops []op
+	i, j := 0, 0
+	for i < len(d.a) || j < len(d.b) {
+		switch {
+		case i < len(d.a) && d.deleted[i]:
+			ops = append(ops, op{Deleted, d.aText[i], i, j})
+			i++
+		case j < len(d.b) && d.added[j]:
+			ops = append(ops, op{Added, d.bText[j], i, j})
+			j++
+		default:
+			ops = append(ops, op{Context, d.aText[i], i, j})
+			i++
+			j++
+		}
+	}
+
+	var hunks []Hunk
+	for start := 0; start < len(ops); {
+		if ops[start].kind == Context {
+			start++
+			continue
+		}
+		// Extend the hunk over changes whose context would overlap.
+		last := start
+		for end := start; end < len(ops); end++ {
+			if ops[end].kind != Context {
+				last = end
+			} else if end-last > 2*context {
+				break
+			}
+		}
+		hunks = append(hunks, makeHunk(ops[max(0, start-context):min(len(ops), last+context+1)]))
+		start = last + 1
+	}
+	return hunks
+}
+
+// makeHunk returns the hunk of ops.
+func makeHunk(ops []op) Hunk {
+	h := Hunk{OldStart: ops[0].old, NewStart: ops[0].new}
+	for _, o := range ops {
+		text, hasNewline := trimNewline(o.text)
+		l := Line{Kind: o.kind, Text: text, NoNewlineAtEOF: !hasNewline}
+		if o.kind != Added {
+			h.OldLines++
+			l.OldLine = o.old + 1
+		}
+		if o.kind != Deleted {
+			h.NewLines++
+			l.NewLine = o.new + 1
+		}
+		h.Lines = append(h.Lines, l)
+	}
+	// Like git, a side without lines starts at the line before the hunk.
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+	return h
+}
+
+func trimNewline(s string) (string, bool) {
+	if len(s) > 0 && s[len(s)-1] == '\n' {
+		return s[:len(s)-1], true
+	}
+	return s, false
+}
@@ -0,0 +1,186 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     string
+	}{
+		{
+			name:    "change with context",
+			old:     "a\nb\nc\nd\ne\n",
+			new:     "a\nb\nC\nd\ne\n",
+			context: 1,
+			want:    "@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n",
+		},
+		{
+			name:    "distant changes get separate hunks",
+			old:     "1\n2\n3\n4\n5\n6\n7\n",
+			new:     "one\n2\n3\n4\n5\n6\nseven\n",
+			context: 1,
+			want:    "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+seven\n",
+		},
+		{
+			name:    "nearby changes share a hunk",
+			old:     "1\n2\n3\n4\n",
+			new:     "one\n2\n3\nfour\n",
+			context: 1,
+			want:    "@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n-4\n+four\n",
+		},
+		{
+			name:    "added file",
+			new:     "x\ny\n",
+			context: 3,
+			want:    "@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name: "insertion without context",
+			old:  "a\nb\n",
+			new:  "a\nnew\nb\n",
+			want: "@@ -1,0 +2 @@\n+new\n",
+		},
+		{
+			name:    "missing final newline",
+			old:     "a\nb",
+			new:     "a\nb\n",
+			context: 3,
+			want:    "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "moved block",
+			old:  "func a() {}\nfunc b() {}\nfunc c() {}\n",
+			new:  "func c() {}\nfunc a() {}\nfunc b() {}\n",
+			want: "@@ -0,0 +1 @@\n+func c() {}\n@@ -3 +3,0 @@\n-func c() {}\n",
+		},
+		{name: "unchanged", old: "a\n", new: "a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			if err := WriteHunks(&got, Compare([]byte(tt.old), []byte(tt.new), tt.context)); err != nil {
+				t.Fatalf("WriteHunks() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Compare() =\n%s\nexpected\n%s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+// apply applies hunks to old, failing if they do not match it.
+func apply(t *testing.T, old []string, hunks []Hunk) []string {
+	t.Helper()
+	var out []string
+	next := 0 // index of the next old line
+	for _, h := range hunks {
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			start = h.OldStart
+		}
+		out = append(out, old[next:start]...)
+		next = start
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Added:
+				out = append(out, l.Text)
+			default:
+				if old[next] != l.Text || l.OldLine != next+1 {
+					t.Fatalf("hunk line %+v does not match old line %d %q", l, next+1, old[next])
+				}
+				if l.Kind == Context {
+					out = append(out, l.Text)
+				}
+				next++
+			}
+		}
+	}
+	return append(out, old[next:]...)
+}
+
+func TestCompareApplies(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	lines := func(n, alphabet int) []string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprint(r.IntN(alphabet))
+		}
+		return out
+	}
+	join := func(lines []string) []byte {
+		if len(lines) == 0 {
+			return nil
+		}
+		return []byte(strings.Join(lines, "\n") + "\n")
+	}
+
+	for i := range 300 {
+		// Small alphabets repeat lines, exercising the Myers fallback;
+		// large ones give unique anchors. The last cases exceed maxEdits.
+		n, alphabet := r.IntN(60), 2+r.IntN(100)
+		if i >= 295 {
+			n, alphabet = 3000, 3
+		}
+		old, new := lines(n, alphabet), lines(r.IntN(n+5), alphabet)
+		if i%2 == 0 && n > 0 {
+			new = append(append(lines(2, alphabet), old[n/3:]...), lines(3, alphabet)...)
+		}
+		context := r.IntN(4)
+		hunks := Compare(join(old), join(new), context)
+		if got := apply(t, old, hunks); !reflect.DeepEqual(got, new) && !(len(got) == 0 && len(new) == 0) {
+			t.Fatalf("case %d: applying Compare() gave %q, expected %q", i, got, new)
+		}
+		for _, h := range hunks {
+			var oldLines, newLines int
+			for _, l := range h.Lines {
+				if l.Kind != Added {
+					oldLines++
+				}
+				if l.Kind != Deleted {
+					newLines++
+				}
+			}
+			if oldLines != h.OldLines || newLines != h.NewLines {
+				t.Fatalf("case %d: hunk counts %d,%d, expected %d,%d", i, h.OldLines, h.NewLines, oldLines, newLines)
+			}
+		}
+	}
+}
+
+func TestWriteParses(t *testing.T) {
+	hunks := Compare([]byte("a\n"), []byte("a\n// TODO: b\n"), 3)
+	files := []File{
+		{OldPath: "main.go", NewPath: "main.go", Hunks: hunks},
+		{NewPath: "new.txt", IsNew: true, Hunks: Compare(nil, []byte("x\n"), 3)},
+		{OldPath: "gone.txt", IsDeleted: true},
+		{OldPath: "old name.go", NewPath: "new name.go", IsRename: true, Similarity: 90, Hunks: hunks},
+		{OldPath: "logo.png", NewPath: "logo.png", IsBinary: true},
+		{OldPath: `we"ird.go`, NewPath: `we"ird.go`, Hunks: hunks},
+	}
+	var b strings.Builder
+	for _, f := range files {
+		if err := Write(&b, f); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	want := []fileSummary{
+		{OldPath: "main.go", NewPath: "main.go", Added: []string{"2:// TODO: b"}},
+		{NewPath: "new.txt", NewMode: "100644", IsNew: true, Added: []string{"1:x"}},
+		{OldPath: "gone.txt", OldMode: "100644", IsDeleted: true},
+		{OldPath: "old name.go", NewPath: "new name.go", IsRename: true, Similarity: 90, Added: []string{"2:// TODO: b"}},
+		{OldPath: "logo.png", NewPath: "logo.png", IsBinary: true},
+		{OldPath: `we"ird.go`, NewPath: `we"ird.go`, Added: []string{"2:// TODO: b"}},
+	}
+	if got := summarize(Parse(b.String())); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(Write()) =\n%+v\nexpected\n%+v\ndiff:\n%s", got, want, b.String())
+	}
+}
@@ -0,0 +1,564 @@
+// Package diff parses unified diffs as produced by git, including extended
+// headers for renames, copies, mode changes, and binary files.
+package diff
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DevNull is the path git uses for the missing side of an added or deleted
+// file.
+const DevNull = "/dev/null"
+
+var hunkHeaderRegex = regexp.MustCompile(`^@@\s+-(\d+)(?:,(\d+))?\s+\+(\d+)(?:,(\d+))?\s+@@ ?(.*)$`)
+
+// LineKind identifies the role of a line in a hunk.
+type LineKind int
+
+const (
+	// Context is a line present in both versions.
+	Context LineKind = iota
+	// Added is a line present only in the new version.
+	Added
+	// Deleted is a line present only in the old version.
+	Deleted
+)
+
+// Line is a single line of a hunk.
+type Line struct {
+	Kind LineKind
+	// Text is the line content without the leading marker.
+	Text string
+	// OldLine is the 1-based line number in the old file, or 0 for added
+	// lines.
+	OldLine int
+	// NewLine is the 1-based line number in the new file, or 0 for deleted
+	// lines.
+	NewLine int
+	// NoNewlineAtEOF is set when the line is followed by
+	// "\ No newline at end of file".
+	NoNewlineAtEOF bool
+}
+
+// Hunk is a contiguous block of changes.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	// Section is the text after the closing "@@", usually the enclosing
+	// function.
+	Section string
+	Lines   []Line
+}
+
+// File is the diff of a single file.
+type File struct {
+	// OldPath is the path before the change, or "" for added files.
+	OldPath string
+	// NewPath is the path after the change, or "" for deleted files.
+	NewPath string
+	OldMode string
+	NewMode string
+	IsNew   bool
+	// IsDeleted is set for deleted files.
+	IsDeleted bool
+	IsRename  bool
+	IsCopy    bool
+	IsBinary  bool
+	// Similarity is the similarity index of a rename or copy, in percent.
+	Similarity int
+	Hunks      []Hunk
+	// Oversized is set when the file's diff exceeded the Reader's
+	// MaxFileSize. Its hunks are discarded.
+	Oversized bool
+}
+
+// Path returns the path of the file after the change, or before it for
+// deleted files.
+func (f File) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// AddedLines returns the added lines of all hunks, in order.
+func (f File) AddedLines() []Line {
+	var added []Line
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind == Added {
+				added = append(added, l)
+			}
+		}
+	}
+	return added
+}
+
+// Parse parses a unified diff. Parsing is lenient: unrecognized lines are
+// skipped and truncated hunks keep the lines read so far, so Parse never
+// fails.
+func Parse(text string) []File {
+	var files []File
+	r := NewReader(strings.NewReader(text), ReaderOptions{})
+	for {
+		f, err := r.Next()
+		if err != nil {
+			return files
+		}
+		files = append(files, f)
+	}
+}
+
+// ReaderOptions limits the memory a Reader uses.
+type ReaderOptions struct {
+	// MaxFileSize is the largest diff, in bytes, kept for a single file.
+	// Larger files are returned with Oversized set and no hunks. Zero means
+	// no limit.
+	MaxFileSize int64
+}
+
+// Reader parses a unified diff from a stream one file at a time, so only
+// the file being parsed is held in memory.
+type Reader struct {
+	src  *bufio.Reader
+	opts ReaderOptions
+	// ahead holds lines that were peeked at but not consumed.
+	ahead []string
+	eof   bool
+	err   error
+	// done holds parsed files not yet returned by Next.
+	done []File
+	// current is the file being parsed, or nil.
+	current *File
+	// size is the number of bytes consumed for the current file.
+	size int64
+	// inHunks is set once the current file's first hunk header is read.
+	inHunks bool
+	// stripPrefix records whether the current file's paths carry "a/" and
+	// "b/" style prefixes.
+	stripPrefix bool
+	// headerOld and headerNew are the paths from the "diff --git" line, used
+	// when no other header names the file.
+	headerOld, headerNew string
+}
+
+// NewReader returns a Reader that parses the diff read from r.
+func NewReader(r io.Reader, opts ReaderOptions) *Reader {
+	return &Reader{src: bufio.NewReaderSize(r, 64*1024), opts: opts}
+}
+
+// Next returns the next file in the diff. It returns io.EOF after the last
+// file, or the error that stopped reading, once the files read before it
+// have been returned.
+func (p *Reader) Next() (File, error) {
+	for len(p.done) == 0 {
+		line, ok := p.peek(0)
+		if !ok {
+			p.finishFile()
+			if len(p.done) > 0 {
+				break
+			}
+			if p.err != nil {
+				return File{}, p.err
+			}
+			return File{}, io.EOF
+		}
+		p.step(strings.TrimSuffix(line, "\r"))
+	}
+	f := p.done[0]
+	p.done = p.done[1:]
+	return f, nil
+}
+
+// step handles a line outside of a hunk.
+func (p *Reader) step(line string) {
+	switch {
+	case strings.HasPrefix(line, "diff --git "):
+		p.startGitFile(line[len("diff --git "):])
+	case strings.HasPrefix(line, "--- ") && p.nextHasPrefix("+++ "):
+		next, _ := p.peek(1)
+		p.fileHeader(line[len("--- "):], strings.TrimSuffix(next, "\r")[len("+++ "):])
+		p.advance()
+	case strings.HasPrefix(line, "@@"):
+		if p.current != nil {
+			if m := hunkHeaderRegex.FindStringSubmatch(line); m != nil {
+				p.advance()
+				p.hunk(m)
+				return
+			}
+		}
+	case p.current != nil && !p.inHunks:
+		p.extendedHeader(line)
+	}
+	p.advance()
+}
+
+// peek returns the line i lines ahead of the current one, reading it if
+// needed. It returns false at the end of the input.
+func (p *Reader) peek(i int) (string, bool) {
+	for len(p.ahead) <= i {
+		line, ok := p.readLine()
+		if !ok {
+			return "", false
+		}
+		p.ahead = append(p.ahead, line)
+	}
+	return p.ahead[i], true
+}
+
+// advance consumes the current line, counting it towards the size of the
+// current file.
+func (p *Reader) advance() {
+	if len(p.ahead) == 0 {
+		return
+	}
+	if p.current != nil {
+		p.size += int64(len(p.ahead[0])) + 1
+		if p.opts.MaxFileSize > 0 && p.size > p.opts.MaxFileSize {
+			p.current.Oversized = true
+			p.current.Hunks = nil
+		}
+	}
+	p.ahead = p.ahead[1:]
+}
+
+// readLine reads the next line without its newline. Once MaxFileSize is
+// exceeded, the rest of a long line is discarded rather than buffered.
+func (p *Reader) readLine() (string, bool) {
+	if p.eof {
+		return "", false
+	}
+	var b []byte
+	for {
+		chunk, err := p.src.ReadSlice('\n')
+		if p.opts.MaxFileSize <= 0 || int64(len(b)) <= p.opts.MaxFileSize {
+			b = append(b, chunk...)
+		}
+		switch err {
+		case nil:
+			return string(b[:len(b)-1]), true
+		case bufio.ErrBufferFull:
+			continue
+		case io.EOF:
+		default:
+			p.err = err
+		}
+		p.eof = true
+		return string(b), len(b) > 0
+	}
+}
+
+func (p *Reader) nextHasPrefix(prefix string) bool {
+	next, ok := p.peek(1)
+	return ok && strings.HasPrefix(next, prefix)
+}
+
+// startGitFile begins a file at a "diff --git" line.
+func (p *Reader) startGitFile(names string) {
+	p.finishFile()
+	p.current = &File{}
+	p.headerOld, p.headerNew = splitGitHeaderNames(names)
+	p.stripPrefix = usesPrefixes(p.headerOld, p.headerNew)
+}
+
+// finishFile fills in paths that only the "diff --git" line named and
+// queues the current file.
+func (p *Reader) finishFile() {
+	if p.current == nil {
+		return
+	}
+	f := p.current
+	if f.OldPath == "" && !f.IsNew && p.headerOld != "" {
+		f.OldPath = p.cleanPath(p.headerOld, true)
+	}
+	if f.NewPath == "" && !f.IsDeleted && p.headerNew != "" {
+		f.NewPath = p.cleanPath(p.headerNew, true)
+	}
+	if f.OldPath != "" || f.NewPath != "" {
+		p.done = append(p.done, *f)
+	}
+	p.current = nil
+	p.size = 0
+	p.inHunks = false
+	p.headerOld, p.headerNew = "", ""
+}
+
+// fileHeader handles a "---" and "+++" line pair.
+func (p *Reader) fileHeader(oldName, newName string) {
+	if p.current == nil || p.inHunks {
+		// A plain unified diff without "diff --git" lines.
+		p.finishFile()
+		p.current = &File{}
+		p.stripPrefix = usesPrefixes(headerPath(oldName), headerPath(newName))
+	}
+	f := p.current
+	if oldPath := headerPath(oldName); oldPath == DevNull {
+		f.IsNew = true
+		f.OldPath = ""
+	} else {
+		f.OldPath = p.cleanPath(oldPath, true)
+	}
+	if newPath := headerPath(newName); newPath == DevNull {
+		f.IsDeleted = true
+		f.NewPath = ""
+	} else {
+		f.NewPath = p.cleanPath(newPath, true)
+	}
+}
+
+// extendedHeader handles a git extended header line between "diff --git"
+// and the first hunk.
+func (p *Reader) extendedHeader(line string) {
+	f := p.current
+	switch {
+	case strings.HasPrefix(line, "new file mode "):
+		f.IsNew = true
+		f.NewMode = strings.TrimPrefix(line, "new file mode ")
+	case strings.HasPrefix(line, "deleted file mode "):
+		f.IsDeleted = true
+		f.OldMode = strings.TrimPrefix(line, "deleted file mode ")
+	case strings.HasPrefix(line, "old mode "):
+		f.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		f.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "index "):
+		if _, mode, ok := strings.Cut(strings.TrimPrefix(line, "index "), " "); ok {
+			f.OldMode, f.NewMode = mode, mode
+		}
+	case strings.HasPrefix(line, "similarity index "):
+		f.Similarity, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%"))
+	case strings.HasPrefix(line, "rename from "):
+		f.IsRename = true
+		f.OldPath = p.cleanPath(unquote(strings.TrimPrefix(line, "rename from ")), false)
+	case strings.HasPrefix(line, "rename to "):
+		f.IsRename = true
+		f.NewPath = p.cleanPath(unquote(strings.TrimPrefix(line, "rename to ")), false)
+	case strings.HasPrefix(line, "copy from "):
+		f.IsCopy = true
+		f.OldPath = p.cleanPath(unquote(strings.TrimPrefix(line, "copy from ")), false)
+	case strings.HasPrefix(line, "copy to "):
+		f.IsCopy = true
+		f.NewPath = p.cleanPath(unquote(strings.TrimPrefix(line, "copy to ")), false)
+	case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ"),
+		line == "GIT binary patch":
+		f.IsBinary = true
+	}
+}
+
+// hunk parses the lines of a hunk whose header matched m. Lines are read
+// until the counts in the header are satisfied, so added lines starting with
+// "++" and deleted lines starting with "--" are not mistaken for headers.
+func (p *Reader) hunk(m []string) {
+	h := Hunk{
+		OldStart: atoi(m[1]),
+		OldLines: countOrOne(m[2]),
+		NewStart: atoi(m[3]),
+		NewLines: countOrOne(m[4]),
+		Section:  m[5],
+	}
+	p.inHunks = true
+	oldLine, newLine := h.OldStart, h.NewStart
+	oldLeft, newLeft := h.OldLines, h.NewLines
+
+	for {
+		line, ok := p.peek(0)
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(line, `\`) {
+			if n := len(h.Lines); n > 0 {
+				h.Lines[n-1].NoNewlineAtEOF = true
+			}
+			p.advance()
+			continue
+		}
+		if oldLeft <= 0 && newLeft <= 0 {
+			break
+		}
+		if strings.HasPrefix(line, "diff --git ") || hunkHeaderRegex.MatchString(line) {
+			// Malformed counts; the hunk ended early.
+			break
+		}
+
+		// A line only belongs to the hunk while the counts in the header
+		// leave room for it on each side it is on.
+		var l Line
+		switch {
+		case strings.HasPrefix(line, "+") && newLeft > 0:
+			l = Line{Kind: Added, Text: line[1:], NewLine: newLine}
+			newLine++
+			newLeft--
+		case strings.HasPrefix(line, "-") && oldLeft > 0:
+			l = Line{Kind: Deleted, Text: line[1:], OldLine: oldLine}
+			oldLine++
+			oldLeft--
+		case strings.HasPrefix(line, " ") && oldLeft > 0 && newLeft > 0:
+			l = Line{Kind: Context, Text: line[1:], OldLine: oldLine, NewLine: newLine}
+			oldLine++
+			newLine++
+			oldLeft--
+			newLeft--
+		case (line == "" || line == "\r") && oldLeft > 0 && newLeft > 0:
+			// An empty context line whose leading space was stripped, as
+			// some editors and mail clients do.
+			l = Line{Kind: Context, Text: line, OldLine: oldLine, NewLine: newLine}
+			oldLine++
+			newLine++
+			oldLeft--
+			newLeft--
+		case line == "", line == "\r":
+			// A line without a marker the counts leave no room for as
+			// context carries no content; skip it rather than guess which
+			// side it belongs to.
+			p.advance()
+			continue
+		default:
+			// Not a hunk line, or one the counts leave no room for; the
+			// hunk ended or was truncated.
+			p.addHunk(h)
+			return
+		}
+		p.advance()
+		if !p.current.Oversized {
+			h.Lines = append(h.Lines, l)
+		}
+	}
+	p.addHunk(h)
+}
+
+// addHunk appends h to the current file unless the file is oversized.
+func (p *Reader) addHunk(h Hunk) {
+	if !p.current.Oversized {
+		p.current.Hunks = append(p.current.Hunks, h)
+	}
+}
+
+// cleanPath removes the "a/" or "b/" style prefix, if the diff uses one and
+// prefixed is set, and cleans the path.
+func (p *Reader) cleanPath(name string, prefixed bool) string {
+	if prefixed && p.stripPrefix && hasPrefixDir(name) {
+		name = name[2:]
+	}
+	return path.Clean(name)
+}
+
+// usesPrefixes reports whether a pair of header names carries git's source
+// and destination prefixes, which differ ("a/" and "b/" by default). Names
+// from a --no-prefix diff that happen to start with a one-letter directory
+// share it on both sides and are left alone.
+func usesPrefixes(oldName, newName string) bool {
+	switch {
+	case oldName == DevNull:
+		return hasPrefixDir(newName)
+	case newName == DevNull:
+		return hasPrefixDir(oldName)
+	}
+	return hasPrefixDir(oldName) && hasPrefixDir(newName) && oldName[0] != newName[0]
+}
+
+// hasPrefixDir reports whether name starts with a one-letter directory such
+// as the "a/" and "b/" (or mnemonic "i/", "w/", "c/", "o/") prefixes git adds.
+func hasPrefixDir(name string) bool {
+	return len(name) > 2 && name[1] == '/' && name[0] != '/' && name[0] != '.'
+}
+
+// headerPath extracts the path from the name part of a "---" or "+++" line.
+// Unquoted names may be followed by a tab and a timestamp, which git also
+// uses to terminate names containing spaces.
+func headerPath(name string) string {
+	name = strings.TrimSuffix(name, "\r")
+	if strings.HasPrefix(name, `"`) {
+		quoted, _ := cutQuoted(name)
+		return quoted
+	}
+	if before, _, found := strings.Cut(name, "\t"); found {
+		return before
+	}
+	return name
+}
+
+// splitGitHeaderNames splits the names on a "diff --git" line. Either name
+// may be quoted. Unquoted names containing spaces are split where both
+// halves name the same file, which holds for everything but renames, whose
+// paths come from the "rename from/to" lines instead.
+func splitGitHeaderNames(names string) (string, string) {
+	names = strings.TrimSuffix(names, "\r")
+	if strings.HasPrefix(names, `"`) {
+		oldName, rest := cutQuoted(names)
+		return oldName, unquote(strings.TrimPrefix(rest, " "))
+	}
+	if i := strings.Index(names, ` "`); i >= 0 && strings.HasSuffix(names, `"`) {
+		return names[:i], unquote(names[i+1:])
+	}
+
+	// Try the midpoint first, then every space.
+	if mid := len(names) / 2; len(names)%2 == 1 && names[mid] == ' ' && samePath(names[:mid], names[mid+1:]) {
+		return names[:mid], names[mid+1:]
+	}
+	for i := 0; i < len(names); i++ {
+		if names[i] == ' ' && samePath(names[:i], names[i+1:]) {
+			return names[:i], names[i+1:]
+		}
+	}
+	if i := strings.LastIndex(names, " b/"); i >= 0 {
+		return names[:i], names[i+1:]
+	}
+	if oldName, newName, found := strings.Cut(names, " "); found {
+		return oldName, newName
+	}
+	return names, names
+}
+
+// samePath reports whether two header names refer to the same path once
+// their prefixes are removed.
+func samePath(a, b string) bool {
+	if usesPrefixes(a, b) {
+		return a[2:] == b[2:]
+	}
+	return a == b
+}
+
+// cutQuoted splits a leading C-style quoted string from s, returning the
+// unquoted value and the rest of s.
+func cutQuoted(s string) (string, string) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return unquote(s[:i+1]), s[i+1:]
+		}
+	}
+	return unquote(s), ""
+}
+
+// unquote decodes a path that git quoted because it contains special or
+// non-ASCII characters, such as "a/\303\251t\303\251.go". Unquoted input is
+// returned unchanged.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s[1 : len(s)-1]
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// countOrOne parses a hunk line count, which is omitted when it is 1.
+func countOrOne(s string) int {
+	if s == "" {
+		return 1
+	}
+	return atoi(s)
+}
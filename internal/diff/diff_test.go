@@ -0,0 +1,349 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fileSummary is the part of a File compared by the tests.
+type fileSummary struct {
+	OldPath, NewPath string
+	OldMode, NewMode string
+	IsNew, IsDeleted bool
+	IsRename, IsCopy bool
+	IsBinary         bool
+	Similarity       int
+	// Added lists each added line as "NEWLINE:TEXT".
+	Added []string
+}
+
+func summarize(files []File) []fileSummary {
+	var summaries []fileSummary
+	for _, f := range files {
+		s := fileSummary{
+			OldPath: f.OldPath, NewPath: f.NewPath,
+			OldMode: f.OldMode, NewMode: f.NewMode,
+			IsNew: f.IsNew, IsDeleted: f.IsDeleted,
+			IsRename: f.IsRename, IsCopy: f.IsCopy,
+			IsBinary:   f.IsBinary,
+			Similarity: f.Similarity,
+		}
+		for _, l := range f.AddedLines() {
+			s.Added = append(s.Added, fmt.Sprintf("%d:%s", l.NewLine, l.Text))
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+func readCorpus(t testing.TB, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	return string(data)
+}
+
+func TestParseCorpus(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected []fileSummary
+	}{
+		{
+			file: "git_mixed.diff",
+			expected: []fileSummary{
+				{OldPath: "a/b.go", NewPath: "a/b.go", OldMode: "100644", NewMode: "100644", Added: []string{"2:// HACK: prefix dir"}},
+				{NewPath: "added.go", NewMode: "100644", IsNew: true, Added: []string{"1:// NOTE: new"}},
+				{OldPath: "gone.txt", OldMode: "100644", IsDeleted: true},
+				{OldPath: "img.bin", NewPath: "img.bin", OldMode: "100644", NewMode: "100644", IsBinary: true},
+				{OldPath: "my file.go", NewPath: "my file.go", OldMode: "100644", NewMode: "100644", Added: []string{"2:// FIXME: spaced"}},
+				{OldPath: "old.go", NewPath: "new.go", OldMode: "100644", NewMode: "100644", IsRename: true, Similarity: 74, Added: []string{"5:// TODO: rename b"}},
+				{OldPath: "nn.txt", NewPath: "nn.txt", OldMode: "100644", NewMode: "100644", Added: []string{"1:no newline", "2:+++ still text"}},
+				{OldPath: "run.sh", NewPath: "run.sh", OldMode: "100644", NewMode: "100755"},
+				{OldPath: "été.go", NewPath: "été.go", OldMode: "100644", NewMode: "100644", Added: []string{"2:# TODO: accent"}},
+			},
+		},
+		{
+			file: "binary_patch.diff",
+			expected: []fileSummary{
+				{OldPath: "img.bin", NewPath: "img.bin", OldMode: "100644", NewMode: "100644", IsBinary: true},
+			},
+		},
+		{
+			file: "no_prefix.diff",
+			expected: []fileSummary{
+				{OldPath: "a/b.go", NewPath: "a/b.go", OldMode: "100644", NewMode: "100644", Added: []string{"2:// HACK: prefix dir"}},
+				{OldPath: "my file.go", NewPath: "my file.go", OldMode: "100644", NewMode: "100644", Added: []string{"2:// FIXME: spaced"}},
+			},
+		},
+		{
+			file: "copy_with_nested_diff.diff",
+			expected: []fileSummary{
+				{OldPath: "new.go", NewPath: "copy.go", OldMode: "100644", NewMode: "100644", IsCopy: true, Similarity: 82, Added: []string{"9:// XXX: copied"}},
+				{NewPath: "noprefix.diff", NewMode: "100644", IsNew: true, Added: []string{
+					"1:diff --git a/b.go a/b.go",
+					"2:index 2a93cde..6eabace 100644",
+					"3:--- a/b.go",
+					"4:+++ a/b.go",
+					"5:@@ -1 +1,2 @@",
+					"6: package a",
+					"7:+// HACK: prefix dir",
+					"8:diff --git my file.go my file.go",
+					"9:index 587be6b..4e4d920 100644",
+					"10:--- my file.go\t",
+					"11:+++ my file.go\t",
+					"12:@@ -1 +1,2 @@",
+					"13: x",
+					"14:+// FIXME: spaced",
+				}},
+			},
+		},
+		{
+			file: "plain_unified.diff",
+			expected: []fileSummary{
+				{OldPath: "p0", NewPath: "p1", Added: []string{"2:// BUG: plain"}},
+			},
+		},
+		{
+			file: "format_patch.diff",
+			expected: []fileSummary{
+				{NewPath: "sig.go", NewMode: "100644", IsNew: true, Added: []string{"1:-- ", "2:x", "3:// TODO: after dashes"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := summarize(Parse(readCorpus(t, tt.file)))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Parse() =\n%+v\nexpected\n%+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseLineNumbers(t *testing.T) {
+	files := Parse(`diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -10,4 +10,4 @@ func main() {
+ a
+-b
++c
+ d
+\ No newline at end of file`)
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("Parse() = %+v, expected one file with one hunk", files)
+	}
+	h := files[0].Hunks[0]
+	if h.Section != "func main() {" {
+		t.Errorf("Section = %q", h.Section)
+	}
+	want := []Line{
+		{Kind: Context, Text: "a", OldLine: 10, NewLine: 10},
+		{Kind: Deleted, Text: "b", OldLine: 11},
+		{Kind: Added, Text: "c", NewLine: 11},
+		{Kind: Context, Text: "d", OldLine: 12, NewLine: 12, NoNewlineAtEOF: true},
+	}
+	if !reflect.DeepEqual(h.Lines, want) {
+		t.Errorf("Lines = %+v, expected %+v", h.Lines, want)
+	}
+}
+
+func TestParseEmptyContextLine(t *testing.T) {
+	files := Parse("diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,4 @@\n a\n\n+// TODO: c\n d\n")
+	if len(files) != 1 || len(files[0].Hunks) != 1 {
+		t.Fatalf("Parse() = %+v, expected one file with one hunk", files)
+	}
+	want := []Line{
+		{Kind: Context, Text: "a", OldLine: 1, NewLine: 1},
+		{Kind: Context, OldLine: 2, NewLine: 2},
+		{Kind: Added, Text: "// TODO: c", NewLine: 3},
+		{Kind: Context, Text: "d", OldLine: 3, NewLine: 4},
+	}
+	if got := files[0].Hunks[0].Lines; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %+v, expected %+v", got, want)
+	}
+}
+
+func TestReaderMaxFileSize(t *testing.T) {
+	long := "+" + strings.Repeat("x", 200*1024)
+	text := "diff --git a/big.txt b/big.txt\n--- a/big.txt\n+++ b/big.txt\n@@ -1,0 +1,2 @@\n" + long + "\n+// TODO: lost\n" +
+		"--- small.go\n+++ small.go\n@@ -1,0 +1,1 @@\n+// TODO: kept\n"
+
+	r := NewReader(strings.NewReader(text), ReaderOptions{MaxFileSize: 1024})
+	var got []fileSummary
+	var oversized []bool
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		got = append(got, summarize([]File{f})...)
+		oversized = append(oversized, f.Oversized)
+	}
+
+	expected := []fileSummary{
+		{OldPath: "big.txt", NewPath: "big.txt"},
+		{OldPath: "small.go", NewPath: "small.go", Added: []string{"1:// TODO: kept"}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Next() =\n%+v\nexpected\n%+v", got, expected)
+	}
+	if !reflect.DeepEqual(oversized, []bool{true, false}) {
+		t.Errorf("Oversized = %v, expected [true false]", oversized)
+	}
+}
+
+func TestReaderLongLines(t *testing.T) {
+	long := strings.Repeat("y", 100*1024)
+	files := Parse("--- a/x\n+++ b/x\n@@ -0,0 +1,1 @@\n+" + long + "\n")
+	if len(files) != 1 || len(files[0].AddedLines()) != 1 || files[0].AddedLines()[0].Text != long {
+		t.Fatalf("Parse() did not keep a %d byte line", len(long))
+	}
+}
+
+// failingReader returns its text, then err.
+type failingReader struct {
+	text string
+	err  error
+}
+
+func (r *failingReader) Read(b []byte) (int, error) {
+	if r.text == "" {
+		return 0, r.err
+	}
+	n := copy(b, r.text)
+	r.text = r.text[n:]
+	return n, nil
+}
+
+func TestReaderError(t *testing.T) {
+	boom := errors.New("boom")
+	r := NewReader(&failingReader{text: "--- a/x\n+++ b/x\n@@ -0,0 +1,1 @@\n+done\n", err: boom}, ReaderOptions{})
+	f, err := r.Next()
+	if err != nil || f.NewPath != "x" {
+		t.Fatalf("Next() = %+v, %v, expected file x", f, err)
+	}
+	if _, err := r.Next(); err != boom {
+		t.Fatalf("Next() error = %v, expected %v", err, boom)
+	}
+}
+
+func TestSplitGitHeaderNames(t *testing.T) {
+	tests := []struct {
+		names   string
+		wantOld string
+		wantNew string
+	}{
+		{names: "a/main.go b/main.go", wantOld: "a/main.go", wantNew: "b/main.go"},
+		{names: "a/my file.go b/my file.go", wantOld: "a/my file.go", wantNew: "b/my file.go"},
+		{names: "a/b c/d b/e f", wantOld: "a/b c/d", wantNew: "b/e f"},
+		{names: `"a/tab\there" "b/tab\there"`, wantOld: "a/tab\there", wantNew: "b/tab\there"},
+		{names: `a/plain "b/\303\251"`, wantOld: "a/plain", wantNew: "b/é"},
+		{names: "x.go x.go", wantOld: "x.go", wantNew: "x.go"},
+	}
+	for _, tt := range tests {
+		gotOld, gotNew := splitGitHeaderNames(tt.names)
+		if gotOld != tt.wantOld || gotNew != tt.wantNew {
+			t.Errorf("splitGitHeaderNames(%q) = %q, %q, expected %q, %q", tt.names, gotOld, gotNew, tt.wantOld, tt.wantNew)
+		}
+	}
+}
+
+func TestHunkHeaderRegex(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		matches  bool
+		newStart string
+	}{
+		{"Basic hunk", "@@ -1,3 +1,4 @@", true, "1"},
+		{"Single line", "@@ -5 +5,2 @@", true, "5"},
+		{"Large line numbers", "@@ -100,50 +150,75 @@", true, "150"},
+		{"With context", "@@ -10,5 +15,8 @@ func test() {", true, "15"},
+		{"Invalid hunk", "not a hunk header", false, ""},
+		{"Partial hunk", "@@ -1,3", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := hunkHeaderRegex.FindStringSubmatch(tt.input)
+			if !tt.matches {
+				if matches != nil {
+					t.Errorf("Expected regex not to match %q, but it matched", tt.input)
+				}
+				return
+			}
+			if matches == nil {
+				t.Fatalf("Expected regex to match %q, but it didn't", tt.input)
+			}
+			if matches[3] != tt.newStart {
+				t.Errorf("Expected new start %q, got %q", tt.newStart, matches[3])
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	entries, err := os.ReadDir("testdata")
+	if err != nil {
+		f.Fatalf("ReadDir() error: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".diff") {
+			f.Add(readCorpus(f, e.Name()))
+		}
+	}
+	f.Add("@@ -1 +1 @@\n+x")
+	f.Add("diff --git \"a/unterminated b/x\n--- \"a\n+++ \"b\n@@ -0,0 +1,2 @@\n+")
+
+	f.Fuzz(func(t *testing.T, text string) {
+		for _, file := range Parse(text) {
+			if file.Path() == "" {
+				t.Fatalf("file without a path: %+v", file)
+			}
+			for _, h := range file.Hunks {
+				oldLine, newLine := h.OldStart, h.NewStart
+				var oldCount, newCount int
+				for _, l := range h.Lines {
+					switch l.Kind {
+					case Added:
+						if l.NewLine != newLine || l.OldLine != 0 {
+							t.Fatalf("added line numbered %d/%d, expected new line %d", l.OldLine, l.NewLine, newLine)
+						}
+						newLine++
+						newCount++
+					case Deleted:
+						if l.OldLine != oldLine || l.NewLine != 0 {
+							t.Fatalf("deleted line numbered %d/%d, expected old line %d", l.OldLine, l.NewLine, oldLine)
+						}
+						oldLine++
+						oldCount++
+					case Context:
+						if l.OldLine != oldLine || l.NewLine != newLine {
+							t.Fatalf("context line numbered %d/%d, expected %d/%d", l.OldLine, l.NewLine, oldLine, newLine)
+						}
+						oldLine++
+						newLine++
+						oldCount++
+						newCount++
+					}
+				}
+				if oldCount > h.OldLines || newCount > h.NewLines {
+					t.Fatalf("hunk %+v has %d old and %d new lines, more than its header", h, oldCount, newCount)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteHeader writes the header of f as git does: the "diff --git" line,
+// extended headers for new, deleted, renamed, copied, and binary files, and
+// unless f is binary, the "---" and "+++" lines. The hunks are left to
+// WriteHunks.
+func WriteHeader(w io.Writer, f File) error {
+	b := bufio.NewWriter(w)
+	oldPath, newPath := f.OldPath, f.NewPath
+	if oldPath == "" {
+		oldPath = newPath
+	}
+	if newPath == "" {
+		newPath = oldPath
+	}
+	fmt.Fprintf(b, "diff --git %s %s\n", quotePath("a/"+oldPath), quotePath("b/"+newPath))
+	switch {
+	case f.IsNew:
+		fmt.Fprintf(b, "new file mode %s\n", modeOrDefault(f.NewMode))
+	case f.IsDeleted:
+		fmt.Fprintf(b, "deleted file mode %s\n", modeOrDefault(f.OldMode))
+	case f.OldMode != "" && f.NewMode != "" && f.OldMode != f.NewMode:
+		fmt.Fprintf(b, "old mode %s\nnew mode %s\n", f.OldMode, f.NewMode)
+	}
+	if f.IsRename || f.IsCopy {
+		verb := "rename"
+		if f.IsCopy {
+			verb = "copy"
+		}
+		if f.Similarity > 0 {
+			fmt.Fprintf(b, "similarity index %d%%\n", f.Similarity)
+		}
+		fmt.Fprintf(b, "%s from %s\n%s to %s\n", verb, quotePath(oldPath), verb, quotePath(newPath))
+	}
+
+	oldName, newName := quotePath("a/"+oldPath), quotePath("b/"+newPath)
+	if f.IsNew {
+		oldName = DevNull
+	}
+	if f.IsDeleted {
+		newName = DevNull
+	}
+	if f.IsBinary {
+		fmt.Fprintf(b, "Binary files %s and %s differ\n", oldName, newName)
+	} else {
+		fmt.Fprintf(b, "--- %s\n+++ %s\n", oldName, newName)
+	}
+	return b.Flush()
+}
+
+// WriteHunks writes hunks in unified diff format.
+func WriteHunks(w io.Writer, hunks []Hunk) error {
+	b := bufio.NewWriter(w)
+	for _, h := range hunks {
+		fmt.Fprintf(b, "@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+		if h.Section != "" {
+			b.WriteString(" " + h.Section)
+		}
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case Added:
+				b.WriteByte('+')
+			case Deleted:
+				b.WriteByte('-')
+			default:
+				b.WriteByte(' ')
+			}
+			b.WriteString(l.Text)
+			b.WriteByte('\n')
+			if l.NoNewlineAtEOF {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.Flush()
+}
+
+// Write writes f, header and hunks, as a git diff.
+func Write(w io.Writer, f File) error {
+	if err := WriteHeader(w, f); err != nil {
+		return err
+	}
+	return WriteHunks(w, f.Hunks)
+}
+
+// hunkRange formats the start and count of one side of a hunk header,
+// omitting a count of 1.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func modeOrDefault(mode string) string {
+	if mode == "" {
+		return "100644"
+	}
+	return mode
+}
+
+// quotePath quotes name as git does when it contains quotes, backslashes,
+// or control characters, which would otherwise be misread.
+func quotePath(name string) string {
+	if strings.IndexFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7f || r == '"' || r == '\\' }) < 0 {
+		return name
+	}
+	return strconv.Quote(name)
+}
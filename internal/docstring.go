@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+	"github.com/odvcencio/gotreesitter"
+)
+
+// docstringSyntax describes how a language writes documentation strings,
+// which its grammar parses as string literals rather than comments.
+type docstringSyntax struct {
+	// find returns the documentation strings among the children of node.
+	find func(node *gotreesitter.Node, bt *gotreesitter.BoundTree) []*gotreesitter.Node
+	// open matches the text before the documentation on its first line, and
+	// close the text after it on its last line.
+	open, close *regexp.Regexp
+}
+
+var (
+	pythonDocstrings = docstringSyntax{
+		find:  pythonDocstringNodes,
+		open:  regexp.MustCompile(`^\s*[rRuU]?("""|'''|"|')`),
+		close: regexp.MustCompile(`("""|'''|"|')\s*$`),
+	}
+	elixirDocstrings = docstringSyntax{
+		find:  matchingChildren(regexp.MustCompile(`^@(?:module|type)?doc\s`), "unary_operator"),
+		open:  regexp.MustCompile(`^\s*@(?:module|type)?doc\s+(?:~[sS])?("""|'''|"|')`),
+		close: regexp.MustCompile(`("""|'''|"|')\s*$`),
+	}
+	rustDocstrings = docstringSyntax{
+		find:  matchingChildren(regexp.MustCompile(`^#!?\[\s*doc\s*=`), "attribute_item", "inner_attribute_item"),
+		open:  regexp.MustCompile(`^\s*#!?\[\s*doc\s*=\s*r?#*"`),
+		close: regexp.MustCompile(`"#*\s*\]\s*$`),
+	}
+	// scriptDocstrings are template literals standing alone as a statement,
+	// a common way to attach long documentation to JavaScript and
+	// TypeScript modules.
+	scriptDocstrings = docstringSyntax{
+		find:  scriptDocstringNodes,
+		open:  regexp.MustCompile("^\\s*`"),
+		close: regexp.MustCompile("`\\s*$"),
+	}
+)
+
+// docstringLanguages maps file extensions to their documentation string
+// syntax.
+var docstringLanguages = map[string]docstringSyntax{
+	".cjs": scriptDocstrings,
+	".ex":  elixirDocstrings,
+	".exs": elixirDocstrings,
+	".js":  scriptDocstrings,
+	".jsx": scriptDocstrings,
+	".mjs": scriptDocstrings,
+	".py":  pythonDocstrings,
+	".pyi": pythonDocstrings,
+	".rs":  rustDocstrings,
+	".ts":  scriptDocstrings,
+	".tsx": scriptDocstrings,
+}
+
+// pythonDocstringNodes returns the docstring of a module, class, or
+// function: a string literal that is the first statement of its body.
+func pythonDocstringNodes(node *gotreesitter.Node, bt *gotreesitter.BoundTree) []*gotreesitter.Node {
+	body := node
+	switch bt.NodeType(node) {
+	case "module":
+	case "class_definition", "function_definition":
+		body = childOfType(node, bt, "block")
+		if body == nil {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	for i := 0; i < body.ChildCount(); i++ {
+		stmt := body.Child(i)
+		if stmt == nil || isCommentNode(bt.NodeType(stmt)) {
+			continue
+		}
+		if bt.NodeType(stmt) == "expression_statement" && stmt.ChildCount() == 1 {
+			if s := stmt.Child(0); s != nil && bt.NodeType(s) == "string" {
+				return []*gotreesitter.Node{s}
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// scriptDocstringNodes returns the template literals among the children of
+// node that make up a whole expression statement.
+func scriptDocstringNodes(node *gotreesitter.Node, bt *gotreesitter.BoundTree) []*gotreesitter.Node {
+	var found []*gotreesitter.Node
+	for i := 0; i < node.ChildCount(); i++ {
+		stmt := node.Child(i)
+		if stmt == nil || bt.NodeType(stmt) != "expression_statement" || stmt.ChildCount() == 0 || stmt.ChildCount() > 2 {
+			continue
+		}
+		if s := stmt.Child(0); s != nil && bt.NodeType(s) == "template_string" {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// matchingChildren returns a finder for the children of a node that have one
+// of the given types and whose text matches re.
+func matchingChildren(re *regexp.Regexp, nodeTypes ...string) func(*gotreesitter.Node, *gotreesitter.BoundTree) []*gotreesitter.Node {
+	return func(node *gotreesitter.Node, bt *gotreesitter.BoundTree) []*gotreesitter.Node {
+		var found []*gotreesitter.Node
+		for i := 0; i < node.ChildCount(); i++ {
+			child := node.Child(i)
+			if child == nil {
+				continue
+			}
+			for _, t := range nodeTypes {
+				if bt.NodeType(child) == t && re.MatchString(bt.NodeText(child)) {
+					found = append(found, child)
+					break
+				}
+			}
+		}
+		return found
+	}
+}
+
+// childOfType returns the first direct child of node with the given type, or
+// nil.
+func childOfType(node *gotreesitter.Node, bt *gotreesitter.BoundTree, nodeType string) *gotreesitter.Node {
+	for i := 0; i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child != nil && bt.NodeType(child) == nodeType {
+			return child
+		}
+	}
+	return nil
+}
+
+// extractTODOsFromDocstrings reports "MARKER:" lines in the documentation
+// strings among the children of node that intersect with added lines.
+// Documentation is prose, so markers must be uppercase and followed by a
+// colon, as in Markdown paragraphs.
+func extractTODOsFromDocstrings(node *gotreesitter.Node, bt *gotreesitter.BoundTree, fc fileChange, symbol string, todos *[]types.TODO, re *regexp.Regexp) {
+	syntax, ok := docstringLanguages[strings.ToLower(path.Ext(fc.path))]
+	if !ok {
+		return
+	}
+
+	for _, doc := range syntax.find(node, bt) {
+		startLine := int(doc.StartPoint().Row) + 1
+		lines := strings.Split(bt.NodeText(doc), "\n")
+		lines[0] = syntax.open.ReplaceAllString(lines[0], "")
+		last := len(lines) - 1
+		lines[last] = syntax.close.ReplaceAllString(lines[last], "")
+
+		for i, line := range lines {
+			fileLine := startLine + i
+			if !lineInRanges(fileLine, fc.addedRanges) {
+				continue
+			}
+			if matches := re.FindStringSubmatch(line); len(matches) > 2 {
+				*todos = append(*todos, types.TODO{
+					Filename: fc.path,
+					Line:     fileLine,
+					Comment:  strings.TrimSpace(matches[1]),
+					Type:     matches[2],
+					Symbol:   symbol,
+				})
+			}
+		}
+	}
+}
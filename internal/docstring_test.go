@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestParseDiffWithOptionsDocstrings(t *testing.T) {
+	defaultTypes := []string{"TODO", "FIXME", "HACK", "NOTE", "XXX", "BUG"}
+
+	tests := []struct {
+		name       string
+		path       string
+		content    string
+		docstrings bool
+		expected   []types.TODO
+	}{
+		{
+			name:       "Python module and function docstrings",
+			path:       "app.py",
+			content:    "\"\"\"FIXME: module docs.\"\"\"\n\n\ndef bar():\n    \"\"\"Return bar.\n\n    TODO: handle None.\n    Note that bar is cached.\n    \"\"\"\n    msg = \"TODO: not a docstring\"\n    return msg\n",
+			docstrings: true,
+			expected: []types.TODO{
+				{Filename: "app.py", Line: 1, Comment: "FIXME: module docs.", Type: "FIXME"},
+				{Filename: "app.py", Line: 7, Comment: "TODO: handle None.", Type: "TODO", Symbol: "def bar"},
+			},
+		},
+		{
+			name:       "Python docstrings ignored when disabled",
+			path:       "app.py",
+			content:    "def bar():\n    \"\"\"TODO: handle None.\"\"\"\n    return 1\n",
+			docstrings: false,
+			expected:   nil,
+		},
+		{
+			name:       "Rust doc attributes",
+			path:       "lib.rs",
+			content:    "#![doc = \"NOTE: crate docs\"]\n\n#[doc = \"HACK: undocumented invariant\"]\nfn run() {\n    let s = \"TODO: plain string\";\n}\n",
+			docstrings: true,
+			expected: []types.TODO{
+				{Filename: "lib.rs", Line: 1, Comment: "NOTE: crate docs", Type: "NOTE"},
+				{Filename: "lib.rs", Line: 3, Comment: "HACK: undocumented invariant", Type: "HACK"},
+			},
+		},
+		{
+			name:       "Elixir module and function docs",
+			path:       "lib/app.ex",
+			content:    "defmodule App do\n  @moduledoc \"\"\"\n  TODO: describe the app.\n  \"\"\"\n\n  @doc \"BUG: crashes on empty input\"\n  def run(x), do: x\nend\n",
+			docstrings: true,
+			expected: []types.TODO{
+				{Filename: "lib/app.ex", Line: 3, Comment: "TODO: describe the app.", Type: "TODO"},
+				{Filename: "lib/app.ex", Line: 6, Comment: "BUG: crashes on empty input", Type: "BUG"},
+			},
+		},
+		{
+			name:       "Standalone template literal in JavaScript",
+			path:       "index.js",
+			content:    "`\nXXX: document the exports\n`;\nconst s = `TODO: not documentation`;\n",
+			docstrings: true,
+			expected: []types.TODO{
+				{Filename: "index.js", Line: 2, Comment: "XXX: document the exports", Type: "XXX"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{tt.path: []byte(tt.content)}
+			result := ParseDiffWithOptions(newFileDiff(tt.path, tt.content), files, ParseOptions{
+				Types:      defaultTypes,
+				Docstrings: tt.docstrings,
+			})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithOptions() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
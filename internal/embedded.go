@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+var (
+	// embeddedOpenRegex matches an opening <script> or <style> tag and
+	// captures the tag name and its attributes.
+	embeddedOpenRegex = regexp.MustCompile(`(?i)<(script|style)\b([^>]*)>`)
+	// embeddedAttrRegex matches a lang or type attribute and captures its
+	// name and value.
+	embeddedAttrRegex = regexp.MustCompile(`(?i)\b(lang|type)\s*=\s*["']?([^"'\s>]+)`)
+	// embeddedCloseRegex matches the closing tag of each embedded block.
+	embeddedCloseRegex = map[string]*regexp.Regexp{
+		"script": regexp.MustCompile(`(?i)</script\s*>`),
+		"style":  regexp.MustCompile(`(?i)</style\s*>`),
+	}
+)
+
+// embeddedDocumentExtensions lists the markup file types whose <script> and
+// <style> blocks are written in other languages.
+var embeddedDocumentExtensions = map[string]bool{
+	".astro":  true,
+	".htm":    true,
+	".html":   true,
+	".svelte": true,
+	".vue":    true,
+	".xhtml":  true,
+}
+
+// scriptTypeLanguages maps <script type="..."> values to languages. Types not
+// listed here, such as "application/ld+json", mark data blocks, which are
+// skipped.
+var scriptTypeLanguages = map[string]string{
+	"application/javascript": "javascript",
+	"application/typescript": "typescript",
+	"module":                 "javascript",
+	"text/babel":             "jsx",
+	"text/html":              "html",
+	"text/javascript":        "javascript",
+	"text/jsx":               "jsx",
+	"text/typescript":        "typescript",
+	"text/x-template":        "html",
+}
+
+// embeddedBlock is a <script> or <style> block, or Astro frontmatter, inside
+// a markup document.
+type embeddedBlock struct {
+	language string
+	// start and end are the byte offsets of the block contents in the
+	// document text.
+	start, end int
+}
+
+// isEmbeddedDocument reports whether filename is a markup document with
+// embedded script and style blocks.
+func isEmbeddedDocument(filename string) bool {
+	return embeddedDocumentExtensions[strings.ToLower(path.Ext(filename))]
+}
+
+// parseEmbeddedTODOs extracts TODO comments from an HTML, Vue, Svelte, or
+// Astro file.
+func parseEmbeddedTODOs(fc fileChange, content []byte, p markerPatterns) []types.TODO {
+	return parseEmbeddedRegionTODOs(fc, fileRegion(fc.path, content), p)
+}
+
+// parseEmbeddedRegionTODOs extracts TODO comments from a markup region on
+// lines that map to added file lines. Each embedded block is parsed with the
+// grammar of its language; the remaining markup is checked for HTML comments
+// (and JSX comments in Astro).
+func parseEmbeddedRegionTODOs(fc fileChange, r sourceRegion, p markerPatterns) []types.TODO {
+	local := regionChange(fc, r)
+	if len(local.addedRanges) == 0 {
+		return nil
+	}
+
+	ext := strings.ToLower(path.Ext(r.filename))
+	text := strings.Join(r.lines, "\n")
+	markup := []byte(text)
+
+	var todos []types.TODO
+	for _, b := range findEmbeddedBlocks(text, ext) {
+		todos = append(todos, b.parse(local, text, p)...)
+		// Blank out the block so that its contents are not mistaken for
+		// markup, keeping newlines so line numbers are unchanged.
+		for i := b.start; i < b.end; i++ {
+			if markup[i] != '\n' {
+				markup[i] = ' '
+			}
+		}
+	}
+
+	scanner := delimitedCommentScanner{delimiters: htmlDelimiters}
+	if ext == ".astro" {
+		scanner.delimiters = jsxDelimiters
+	}
+	for i, line := range strings.Split(string(markup), "\n") {
+		segments := scanner.scan(line)
+		if len(segments) > 0 && lineInRanges(i+1, local.addedRanges) {
+			todos = append(todos, commentSegmentTODOs(segments, i+1, p.body)...)
+		}
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool { return todos[i].Line < todos[j].Line })
+	return r.remap(fc.path, todos)
+}
+
+// findEmbeddedBlocks returns the embedded blocks in a markup document, in
+// order. Tags inside HTML comments are ignored. An unclosed block runs to the
+// end of the document.
+func findEmbeddedBlocks(text, ext string) []embeddedBlock {
+	var blocks []embeddedBlock
+	pos := 0
+	if ext == ".astro" {
+		if fm, ok := astroFrontmatter(text); ok {
+			blocks = append(blocks, fm)
+			pos = fm.end
+		}
+	}
+
+	for pos < len(text) {
+		loc := embeddedOpenRegex.FindStringSubmatchIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		if c := strings.Index(text[pos:], "<!--"); c >= 0 && c < loc[0] {
+			end := strings.Index(text[pos+c:], "-->")
+			if end < 0 {
+				break
+			}
+			pos += c + end + len("-->")
+			continue
+		}
+
+		tag := strings.ToLower(text[pos+loc[2] : pos+loc[3]])
+		attrs := text[pos+loc[4] : pos+loc[5]]
+		start := pos + loc[1]
+		if strings.HasSuffix(strings.TrimSpace(attrs), "/") {
+			pos = start
+			continue
+		}
+
+		block := embeddedBlock{language: embeddedLanguage(tag, attrs), start: start, end: len(text)}
+		pos = len(text)
+		if c := embeddedCloseRegex[tag].FindStringIndex(text[start:]); c != nil {
+			block.end = start + c[0]
+			pos = start + c[1]
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// astroFrontmatter returns the TypeScript frontmatter block fenced by "---"
+// lines at the top of an Astro component.
+func astroFrontmatter(text string) (embeddedBlock, bool) {
+	first, rest, found := strings.Cut(text, "\n")
+	if !found || strings.TrimSpace(first) != "---" {
+		return embeddedBlock{}, false
+	}
+	start := len(first) + 1
+	offset := start
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		if strings.TrimSpace(line) == "---" {
+			return embeddedBlock{language: "typescript", start: start, end: offset}, true
+		}
+		offset += len(line) + 1
+		rest = next
+	}
+	return embeddedBlock{}, false
+}
+
+// embeddedLanguage returns the language of a <script> or <style> block from
+// its lang or type attribute, or "" for data blocks.
+func embeddedLanguage(tag, attrs string) string {
+	var lang, typ string
+	for _, m := range embeddedAttrRegex.FindAllStringSubmatch(attrs, -1) {
+		switch strings.ToLower(m[1]) {
+		case "lang":
+			lang = strings.ToLower(m[2])
+		case "type":
+			typ = strings.ToLower(m[2])
+		}
+	}
+	switch {
+	case lang != "":
+		return lang
+	case tag == "style":
+		return "css"
+	case typ == "":
+		return "javascript"
+	}
+	return scriptTypeLanguages[typ]
+}
+
+// parse extracts TODO comments from the block contents using the grammar of
+// its language.
+func (b embeddedBlock) parse(local fileChange, text string, p markerPatterns) []types.TODO {
+	if b.language == "" {
+		return nil
+	}
+	startLine := strings.Count(text[:b.start], "\n") + 1
+	r := sourceRegion{
+		filename: regionFilename(b.language),
+		lines:    strings.Split(text[b.start:b.end], "\n"),
+	}
+	for i := range r.lines {
+		r.fileLines = append(r.fileLines, startLine+i)
+	}
+	return parseRegionTODOs(local, r, p)
+}
@@ -0,0 +1,111 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestParseDiffWithContentsEmbedded(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		expected []types.TODO
+	}{
+		{
+			name: "Vue single-file component",
+			path: "src/App.vue",
+			content: `<template>
+  <!-- TODO: add aria labels -->
+  <div>{{ msg }}</div>
+</template>
+
+<script setup lang="ts">
+// FIXME: type the props
+const msg = 'hi'
+</script>
+
+<style scoped lang="scss">
+/* HACK: override vendor */
+.a { color: red; }
+</style>
+`,
+			expected: []types.TODO{
+				{Filename: "src/App.vue", Line: 2, Comment: "<!-- TODO: add aria labels -->", Type: "TODO"},
+				{Filename: "src/App.vue", Line: 7, Comment: "// FIXME: type the props", Type: "FIXME"},
+				{Filename: "src/App.vue", Line: 12, Comment: "/* HACK: override vendor */", Type: "HACK"},
+			},
+		},
+		{
+			name: "Svelte component with commented-out script",
+			path: "src/Counter.svelte",
+			content: `<script context="module" lang="ts">
+  export const prerender = true; // TODO: revisit
+</script>
+<script>
+  let count = 0;
+</script>
+<!-- <script>// NOTE: commented out</script> -->
+<button on:click={() => count++}>{count}</button>
+`,
+			expected: []types.TODO{
+				{Filename: "src/Counter.svelte", Line: 2, Comment: "// TODO: revisit", Type: "TODO"},
+			},
+		},
+		{
+			name: "Astro frontmatter and JSX comments",
+			path: "src/pages/index.astro",
+			content: `---
+// TODO: fetch from CMS
+const title = "Home";
+---
+<h1>{title}</h1>
+{/* FIXME: add subtitle */}
+<script>
+  /* XXX: analytics */
+</script>
+`,
+			expected: []types.TODO{
+				{Filename: "src/pages/index.astro", Line: 2, Comment: "// TODO: fetch from CMS", Type: "TODO"},
+				{Filename: "src/pages/index.astro", Line: 6, Comment: "{/* FIXME: add subtitle */}", Type: "FIXME"},
+				{Filename: "src/pages/index.astro", Line: 8, Comment: "/* XXX: analytics */", Type: "XXX"},
+			},
+		},
+		{
+			name: "HTML inline script and data blocks",
+			path: "index.html",
+			content: `<p>Hi</p><script>// BUG: inline</script><!-- NOTE: footer -->
+<script type="application/ld+json">{"@context": "https://schema.org"}</script>
+<script src="app.js" />
+<style>
+  body { margin: 0; } /* TODO: reset */
+</style>
+`,
+			expected: []types.TODO{
+				{Filename: "index.html", Line: 1, Comment: "// BUG: inline", Type: "BUG"},
+				{Filename: "index.html", Line: 1, Comment: "<!-- NOTE: footer -->", Type: "NOTE"},
+				{Filename: "index.html", Line: 5, Comment: "/* TODO: reset */", Type: "TODO"},
+			},
+		},
+		{
+			name:    "HTML fenced in Markdown",
+			path:    "README.md",
+			content: "# Embed\n\n```html\n<script>\n  // TODO: in fence\n</script>\n```\n",
+			expected: []types.TODO{
+				{Filename: "README.md", Line: 5, Comment: "// TODO: in fence", Type: "TODO"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{tt.path: []byte(tt.content)}
+			result := ParseDiffWithContents(newFileDiff(tt.path, tt.content), files)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithContents() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
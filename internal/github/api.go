@@ -0,0 +1,349 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+// Media types selecting the representation of REST API responses.
+const (
+	jsonMediaType = "application/vnd.github+json"
+	rawMediaType  = "application/vnd.github.raw+json"
+	diffMediaType = "application/vnd.github.diff"
+)
+
+// Replaced in tests.
+var (
+	currentRepository = repository.Current
+	currentBranch     = gitCurrentBranch
+)
+
+// repoRef identifies a repository on a GitHub host.
+type repoRef struct {
+	host  string
+	owner string
+	name  string
+}
+
+func (r repoRef) nameWithOwner() string {
+	return r.owner + "/" + r.name
+}
+
+// prRef identifies a pull request. For a branch without an open pull
+// request, number is zero and the ref identifies the comparison of the
+// branch head with base, or with the default branch if base is empty,
+// which stands in for the pull request that has yet to be opened.
+type prRef struct {
+	repo   repoRef
+	number int
+	head   string
+	base   string
+}
+
+// headRef returns the git ref of the head of pr.
+func (pr prRef) headRef() string {
+	if pr.number == 0 {
+		return "refs/heads/" + pr.head
+	}
+	return fmt.Sprintf("refs/pull/%d/head", pr.number)
+}
+
+// restKey identifies a REST client by host and the media type it accepts.
+type restKey struct {
+	host   string
+	accept string
+}
+
+// newClient returns a Client whose API clients are built from opts, with
+// the host and Accept header set per request.
+func newClient(opts api.ClientOptions) *Client {
+	opts.Transport = conditionalTransport{base: opts.Transport}
+	return &Client{
+		opts:    opts,
+		rest:    make(map[restKey]*api.RESTClient),
+		graphql: make(map[string]*api.GraphQLClient),
+		prs:     make(map[[2]string]prRef),
+		metas:   make(map[prRef]repoMeta),
+		bases:   make(map[mergeBaseKey]string),
+	}
+}
+
+// defaultHost returns the host of repositories given without one: the
+// configured host, or GH_HOST, or the host gh is logged in to.
+func (c *Client) defaultHost() string {
+	if c.opts.Host != "" {
+		return c.opts.Host
+	}
+	host, _ := auth.DefaultHost()
+	return host
+}
+
+// clientOptions returns the options of an API client for host, reporting an
+// ErrAuth error if no token is configured for it.
+func (c *Client) clientOptions(host string) (api.ClientOptions, error) {
+	opts := c.opts
+	opts.Host = host
+	if opts.AuthToken == "" {
+		opts.AuthToken, _ = auth.TokenForHost(host)
+	}
+	if opts.AuthToken == "" {
+		hint := "GH_TOKEN"
+		if auth.IsEnterprise(host) {
+			hint = "GH_ENTERPRISE_TOKEN"
+		}
+		return opts, &APIError{Kind: ErrAuth, Err: fmt.Errorf("no authentication token found for %s; run `gh auth login` or set %s", host, hint)}
+	}
+	return opts, nil
+}
+
+// restClient returns the REST client for host that accepts the media type
+// accept, creating it on first use.
+func (c *Client) restClient(host, accept string) (*api.RESTClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := restKey{host: host, accept: accept}
+	if client, ok := c.rest[key]; ok {
+		return client, nil
+	}
+	opts, err := c.clientOptions(host)
+	if err != nil {
+		return nil, err
+	}
+	opts.Headers = map[string]string{"Accept": accept}
+	client, err := api.NewRESTClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.rest[key] = client
+	return client, nil
+}
+
+// graphQLClient returns the GraphQL client for host, creating it on first
+// use.
+func (c *Client) graphQLClient(host string) (*api.GraphQLClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.graphql[host]; ok {
+		return client, nil
+	}
+	opts, err := c.clientOptions(host)
+	if err != nil {
+		return nil, err
+	}
+	client, err := api.NewGraphQLClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.graphql[host] = client
+	return client, nil
+}
+
+// get requests path from the REST API of host and returns the response
+// body, which the caller must close.
+func (c *Client) get(ctx context.Context, host, path, accept string) (io.ReadCloser, error) {
+	resp, err := c.getResponse(ctx, host, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// getResponse is get returning the whole response, for its headers.
+func (c *Client) getResponse(ctx context.Context, host, path, accept string) (*http.Response, error) {
+	client, err := c.restClient(host, accept)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.RequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return resp, nil
+}
+
+// getBytes is get with the body read in full.
+func (c *Client) getBytes(ctx context.Context, host, path, accept string) ([]byte, error) {
+	body, err := c.get(ctx, host, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// query runs a GraphQL query against host, decoding the data into resp.
+func (c *Client) query(ctx context.Context, host, query string, vars map[string]any, resp any) error {
+	client, err := c.graphQLClient(host)
+	if err != nil {
+		return err
+	}
+	return classifyError(client.DoWithContext(ctx, query, vars, resp))
+}
+
+// resolveRepo parses a [HOST/]OWNER/REPO argument, defaulting to the
+// repository of the current directory's git remotes, or GH_REPO.
+func (c *Client) resolveRepo(repo string) (repoRef, error) {
+	var (
+		r   repository.Repository
+		err error
+	)
+	if repo == "" {
+		r, err = currentRepository()
+	} else {
+		r, err = repository.ParseWithHost(repo, c.defaultHost())
+	}
+	if err != nil {
+		return repoRef{}, err
+	}
+	return repoRef{host: r.Host, owner: r.Owner, name: r.Name}, nil
+}
+
+// resolvePR identifies the pull request selected by a repo and PR argument
+// the way `gh pr view` does. The PR may be a number, "#number", a URL, or a
+// head branch, optionally as "OWNER:BRANCH"; empty means the PR for the
+// current branch. A named branch of the repository without an open PR, or
+// the current one once SetCompareBase has set a base, resolves to its
+// comparison with that base. Resolutions are remembered for the life of
+// the Client.
+func (c *Client) resolvePR(ctx context.Context, repo, pr string) (prRef, error) {
+	key := [2]string{repo, pr}
+	c.mu.Lock()
+	ref, ok := c.prs[key]
+	c.mu.Unlock()
+	if ok {
+		return ref, nil
+	}
+
+	ref, err := c.lookupPR(ctx, repo, pr)
+	if err != nil {
+		return prRef{}, err
+	}
+	c.mu.Lock()
+	c.prs[key] = ref
+	c.mu.Unlock()
+	return ref, nil
+}
+
+func (c *Client) lookupPR(ctx context.Context, repo, pr string) (prRef, error) {
+	if ref, ok := parsePRURL(pr); ok {
+		return ref, nil
+	}
+
+	r, err := c.resolveRepo(repo)
+	if err != nil {
+		return prRef{}, err
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(pr, "#")); err == nil && n > 0 {
+		return prRef{repo: r, number: n}, nil
+	}
+
+	branch := pr
+	if branch == "" {
+		if branch, err = currentBranch(ctx); err != nil {
+			return prRef{}, err
+		}
+	}
+	n, err := c.findPRForBranch(ctx, r, branch)
+	// A branch without an open PR is compared with its base only when it
+	// was named or a base was given, so that a run on the default branch
+	// fails rather than comparing it with itself.
+	if errors.Is(err, ErrNotFound) && !strings.Contains(branch, ":") && (pr != "" || c.compareBase != "") {
+		return prRef{repo: r, head: branch, base: c.compareBase}, nil
+	}
+	if err != nil {
+		return prRef{}, err
+	}
+	return prRef{repo: r, number: n}, nil
+}
+
+// parsePRURL parses a pull request URL such as
+// https://github.com/OWNER/REPO/pull/123, ignoring any trailing path such
+// as "/files".
+func parsePRURL(s string) (prRef, bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return prRef{}, false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] == "" || parts[1] == "" || parts[2] != "pull" {
+		return prRef{}, false
+	}
+	n, err := strconv.Atoi(parts[3])
+	if err != nil || n <= 0 {
+		return prRef{}, false
+	}
+	return prRef{repo: repoRef{host: u.Hostname(), owner: parts[0], name: parts[1]}, number: n}, true
+}
+
+const prForBranchQuery = `query($owner: String!, $name: String!, $branch: String!) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(headRefName: $branch, states: OPEN, first: 30, orderBy: {field: CREATED_AT, direction: DESC}) {
+      nodes { number headRepositoryOwner { login } }
+    }
+  }
+}`
+
+// findPRForBranch returns the newest open pull request whose head is
+// branch, which may be given as "OWNER:BRANCH" to select a fork's branch.
+func (c *Client) findPRForBranch(ctx context.Context, r repoRef, branch string) (int, error) {
+	owner, name, hasOwner := strings.Cut(branch, ":")
+	if !hasOwner {
+		owner, name = "", branch
+	}
+
+	var resp struct {
+		Repository struct {
+			PullRequests struct {
+				Nodes []struct {
+					Number              int
+					HeadRepositoryOwner struct {
+						Login string
+					}
+				}
+			}
+		}
+	}
+	vars := map[string]any{"owner": r.owner, "name": r.name, "branch": name}
+	if err := c.query(ctx, r.host, prForBranchQuery, vars, &resp); err != nil {
+		return 0, err
+	}
+	for _, node := range resp.Repository.PullRequests.Nodes {
+		if owner == "" || strings.EqualFold(node.HeadRepositoryOwner.Login, owner) {
+			return node.Number, nil
+		}
+	}
+	return 0, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("no open pull requests found for branch %q", branch)}
+}
+
+// gitCurrentBranch returns the branch checked out in the current directory.
+func gitCurrentBranch(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", errors.New("could not determine current branch: not on any branch")
+		}
+		return "", fmt.Errorf("could not determine current branch: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// contentsPath returns the REST API path of a file at ref.
+func contentsPath(r repoRef, path, ref string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return fmt.Sprintf("repos/%s/%s/contents/%s?ref=%s", url.PathEscape(r.owner), url.PathEscape(r.name), strings.Join(segments, "/"), url.QueryEscape(ref))
+}
@@ -0,0 +1,188 @@
+package github
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/repository"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "unauthorized", err: &api.HTTPError{StatusCode: 401}, want: ErrAuth},
+		{name: "not found", err: &api.HTTPError{StatusCode: 404}, want: ErrNotFound},
+		{name: "forbidden", err: &api.HTTPError{StatusCode: 403, Headers: http.Header{}}, want: ErrPermission},
+		{name: "rate limited", err: &api.HTTPError{StatusCode: 403, Headers: http.Header{"X-Ratelimit-Remaining": {"0"}}}, want: ErrRateLimited},
+		{name: "too many requests", err: &api.HTTPError{StatusCode: 429}, want: ErrRateLimited},
+		{name: "server error", err: &api.HTTPError{StatusCode: 500}},
+		{name: "GraphQL not found", err: &api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: "NOT_FOUND"}}}, want: ErrNotFound},
+		{name: "GraphQL forbidden", err: &api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: "FORBIDDEN"}}}, want: ErrPermission},
+		{name: "GraphQL rate limited", err: &api.GraphQLError{Errors: []api.GraphQLErrorItem{{Type: "RATE_LIMITED"}}}, want: ErrRateLimited},
+	}
+	kinds := []error{ErrNotFound, ErrAuth, ErrRateLimited, ErrPermission}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			for _, kind := range kinds {
+				if got := errors.Is(err, kind); got != (kind == tt.want) {
+					t.Errorf("errors.Is(err, %v) = %v", kind, got)
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("classifyError() = %v, expected it to wrap %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParsePRURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   prRef
+		wantOK bool
+	}{
+		{
+			url:    "https://github.com/owner/repo/pull/12",
+			want:   prRef{repo: repoRef{host: "github.com", owner: "owner", name: "repo"}, number: 12},
+			wantOK: true,
+		},
+		{
+			url:    "https://ghe.example.com/owner/repo/pull/7/files",
+			want:   prRef{repo: repoRef{host: "ghe.example.com", owner: "owner", name: "repo"}, number: 7},
+			wantOK: true,
+		},
+		{url: "https://github.com/owner/repo/issues/12"},
+		{url: "https://github.com/owner/repo/pull/abc"},
+		{url: "feature/pull/12"},
+		{url: "12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, ok := parsePRURL(tt.url)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parsePRURL() = %+v, %v, expected %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolvePR(t *testing.T) {
+	original := currentRepository
+	currentRepository = func() (repository.Repository, error) {
+		return repository.Repository{Host: "github.com", Owner: "cur", Name: "repo"}, nil
+	}
+	t.Cleanup(func() { currentRepository = original })
+	originalBranch := currentBranch
+	branch := "topic"
+	currentBranch = func(context.Context) (string, error) { return branch, nil }
+	t.Cleanup(func() { currentBranch = originalBranch })
+
+	here := repoRef{host: "github.com", owner: "cur", name: "repo"}
+	tests := []struct {
+		name       string
+		repo       string
+		pr         string
+		current    string
+		base       string
+		want       prRef
+		wantBranch string
+		wantErr    error
+	}{
+		{name: "number", repo: "o/r", pr: "12", want: prRef{repo: repoRef{host: "github.com", owner: "o", name: "r"}, number: 12}},
+		{name: "hash number in current repo", pr: "#5", want: prRef{repo: here, number: 5}},
+		{name: "host-qualified repo", repo: "ghe.example.com/o/r", pr: "3", want: prRef{repo: repoRef{host: "ghe.example.com", owner: "o", name: "r"}, number: 3}},
+		{name: "URL", repo: "o/r", pr: "https://github.com/x/y/pull/8", want: prRef{repo: repoRef{host: "github.com", owner: "x", name: "y"}, number: 8}},
+		{name: "branch", pr: "feature", want: prRef{repo: here, number: 21}, wantBranch: "feature"},
+		{name: "fork branch", pr: "fork:feature", want: prRef{repo: here, number: 22}, wantBranch: "feature"},
+		{name: "current branch", want: prRef{repo: here, number: 20}, wantBranch: "topic"},
+		{name: "branch without PR", pr: "nothing", want: prRef{repo: here, head: "nothing"}, wantBranch: "nothing"},
+		{name: "fork branch without PR", pr: "fork:nothing", wantBranch: "nothing", wantErr: ErrNotFound},
+		{name: "current branch without PR", current: "main", wantBranch: "main", wantErr: ErrNotFound},
+		{name: "current branch without PR and a base", current: "nothing", base: "develop", want: prRef{repo: here, head: "nothing", base: "develop"}, wantBranch: "nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBranch any
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := decodeGraphQL(t, r)
+				gotBranch = req.Variables["branch"]
+				switch gotBranch {
+				case "topic":
+					writeJSON(w, http.StatusOK, `{"data":{"repository":{"pullRequests":{"nodes":[{"number":20,"headRepositoryOwner":{"login":"cur"}}]}}}}`)
+				case "feature":
+					writeJSON(w, http.StatusOK, `{"data":{"repository":{"pullRequests":{"nodes":[
+						{"number":21,"headRepositoryOwner":{"login":"cur"}},
+						{"number":22,"headRepositoryOwner":{"login":"Fork"}}
+					]}}}}`)
+				default:
+					writeJSON(w, http.StatusOK, `{"data":{"repository":{"pullRequests":{"nodes":[]}}}}`)
+				}
+			}))
+
+			branch = cmp.Or(tt.current, "topic")
+			c.SetCompareBase(tt.base)
+			got, err := c.resolvePR(context.Background(), tt.repo, tt.pr)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("resolvePR() error = %v, expected %v", err, tt.wantErr)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("resolvePR() = %+v, %v, expected %+v", got, err, tt.want)
+			}
+			if tt.wantBranch != "" && gotBranch != tt.wantBranch {
+				t.Errorf("queried branch %v, expected %q", gotBranch, tt.wantBranch)
+			}
+			if tt.wantBranch == "" && gotBranch != nil {
+				t.Errorf("unexpected branch query for %v", gotBranch)
+			}
+		})
+	}
+}
+
+func TestClientHostAndToken(t *testing.T) {
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("GH_HOST", "ghe.example.com")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise-token")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "")
+
+	var gotHost, gotPath, gotAuth string
+	opts := testClientOptions(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotPath, gotAuth = r.Host, r.URL.Path, r.Header.Get("Authorization")
+		_, _ = io.WriteString(w, "ok")
+	}))
+	opts.Host, opts.AuthToken = "", ""
+
+	data, found, err := newClient(opts).FetchFileAtRef(context.Background(), "o/r", "a.txt", "main")
+	if err != nil || !found || string(data) != "ok" {
+		t.Fatalf("FetchFileAtRef() = %q, %v, %v", data, found, err)
+	}
+	got := []string{gotHost, gotPath, gotAuth}
+	want := []string{"ghe.example.com", "/api/v3/repos/o/r/contents/a.txt", "token enterprise-token"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("request = %v, expected %v", got, want)
+	}
+}
+
+func TestClientMissingToken(t *testing.T) {
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("GH_PATH", "/nonexistent/gh")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "")
+
+	opts := testClientOptions(t, http.NotFoundHandler())
+	opts.AuthToken = ""
+	_, err := newClient(opts).FetchDiff(context.Background(), "ghe.example.com/o/r", "1")
+	if !errors.Is(err, ErrAuth) {
+		t.Fatalf("FetchDiff() error = %v, expected ErrAuth", err)
+	}
+}
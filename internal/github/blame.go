@@ -0,0 +1,181 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// blameBatchSize is the number of files blamed by one GraphQL query. Blame
+// is costly for the API to compute, so batches are smaller than for blobs.
+const blameBatchSize = 10
+
+// BlameRange is a range of lines at the PR head last changed by one commit.
+type BlameRange struct {
+	Start  int          `json:"start"`
+	End    int          `json:"end"`
+	Commit types.Commit `json:"commit"`
+}
+
+// Blamer is implemented by fetchers that can tell which commit last changed
+// each line at the PR head. Collect uses it to attribute TODOs when
+// CollectOptions.Attribute is set.
+type Blamer interface {
+	// Blame returns the blame of each of paths at the PR head.
+	Blame(ctx context.Context, repo, pr string, paths []string) (map[string][]BlameRange, error)
+}
+
+// attribute sets the commit of each of todos to the commit that last
+// changed its line, when the fetcher can tell. Since TODOs are only
+// reported on lines the PR added, that is a commit of the PR. Failing to
+// attribute them is not fatal: the TODOs are reported without commits.
+func attribute(ctx context.Context, fetcher PRFetcher, repo, pr string, todos []types.TODO) {
+	b, ok := fetcher.(Blamer)
+	if !ok || len(todos) == 0 {
+		return
+	}
+	blame, err := b.Blame(ctx, repo, pr, todoPaths(todos))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not attribute TODO-style comments to commits: %v\n", err)
+		return
+	}
+	for i := range todos {
+		ranges := blame[todos[i].Filename]
+		j := slices.IndexFunc(ranges, func(r BlameRange) bool { return r.Start <= todos[i].Line && todos[i].Line <= r.End })
+		if j >= 0 {
+			commit := ranges[j].Commit
+			todos[i].Commit = &commit
+		}
+	}
+}
+
+// todoPaths returns the files of todos, in order of first appearance.
+func todoPaths(todos []types.TODO) []string {
+	var paths []string
+	for _, todo := range todos {
+		if !slices.Contains(paths, todo.Filename) {
+			paths = append(paths, todo.Filename)
+		}
+	}
+	return paths
+}
+
+// blameQuery returns a query blaming n files, given as the variables $p0
+// to $p<n-1>, at the commit $oid under the aliases f0 to f<n-1>.
+func blameQuery(n int) string {
+	var b strings.Builder
+	b.WriteString("query($owner: String!, $name: String!, $oid: GitObjectID!")
+	for i := range n {
+		fmt.Fprintf(&b, ", $p%d: String!", i)
+	}
+	b.WriteString(") {\n  repository(owner: $owner, name: $name) {\n    object(oid: $oid) {\n      ... on Commit {\n")
+	for i := range n {
+		fmt.Fprintf(&b, "        f%d: blame(path: $p%d) { ranges { startingLine endingLine commit { oid messageHeadline author { name } } } }\n", i, i)
+	}
+	b.WriteString("      }\n    }\n  }\n}")
+	return b.String()
+}
+
+// blameNode is the blame of a file returned by blameQuery.
+type blameNode struct {
+	Ranges []struct {
+		StartingLine int `json:"startingLine"`
+		EndingLine   int `json:"endingLine"`
+		Commit       struct {
+			Oid             string `json:"oid"`
+			MessageHeadline string `json:"messageHeadline"`
+			Author          *struct {
+				Name string `json:"name"`
+			} `json:"author"`
+		} `json:"commit"`
+	} `json:"ranges"`
+}
+
+// Blame returns the blame of each of paths at the PR head. Files are
+// blamed by local git when the head commit is in the client's local
+// repository with its history, and otherwise by GraphQL queries whose
+// results are cached by head commit.
+func (c *Client) Blame(ctx context.Context, repo, pr string, paths []string) (map[string][]BlameRange, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := c.fetchPRMeta(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	head := meta.Repository.PullRequest.HeadRefOid
+	c.local.fetchPR(ctx, ref, head)
+	local := c.local.canBlame(ctx, head)
+
+	blame := make(map[string][]BlameRange, len(paths))
+	var remote []string
+	for _, path := range paths {
+		if local {
+			if ranges, ok := c.local.blame(ctx, head, path); ok {
+				blame[path] = ranges
+				continue
+			}
+		}
+		if data, ok := c.cache.Get(blameKey(ref.repo, head, path)); ok {
+			var ranges []BlameRange
+			if json.Unmarshal(data, &ranges) == nil {
+				blame[path] = ranges
+				continue
+			}
+		}
+		remote = append(remote, path)
+	}
+
+	for len(remote) > 0 {
+		batch := remote[:min(len(remote), blameBatchSize)]
+		remote = remote[len(batch):]
+		if err := c.blameBatch(ctx, ref.repo, head, batch, blame); err != nil {
+			return nil, err
+		}
+	}
+	return blame, nil
+}
+
+// blameBatch blames paths at the commit head with one query, adding them
+// to blame.
+func (c *Client) blameBatch(ctx context.Context, r repoRef, head string, paths []string, blame map[string][]BlameRange) error {
+	vars := map[string]any{"owner": r.owner, "name": r.name, "oid": head}
+	for i, path := range paths {
+		vars[fmt.Sprintf("p%d", i)] = path
+	}
+	var resp struct {
+		Repository struct {
+			Object map[string]*blameNode `json:"object"`
+		} `json:"repository"`
+	}
+	if err := c.query(ctx, r.host, blameQuery(len(paths)), vars, &resp); err != nil {
+		return err
+	}
+	if resp.Repository.Object == nil {
+		return &APIError{Kind: ErrNotFound, Err: fmt.Errorf("commit %s not found in %s", head, r.nameWithOwner())}
+	}
+	for i, path := range paths {
+		node := resp.Repository.Object[fmt.Sprintf("f%d", i)]
+		if node == nil {
+			continue
+		}
+		ranges := make([]BlameRange, len(node.Ranges))
+		for j, nr := range node.Ranges {
+			ranges[j] = BlameRange{Start: nr.StartingLine, End: nr.EndingLine, Commit: types.Commit{SHA: nr.Commit.Oid, Subject: nr.Commit.MessageHeadline}}
+			if nr.Commit.Author != nil {
+				ranges[j].Commit.Author = nr.Commit.Author.Name
+			}
+		}
+		blame[path] = ranges
+		if data, err := json.Marshal(ranges); err == nil {
+			c.cache.Put(blameKey(r, head, path), data)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestBlame(t *testing.T) {
+	var queries atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := decodeGraphQL(t, r)
+		if !strings.Contains(req.Query, "blame(") {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":{"nameWithOwner":"o/r","pullRequest":{"headRefOid":"`+testSHA+`","headRepository":{"nameWithOwner":"o/r"}}}}}`)
+			return
+		}
+		queries.Add(1)
+		if req.Variables["oid"] != testSHA || req.Variables["p0"] != "a.go" || req.Variables["p1"] != "b.go" {
+			t.Errorf("variables = %v, expected a.go and b.go at the head", req.Variables)
+		}
+		writeJSON(w, http.StatusOK, `{"data":{"repository":{"object":{`+
+			`"f0":{"ranges":[{"startingLine":1,"endingLine":3,"commit":{"oid":"aaa","messageHeadline":"Add a","author":{"name":"Alice"}}},{"startingLine":4,"endingLine":4,"commit":{"oid":"bbb","messageHeadline":"Fix a","author":null}}]},`+
+			`"f1":{"ranges":[{"startingLine":1,"endingLine":1,"commit":{"oid":"ccc","messageHeadline":"Add b","author":{"name":"Bob"}}}]}}}}}`)
+	})
+	want := map[string][]BlameRange{
+		"a.go": {
+			{Start: 1, End: 3, Commit: types.Commit{SHA: "aaa", Subject: "Add a", Author: "Alice"}},
+			{Start: 4, End: 4, Commit: types.Commit{SHA: "bbb", Subject: "Fix a"}},
+		},
+		"b.go": {{Start: 1, End: 1, Commit: types.Commit{SHA: "ccc", Subject: "Add b", Author: "Bob"}}},
+	}
+
+	dir := t.TempDir()
+	for range 2 {
+		c := newTestClient(t, handler)
+		c.SetCache(cache.New(dir, 0))
+		got, err := c.Blame(context.Background(), "o/r", "1", []string{"a.go", "b.go"})
+		if err != nil {
+			t.Fatalf("Blame() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Blame() = %+v, expected %+v", got, want)
+		}
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("blame queries = %d, expected the second run to be served from the cache", n)
+	}
+}
+
+func TestBlameLocal(t *testing.T) {
+	dir, first := gitRepo(t, map[string]string{"a.go": "package a\n"})
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n// TODO: blame\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "-c", "user.name=Alice", "-c", "user.email=alice@example.com", "commit", "--quiet", "-am", "Add a TODO")
+	head := git(t, dir, "rev-parse", "HEAD")
+
+	c := newTestClient(t, prMetaHandler(t, head, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	c.SetLocalRepo(dir, false)
+	defer c.Close()
+
+	got, err := c.Blame(context.Background(), "o/r", "1", []string{"a.go"})
+	if err != nil {
+		t.Fatalf("Blame() error = %v", err)
+	}
+	want := map[string][]BlameRange{"a.go": {
+		{Start: 1, End: 1, Commit: types.Commit{SHA: first, Subject: "test", Author: "test"}},
+		{Start: 2, End: 3, Commit: types.Commit{SHA: head, Subject: "Add a TODO", Author: "Alice"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Blame() = %+v, expected %+v", got, want)
+	}
+}
+
+// blameFetcher is a stubFetcher that blames every line of every file on
+// one commit, or fails.
+type blameFetcher struct {
+	*stubFetcher
+	commit types.Commit
+	err    error
+}
+
+func (f blameFetcher) Blame(_ context.Context, _, _ string, paths []string) (map[string][]BlameRange, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	blame := make(map[string][]BlameRange)
+	for _, path := range paths {
+		blame[path] = []BlameRange{{Start: 2, End: 2, Commit: f.commit}}
+	}
+	return blame, nil
+}
+
+func TestCollectAttribute(t *testing.T) {
+	stub := &stubFetcher{diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,3 @@\n package a\n+// TODO: x\n+// TODO: y\n"}
+	commit := types.Commit{SHA: "aaa", Subject: "Add x", Author: "Alice"}
+	opts := CollectOptions{Types: []string{"TODO"}, Attribute: true}
+
+	todos, err := Collect(context.Background(), blameFetcher{stubFetcher: stub, commit: commit}, "o/r", "1", opts)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var got []*types.Commit
+	for _, todo := range todos {
+		got = append(got, todo.Commit)
+	}
+	if want := []*types.Commit{&commit, nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("commits = %v, expected %v", got, want)
+	}
+
+	stderr := captureStderr(t, func() {
+		todos, err = Collect(context.Background(), blameFetcher{stubFetcher: stub, err: &APIError{Kind: ErrRateLimited}}, "o/r", "1", opts)
+	})
+	if err != nil || len(todos) != 2 || todos[0].Commit != nil {
+		t.Errorf("Collect() = %v, %v, expected unattributed TODOs", todos, err)
+	}
+	if !strings.Contains(stderr, "could not attribute") {
+		t.Errorf("stderr = %q, expected a warning", stderr)
+	}
+}
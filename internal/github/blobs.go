@@ -0,0 +1,189 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// blobBatchSize is the number of files fetched by one GraphQL query, which
+// keeps each query well under the API's cost and response size limits.
+const blobBatchSize = 50
+
+// blobNode is a file fetched by blobQuery. Text is nil for binary files.
+type blobNode struct {
+	Text        *string `json:"text"`
+	IsBinary    bool    `json:"isBinary"`
+	IsTruncated bool    `json:"isTruncated"`
+}
+
+// usable reports whether b holds the complete contents of a UTF-8 text file.
+// GraphQL returns other files lossily, if at all.
+func (b *blobNode) usable() bool {
+	return b != nil && b.Text != nil && !b.IsBinary && !b.IsTruncated && !strings.ContainsRune(*b.Text, utf8.RuneError)
+}
+
+// blobQuery returns a query fetching n files, given as the variables $e0 to
+// $e<n-1> in "REV:PATH" form, under the aliases f0 to f<n-1>.
+func blobQuery(n int) string {
+	var b strings.Builder
+	b.WriteString("query($owner: String!, $name: String!")
+	for i := range n {
+		fmt.Fprintf(&b, ", $e%d: String!", i)
+	}
+	b.WriteString(") {\n  repository(owner: $owner, name: $name) {\n")
+	for i := range n {
+		fmt.Fprintf(&b, "    f%d: object(expression: $e%d) { ... on Blob { text isBinary isTruncated } }\n", i, i)
+	}
+	b.WriteString("  }\n}")
+	return b.String()
+}
+
+// blobResult is the outcome of fetching one file in a batch.
+type blobResult struct {
+	done chan struct{}
+	data []byte
+	// ok is false when the file has to be fetched on its own instead.
+	ok bool
+}
+
+// blobFetcher fetches files at the PR head in batches of GraphQL queries,
+// one at a time, each covering the files asked for or prefetched while the
+// previous one ran. Files the queries cannot return as UTF-8 text, such as
+// binary, non-UTF-8, and very large files, are fetched through the REST
+// API instead. Files in the client's cache are not fetched at all, nor are
+// any files when the head commit is in the client's local repository.
+type blobFetcher struct {
+	ctx  context.Context
+	rest *headFetcher
+
+	mu      sync.Mutex
+	queued  []string
+	results map[string]*blobResult
+	running bool
+}
+
+func newBlobFetcher(ctx context.Context, rest *headFetcher) *blobFetcher {
+	return &blobFetcher{ctx: ctx, rest: rest, results: make(map[string]*blobResult)}
+}
+
+// Prefetch queues path for the next batch.
+func (f *blobFetcher) Prefetch(path string) {
+	if f.rest.client.local.has(f.rest.ref) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enqueue(path)
+}
+
+// Fetch returns the contents of path, waiting for its batch.
+func (f *blobFetcher) Fetch(path string) ([]byte, error) {
+	if f.rest.client.local.has(f.rest.ref) {
+		return f.rest.fetch(f.ctx, path)
+	}
+	f.mu.Lock()
+	result := f.enqueue(path)
+	f.mu.Unlock()
+
+	select {
+	case <-result.done:
+	case <-f.ctx.Done():
+		return nil, f.ctx.Err()
+	}
+	f.mu.Lock()
+	delete(f.results, path)
+	f.mu.Unlock()
+
+	if result.ok {
+		return result.data, nil
+	}
+	data, err := f.rest.fetch(f.ctx, path)
+	if err == nil {
+		f.rest.client.cache.Put(f.key(path), data)
+	}
+	return data, err
+}
+
+// Close drops the queued files and the results of files prefetched but not
+// fetched. A batch already running still completes.
+func (f *blobFetcher) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queued = nil
+	clear(f.results)
+}
+
+// key returns the cache key of path.
+func (f *blobFetcher) key(path string) string {
+	return fileKey(f.rest.repo, f.rest.ref, path)
+}
+
+// enqueue returns the result for path, queuing it and starting a batch if
+// it is not already pending. The caller must hold f.mu.
+func (f *blobFetcher) enqueue(path string) *blobResult {
+	if result, ok := f.results[path]; ok {
+		return result
+	}
+	result := &blobResult{done: make(chan struct{})}
+	f.results[path] = result
+	if data, ok := f.rest.client.cache.Get(f.key(path)); ok {
+		result.data, result.ok = data, true
+		close(result.done)
+		return result
+	}
+	f.queued = append(f.queued, path)
+	if !f.running {
+		f.running = true
+		go f.run()
+	}
+	return result
+}
+
+// run fetches the queued files, a batch at a time, until none are left.
+func (f *blobFetcher) run() {
+	for {
+		f.mu.Lock()
+		n := min(len(f.queued), blobBatchSize)
+		if n == 0 {
+			f.running = false
+			f.mu.Unlock()
+			return
+		}
+		batch := f.queued[:n:n]
+		f.queued = f.queued[n:]
+		results := make([]*blobResult, n)
+		for i, path := range batch {
+			results[i] = f.results[path]
+		}
+		f.mu.Unlock()
+
+		blobs := f.fetchBatch(batch)
+		for i, result := range results {
+			if blob := blobs[fmt.Sprintf("f%d", i)]; blob.usable() {
+				result.data, result.ok = []byte(*blob.Text), true
+				f.rest.client.cache.Put(f.key(batch[i]), result.data)
+			}
+			close(result.done)
+		}
+	}
+}
+
+// fetchBatch fetches paths with one query. A failed query returns no
+// files, leaving them to be fetched on their own with retries.
+func (f *blobFetcher) fetchBatch(paths []string) map[string]*blobNode {
+	repo := f.rest.repo
+	vars := map[string]any{"owner": repo.owner, "name": repo.name}
+	for i, path := range paths {
+		vars[fmt.Sprintf("e%d", i)] = f.rest.ref + ":" + path
+	}
+	var resp struct {
+		Repository map[string]*blobNode `json:"repository"`
+	}
+	if err := f.rest.client.query(f.ctx, repo.host, blobQuery(len(paths)), vars, &resp); err != nil {
+		return nil
+	}
+	return resp.Repository
+}
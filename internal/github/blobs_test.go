@@ -0,0 +1,190 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blobHandler answers the PR metadata query with a head at sha in o/r and
+// blob queries from files, keyed by path. Files missing from files are
+// returned as null objects, and "binary" files as binary blobs. Other
+// requests are answered by the contents handler. It records the paths
+// asked for in each blob query.
+func blobHandler(t *testing.T, sha string, files map[string]string, contents http.HandlerFunc) (http.Handler, func() [][]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var batches [][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		req := decodeGraphQL(t, r)
+		if !strings.Contains(req.Query, "object(") {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":{"nameWithOwner":"o/r","pullRequest":{"headRefOid":"`+sha+`","headRepository":{"nameWithOwner":"o/r"}}}}}`)
+			return
+		}
+		var paths []string
+		repo := map[string]any{}
+		for i := 0; ; i++ {
+			expr, ok := req.Variables[fmt.Sprintf("e%d", i)].(string)
+			if !ok {
+				break
+			}
+			path := strings.TrimPrefix(expr, sha+":")
+			paths = append(paths, path)
+			alias := fmt.Sprintf("f%d", i)
+			switch text, ok := files[path]; {
+			case !ok:
+				repo[alias] = nil
+			case text == "binary":
+				repo[alias] = map[string]any{"text": nil, "isBinary": true, "isTruncated": false}
+			default:
+				repo[alias] = map[string]any{"text": text, "isBinary": false, "isTruncated": false}
+			}
+		}
+		mu.Lock()
+		batches = append(batches, paths)
+		mu.Unlock()
+		body, _ := json.Marshal(map[string]any{"data": map[string]any{"repository": repo}})
+		writeJSON(w, http.StatusOK, string(body))
+	})
+	mux.HandleFunc("/", contents)
+	return mux, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return batches
+	}
+}
+
+func TestFetchChangedFileContentsBatched(t *testing.T) {
+	var diff strings.Builder
+	files := make(map[string]string)
+	for i := range 120 {
+		path := fmt.Sprintf("f%03d.go", i)
+		fmt.Fprintf(&diff, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n@@ -1,0 +1,1 @@\n+x\n", path, path, path, path)
+		files[path] = "package " + path
+	}
+	files["f010.go"] = "binary"
+	files["f011.go"] = "caf\ufffd"
+	delete(files, "f012.go")
+
+	var mu sync.Mutex
+	var restPaths []string
+	handler, batches := blobHandler(t, "abc", files, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		restPaths = append(restPaths, strings.TrimPrefix(r.URL.Path, "/repos/o/r/contents/"))
+		mu.Unlock()
+		if r.URL.Query().Get("ref") != "abc" {
+			t.Errorf("contents ref = %q, expected abc", r.URL.Query().Get("ref"))
+		}
+		if strings.HasSuffix(r.URL.Path, "f012.go") {
+			writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+			return
+		}
+		_, _ = io.WriteString(w, "rest "+r.URL.Path)
+	})
+	c := newTestClient(t, handler)
+
+	got, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", diff.String())
+	if err == nil || err.Error() != "failed to fetch 1 changed file(s): f012.go (HTTP 404 Not Found)" {
+		t.Fatalf("FetchChangedFileContents() error = %v, expected f012.go to fail", err)
+	}
+	if len(got) != 119 || string(got["f000.go"]) != "package f000.go" || string(got["f119.go"]) != "package f119.go" {
+		t.Errorf("FetchChangedFileContents() fetched %d files, expected 119 with their own contents", len(got))
+	}
+	if string(got["f010.go"]) != "rest /repos/o/r/contents/f010.go" || string(got["f011.go"]) != "rest /repos/o/r/contents/f011.go" {
+		t.Errorf("binary and non-UTF-8 files = %q, %q, expected them fetched from the contents API", got["f010.go"], got["f011.go"])
+	}
+
+	n := 0
+	for _, batch := range batches() {
+		if len(batch) > blobBatchSize {
+			t.Errorf("blob query for %d files, expected at most %d", len(batch), blobBatchSize)
+		}
+		n += len(batch)
+	}
+	if len(batches()) > 4 || n != 120 {
+		t.Errorf("%d blob queries for %d files, expected at most 4 for 120", len(batches()), n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(restPaths)
+	if want := []string{"f010.go", "f011.go", "f012.go"}; !reflect.DeepEqual(restPaths, want) {
+		t.Errorf("contents API fetched %v, expected %v", restPaths, want)
+	}
+}
+
+func TestBlobFetcherFailedQuery(t *testing.T) {
+	withoutSleep(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadGateway, "{}")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "rest "+r.URL.Path)
+	})
+	c := newTestClient(t, mux)
+
+	f := newBlobFetcher(context.Background(), newHeadFetcher(c, repoRef{host: "github.com", owner: "o", name: "r"}, "abc"))
+	f.Prefetch("a.go")
+	got, err := f.Fetch("a.go")
+	if err != nil || string(got) != "rest /repos/o/r/contents/a.go" {
+		t.Fatalf("Fetch() = %q, %v, expected the contents API response", got, err)
+	}
+}
+
+func TestBlobFetcherClose(t *testing.T) {
+	handler, _ := blobHandler(t, "abc", map[string]string{"a.go": "package a", "b.go": "package b"}, http.NotFound)
+	c := newTestClient(t, handler)
+
+	f := newBlobFetcher(context.Background(), newHeadFetcher(c, repoRef{host: "github.com", owner: "o", name: "r"}, "abc"))
+	f.Prefetch("a.go")
+	f.Prefetch("b.go")
+	if got, err := f.Fetch("a.go"); err != nil || string(got) != "package a" {
+		t.Fatalf("Fetch() = %q, %v, expected a.go", got, err)
+	}
+	f.Close()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.results) != 0 || len(f.queued) != 0 {
+		t.Errorf("after Close(), %d results and %d queued files are held, expected none", len(f.results), len(f.queued))
+	}
+}
+
+func TestFetchPRMetaShared(t *testing.T) {
+	queries := 0
+	handler, _ := blobHandler(t, "abc", map[string]string{"a.go": "package a\n"}, http.NotFound)
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), "object(") {
+				queries++
+			}
+			r.Body = io.NopCloser(strings.NewReader(string(body)))
+		}
+		handler.ServeHTTP(w, r)
+	}))
+
+	ctx := context.Background()
+	refs, err := c.FetchRemoteConfigRefs(ctx, "o/r", "1")
+	if err != nil || refs.HeadRefOid != "abc" {
+		t.Fatalf("FetchRemoteConfigRefs() = %+v, %v", refs, err)
+	}
+	f, err := c.HeadFileFetcher(ctx, "o/r", "1")
+	if err != nil {
+		t.Fatalf("HeadFileFetcher() error = %v", err)
+	}
+	if got, err := f.Fetch("a.go"); err != nil || string(got) != "package a\n" {
+		t.Fatalf("Fetch() = %q, %v", got, err)
+	}
+	if queries != 1 {
+		t.Errorf("PR metadata queries = %d, expected 1", queries)
+	}
+}
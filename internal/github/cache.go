@@ -0,0 +1,140 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+)
+
+// SetCache makes c keep the responses that do not change, such as the diff
+// and files at a PR head commit, in cache, and revalidate files at branch
+// refs with ETags instead of fetching them again. A nil cache disables
+// caching.
+func (c *Client) SetCache(cache *cache.Cache) {
+	c.cache = cache
+}
+
+// Cache keys. Every key names a commit, or for refKey a branch whose entry
+// is revalidated before use, so entries never go stale.
+
+func diffKey(pr prRef, meta *prMeta) string {
+	return fmt.Sprintf("diff\x00%s/%s\x00%d\x00%s\x00%s", pr.repo.host, pr.repo.nameWithOwner(), pr.number, meta.HeadRefOid, meta.BaseRefOid)
+}
+
+func fileKey(r repoRef, sha, path string) string {
+	return fmt.Sprintf("file\x00%s/%s\x00%s\x00%s", r.host, r.nameWithOwner(), sha, path)
+}
+
+func missingKey(r repoRef, sha, path string) string {
+	return "missing\x00" + fileKey(r, sha, path)
+}
+
+func blameKey(r repoRef, sha, path string) string {
+	return "blame\x00" + fileKey(r, sha, path)
+}
+
+func refKey(r repoRef, ref, path string) string {
+	return fmt.Sprintf("ref\x00%s/%s\x00%s\x00%s", r.host, r.nameWithOwner(), ref, path)
+}
+
+// isCommitSHA reports whether ref is a full commit SHA, which unlike a
+// branch always names the same files.
+func isCommitSHA(ref string) bool {
+	if len(ref) != 40 && len(ref) != 64 {
+		return false
+	}
+	return strings.Trim(ref, "0123456789abcdef") == ""
+}
+
+// fetchCachedFile is fetchFile through the cache. Files at a commit SHA,
+// and their absence, are cached for good. Files at a branch are cached
+// with their ETag and fetched again only if they have changed.
+func (c *Client) fetchCachedFile(ctx context.Context, r repoRef, path, ref string) ([]byte, error) {
+	if isCommitSHA(ref) {
+		if data, found, ok := c.local.file(ref, path); ok {
+			if !found {
+				return nil, localNotFound(path, ref)
+			}
+			return data, nil
+		}
+		if data, ok := c.cache.Get(fileKey(r, ref, path)); ok {
+			return data, nil
+		}
+		if _, ok := c.cache.Get(missingKey(r, ref, path)); ok {
+			return nil, &APIError{Kind: ErrNotFound, StatusCode: http.StatusNotFound, Err: fmt.Errorf("%s not found at %s (cached)", path, ref)}
+		}
+		data, err := c.fetchFile(ctx, r, path, ref)
+		switch {
+		case err == nil:
+			c.cache.Put(fileKey(r, ref, path), data)
+		case errors.Is(err, ErrNotFound):
+			c.cache.Put(missingKey(r, ref, path), nil)
+		}
+		return data, err
+	}
+
+	key := refKey(r, ref, path)
+	etag, cached, ok := splitETagEntry(c.cache.Get(key))
+	if ok {
+		ctx = withIfNoneMatch(ctx, etag)
+	}
+	resp, err := c.getResponse(ctx, r.host, contentsPath(r, path, ref), rawMediaType)
+	var apiErr *APIError
+	if ok && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotModified {
+		return cached, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		c.cache.Put(key, append([]byte(etag+"\n"), data...))
+	}
+	return data, nil
+}
+
+// splitETagEntry splits an entry stored by fetchCachedFile into its ETag and
+// data.
+func splitETagEntry(entry []byte, ok bool) (string, []byte, bool) {
+	if !ok {
+		return "", nil, false
+	}
+	etag, data, ok := bytes.Cut(entry, []byte("\n"))
+	return string(etag), data, ok && len(etag) > 0
+}
+
+type ifNoneMatchKey struct{}
+
+// withIfNoneMatch returns a context whose requests are conditional on the
+// resource no longer matching etag.
+func withIfNoneMatch(ctx context.Context, etag string) context.Context {
+	return context.WithValue(ctx, ifNoneMatchKey{}, etag)
+}
+
+// conditionalTransport sets the If-None-Match header of requests whose
+// context carries an ETag from withIfNoneMatch.
+type conditionalTransport struct {
+	base http.RoundTripper
+}
+
+func (t conditionalTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if etag, ok := req.Context().Value(ifNoneMatchKey{}).(string); ok {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", etag)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
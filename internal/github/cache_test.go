@@ -0,0 +1,138 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+)
+
+const testSHA = "0123456789abcdef0123456789abcdef01234567"
+
+func TestFetchFileAtRefCached(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path+"@"+r.URL.Query().Get("ref")+" "+r.Header.Get("If-None-Match"))
+		mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "missing.yml"):
+			writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = io.WriteString(w, "severity: {}\n")
+		}
+	}))
+	c.SetCache(cache.New(t.TempDir(), 0))
+
+	ctx := context.Background()
+	for range 2 {
+		for _, tt := range []struct {
+			path, ref string
+			found     bool
+		}{
+			{path: "a.yml", ref: testSHA, found: true},
+			{path: "missing.yml", ref: testSHA},
+			{path: "a.yml", ref: "main", found: true},
+		} {
+			data, found, err := c.FetchFileAtRef(ctx, "o/r", tt.path, tt.ref)
+			if err != nil || found != tt.found || found && string(data) != "severity: {}\n" {
+				t.Fatalf("FetchFileAtRef(%s, %s) = %q, %v, %v", tt.path, tt.ref, data, found, err)
+			}
+		}
+	}
+
+	want := []string{
+		"/repos/o/r/contents/a.yml@" + testSHA + " ",
+		"/repos/o/r/contents/missing.yml@" + testSHA + " ",
+		"/repos/o/r/contents/a.yml@main ",
+		`/repos/o/r/contents/a.yml@main "v1"`,
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests =\n%s\nexpected\n%s", strings.Join(requests, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestStreamDiffCached(t *testing.T) {
+	diffs := 0
+	head := testSHA
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":{"nameWithOwner":"o/r","pullRequest":{"headRefOid":"`+head+`","baseRefOid":"b1","headRepository":{"nameWithOwner":"o/r"}}}}}`)
+			return
+		}
+		diffs++
+		_, _ = io.WriteString(w, "diff at "+head)
+	}))
+	dir := t.TempDir()
+	c.SetCache(cache.New(dir, 0))
+
+	read := func(c *Client) string {
+		t.Helper()
+		body, err := c.StreamDiff(context.Background(), "o/r", "1")
+		if err != nil {
+			t.Fatalf("StreamDiff() error = %v", err)
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return string(data)
+	}
+	first := read(c)
+	// A new client does not share c's PR metadata, only the cache.
+	again := newClient(c.opts)
+	again.SetCache(cache.New(dir, 0))
+	if got := read(again); got != first || diffs != 1 {
+		t.Errorf("StreamDiff() = %q after %d diff requests, expected %q from the cache", got, diffs, first)
+	}
+
+	head = strings.Repeat("f", 40)
+	moved := newClient(c.opts)
+	moved.SetCache(cache.New(dir, 0))
+	if got := read(moved); got != "diff at "+head || diffs != 2 {
+		t.Errorf("StreamDiff() = %q after %d diff requests, expected a new head to fetch the diff again", got, diffs)
+	}
+}
+
+func TestBlobFetcherCached(t *testing.T) {
+	handler, batches := blobHandler(t, testSHA, map[string]string{"a.go": "package a\n"}, http.NotFound)
+	dir := t.TempDir()
+	fetch := func() ([]byte, error) {
+		c := newTestClient(t, handler)
+		c.SetCache(cache.New(dir, 0))
+		f, err := c.HeadFileFetcher(context.Background(), "o/r", "1")
+		if err != nil {
+			return nil, err
+		}
+		f.Prefetch("a.go")
+		return f.Fetch("a.go")
+	}
+	for range 2 {
+		if got, err := fetch(); err != nil || string(got) != "package a\n" {
+			t.Fatalf("Fetch() = %q, %v", got, err)
+		}
+	}
+	if n := len(batches()); n != 1 {
+		t.Errorf("blob queries = %d, expected the second run to read the cache", n)
+	}
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	for ref, want := range map[string]bool{
+		testSHA:                  true,
+		strings.Repeat("a", 64):  true,
+		"main":                   false,
+		strings.ToUpper(testSHA): false,
+		testSHA[:39]:             false,
+	} {
+		if got := isCommitSHA(ref); got != want {
+			t.Errorf("isCommitSHA(%q) = %v, expected %v", ref, got, want)
+		}
+	}
+}
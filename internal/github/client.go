@@ -1,34 +1,91 @@
-// Package github wraps the `gh` CLI to fetch pull request diffs and file contents.
+// Package github fetches pull request diffs and file contents from the GitHub
+// REST and GraphQL APIs.
 package github
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/url"
+	"io"
 	"os"
 	"strings"
+	"sync"
 
 	"github.com/Suree33/gh-pr-todo/internal"
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+	"github.com/Suree33/gh-pr-todo/internal/charset"
 	"github.com/Suree33/gh-pr-todo/internal/config"
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/internal/language"
 	"github.com/Suree33/gh-pr-todo/pkg/types"
-	"github.com/cli/go-gh/v2"
+	"github.com/cli/go-gh/v2/pkg/api"
 )
 
-var ghExec func(args ...string) (bytes.Buffer, bytes.Buffer, error) = gh.Exec
-
 type PRFetcher interface {
-	FetchDiff(repo, pr string) (string, error)
-	FetchChangedFileContents(repo, pr, diffOutput string) (map[string][]byte, error)
+	FetchDiff(ctx context.Context, repo, pr string) (string, error)
+	FetchChangedFileContents(ctx context.Context, repo, pr, diffOutput string) (map[string][]byte, error)
+}
+
+// DiffStreamer is implemented by fetchers that can stream a PR diff and
+// fetch changed files one at a time. Collect uses it when available, so that
+// very large PRs are parsed without holding the whole diff, or every changed
+// file, in memory.
+type DiffStreamer interface {
+	// StreamDiff returns the PR diff as a stream. Reading it fails if the
+	// diff cannot be read in full.
+	StreamDiff(ctx context.Context, repo, pr string) (io.ReadCloser, error)
+	// HeadFileFetcher returns a fetcher of files at the PR head under ctx.
+	HeadFileFetcher(ctx context.Context, repo, pr string) (FileFetcher, error)
+}
+
+// TextDiffer is implemented by fetchers that can diff a file git reported
+// as binary from its contents. Collect uses it for source files in UTF-16,
+// which git takes for binary because of their NUL bytes.
+type TextDiffer interface {
+	// TextDiff returns the hunks of f between the merge base and the PR
+	// head, converted to UTF-8, or false if f is binary after all.
+	TextDiff(ctx context.Context, repo, pr string, f diff.File) ([]diff.Hunk, bool, error)
+}
+
+// FileFetcher fetches files at a fixed commit. Its methods may be called
+// from several goroutines at once.
+type FileFetcher interface {
+	// Prefetch hints that path will be fetched soon, so that it can be
+	// fetched along with other files.
+	Prefetch(path string)
+	// Fetch returns the contents of path.
+	Fetch(path string) ([]byte, error)
+	// Close drops the files prefetched but not fetched. The fetcher must
+	// not be used afterwards.
+	Close()
 }
 
-type Client struct{}
+// Client fetches pull requests and files through the GitHub API. Hosts and
+// tokens are resolved the way gh resolves them, honoring GH_HOST, GH_REPO,
+// GH_TOKEN, and GH_ENTERPRISE_TOKEN.
+type Client struct {
+	opts api.ClientOptions
+
+	mu      sync.Mutex
+	rest    map[restKey]*api.RESTClient
+	graphql map[string]*api.GraphQLClient
+	prs     map[[2]string]prRef
+	metas   map[prRef]repoMeta
+	bases   map[mergeBaseKey]string
+	cache   *cache.Cache
+	local   *localRepo
+	// compareBase is the branch that branches without an open PR are
+	// compared with, or "" for the default branch.
+	compareBase string
+}
 
 func NewClient() *Client {
-	return &Client{}
+	return newClient(api.ClientOptions{})
 }
 
 type prMeta struct {
+	BaseRefName    string `json:"baseRefName"`
+	BaseRefOid     string `json:"baseRefOid"`
 	HeadRefOid     string `json:"headRefOid"`
 	HeadRepository struct {
 		NameWithOwner string `json:"nameWithOwner"`
@@ -39,88 +96,131 @@ type prMeta struct {
 	} `json:"headRepository"`
 }
 
-// Remote config fetch methods
+// repoMeta is the repository metadata returned by repoQuery and prQuery.
+type repoMeta struct {
+	Repository struct {
+		NameWithOwner    string `json:"nameWithOwner"`
+		DefaultBranchRef struct {
+			Name string `json:"name"`
+		} `json:"defaultBranchRef"`
+		PullRequest *prMeta `json:"pullRequest"`
+	} `json:"repository"`
+}
 
-// FetchRemoteConfigRefs returns repository and PR refs for remote config loading.
-func (c *Client) FetchRemoteConfigRefs(repo, pr string) (config.RemoteConfigRefs, error) {
-	refs := config.RemoteConfigRefs{}
-	host, _ := splitHostRepo(repo)
+const repoQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    nameWithOwner
+    defaultBranchRef { name }
+  }
+}`
 
-	// Get repo default branch info
-	args := []string{"repo", "view"}
-	if repo != "" {
-		args = append(args, repo)
-	}
-	args = append(args, "--json", "defaultBranchRef,nameWithOwner")
-	out, _, err := ghExec(args...)
-	if err != nil {
-		return refs, err
+const prQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    nameWithOwner
+    defaultBranchRef { name }
+    pullRequest(number: $number) {
+      baseRefName
+      baseRefOid
+      headRefOid
+      headRepository { nameWithOwner name owner { login } }
+    }
+  }
+}`
+
+// fetchPRMeta returns the metadata of a pull request and its repository.
+// It is looked up once per client, so that remote config loading and file
+// fetching share the query.
+func (c *Client) fetchPRMeta(ctx context.Context, pr prRef) (repoMeta, error) {
+	c.mu.Lock()
+	meta, ok := c.metas[pr]
+	c.mu.Unlock()
+	if ok {
+		return meta, nil
 	}
 
-	var repoView struct {
-		DefaultBranchRef struct {
-			Name string `json:"name"`
-		} `json:"defaultBranchRef"`
-		NameWithOwner string `json:"nameWithOwner"`
+	if pr.number == 0 {
+		var err error
+		if meta, err = c.fetchCompareMeta(ctx, pr); err != nil {
+			return meta, err
+		}
+	} else {
+		vars := map[string]any{"owner": pr.repo.owner, "name": pr.repo.name, "number": pr.number}
+		if err := c.query(ctx, pr.repo.host, prQuery, vars, &meta); err != nil {
+			return meta, err
+		}
 	}
-	if err := json.Unmarshal(out.Bytes(), &repoView); err != nil {
-		return refs, err
+	if meta.Repository.PullRequest == nil {
+		return meta, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("pull request %s#%d not found", pr.repo.nameWithOwner(), pr.number)}
 	}
-	refs.DefaultBranchRef = repoView.DefaultBranchRef.Name
-	refs.DefaultRepo = withHost(host, repoView.NameWithOwner)
+	c.mu.Lock()
+	c.metas[pr] = meta
+	c.mu.Unlock()
+	return meta, nil
+}
 
-	// Get PR info if specified
-	if pr != "" {
-		args := []string{"pr", "view", "--json", "baseRefName,headRefOid,headRepository"}
-		if repo != "" {
-			args = append(args, "-R", repo)
-		}
-		args = append(args, pr)
-		out, _, err := ghExec(args...)
+// Remote config fetch methods
+
+// FetchRemoteConfigRefs returns repository and PR refs for remote config
+// loading. Without a PR, only the repository's refs are returned, unless
+// SetCompareBase set a base: then the current branch is compared with it,
+// and the refs are those of the comparison.
+func (c *Client) FetchRemoteConfigRefs(ctx context.Context, repo, pr string) (config.RemoteConfigRefs, error) {
+	refs := config.RemoteConfigRefs{}
+	host, _ := splitHostRepo(repo)
+
+	var meta repoMeta
+	if pr == "" && c.compareBase == "" {
+		r, err := c.resolveRepo(repo)
 		if err != nil {
 			return refs, err
 		}
-
-		var prView struct {
-			BaseRefName    string `json:"baseRefName"`
-			HeadRefOid     string `json:"headRefOid"`
-			HeadRepository struct {
-				NameWithOwner string `json:"nameWithOwner"`
-			} `json:"headRepository"`
+		vars := map[string]any{"owner": r.owner, "name": r.name}
+		if err := c.query(ctx, r.host, repoQuery, vars, &meta); err != nil {
+			return refs, err
 		}
-		if err := json.Unmarshal(out.Bytes(), &prView); err != nil {
+	} else {
+		ref, err := c.resolvePR(ctx, repo, pr)
+		if err != nil {
+			return refs, err
+		}
+		if meta, err = c.fetchPRMeta(ctx, ref); err != nil {
 			return refs, err
 		}
-		refs.BaseBranchRef = prView.BaseRefName
+	}
+	refs.DefaultBranchRef = meta.Repository.DefaultBranchRef.Name
+	refs.DefaultRepo = withHost(host, meta.Repository.NameWithOwner)
+
+	if prMeta := meta.Repository.PullRequest; prMeta != nil {
+		refs.BaseBranchRef = prMeta.BaseRefName
 		refs.BaseRepo = refs.DefaultRepo
-		refs.HeadRefOid = prView.HeadRefOid
-		refs.HeadRepo = withHost(host, prView.HeadRepository.NameWithOwner)
+		refs.HeadRefOid = prMeta.HeadRefOid
+		refs.HeadRepo = withHost(host, prMeta.headRepositoryNameWithOwner())
 	}
 
 	return refs, nil
 }
 
-func (c *Client) fetchRawFileContent(repo, path, ref string) ([]byte, string, error) {
-	host, repoPath := splitHostRepo(repo)
-	segments := strings.Split(path, "/")
-	for i, s := range segments {
-		segments[i] = url.PathEscape(s)
-	}
-	apiPath := fmt.Sprintf("repos/%s/contents/%s?ref=%s", repoPath, strings.Join(segments, "/"), url.QueryEscape(ref))
-	args := []string{"api", apiPath, "-H", "Accept: application/vnd.github.raw+json"}
-	if host != "" {
-		args = append(args, "--hostname", host)
+// fetchFile returns the raw contents of path at ref.
+func (c *Client) fetchFile(ctx context.Context, r repoRef, path, ref string) ([]byte, error) {
+	if data, found, ok := c.local.file(ref, path); ok {
+		if !found {
+			return nil, localNotFound(path, ref)
+		}
+		return data, nil
 	}
-	out, stdErr, err := ghExec(args...)
-	return out.Bytes(), stdErr.String(), err
+	return c.getBytes(ctx, r.host, contentsPath(r, path, ref), rawMediaType)
 }
 
 // FetchFileAtRef fetches a file from the repository at a specific ref.
 // Returns (nil, false, nil) when the file is not found (404).
-func (c *Client) FetchFileAtRef(repo, path, ref string) ([]byte, bool, error) {
-	data, stderr, err := c.fetchRawFileContent(repo, path, ref)
+func (c *Client) FetchFileAtRef(ctx context.Context, repo, path, ref string) ([]byte, bool, error) {
+	r, err := c.resolveRepo(repo)
 	if err != nil {
-		if strings.Contains(stderr, "Not Found") || strings.Contains(stderr, "404") {
+		return nil, false, err
+	}
+	data, err := c.fetchCachedFile(ctx, r, path, ref)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
 			return nil, false, nil
 		}
 		return nil, false, fmt.Errorf("fetching %s from %s at %s: %w", path, repo, ref, err)
@@ -153,85 +253,298 @@ func (m prMeta) headRepositoryNameWithOwner() string {
 	return m.HeadRepository.Owner.Login + "/" + m.HeadRepository.Name
 }
 
-func (c *Client) FetchDiff(repo, pr string) (string, error) {
-	args := []string{"pr", "diff"}
-	if repo != "" {
-		args = append(args, "-R", repo)
-	}
-	if pr != "" {
-		args = append(args, pr)
-	}
-	stdOut, stdErr, err := ghExec(args...)
+func (c *Client) FetchDiff(ctx context.Context, repo, pr string) (string, error) {
+	body, err := c.StreamDiff(ctx, repo, pr)
 	if err != nil {
-		if msg := strings.TrimSpace(stdErr.String()); msg != "" {
-			return "", fmt.Errorf("%s", msg)
-		}
 		return "", err
 	}
-	if stdErr.Len() > 0 {
-		fmt.Fprintf(os.Stderr, "Warning: %s\n", stdErr.String())
+	defer body.Close()
+	diff, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
 	}
-	return stdOut.String(), nil
+	return string(diff), nil
 }
 
-func (c *Client) FetchChangedFileContents(repo, pr, diffOutput string) (map[string][]byte, error) {
-	host, _ := splitHostRepo(repo)
-	args := []string{"pr", "view", "--json", "headRefOid,headRepository"}
-	if repo != "" {
-		args = append(args, "-R", repo)
+// StreamDiff streams the PR diff from the pulls API. Diffs GitHub refuses
+// to render for their size are built from the pull request files API
+// instead. With a cache, the diff is kept under the PR's head and base
+// commits and read from the cache while neither has changed. Branches
+// without an open PR are diffed by streamCompareDiff.
+func (c *Client) StreamDiff(ctx context.Context, repo, pr string) (io.ReadCloser, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
+	if err != nil {
+		return nil, err
+	}
+	if ref.number == 0 {
+		return c.streamCompareDiff(ctx, ref)
 	}
-	if pr != "" {
-		args = append(args, pr)
+	key := ""
+	if c.cache != nil {
+		if meta, err := c.fetchPRMeta(ctx, ref); err == nil {
+			key = diffKey(ref, meta.Repository.PullRequest)
+			if body, ok := c.cache.Open(key); ok {
+				return body, nil
+			}
+		}
+	}
+	path := fmt.Sprintf("repos/%s/pulls/%d", ref.repo.nameWithOwner(), ref.number)
+	body, err := c.get(ctx, ref.repo.host, path, diffMediaType)
+	if diffTooLarge(err) {
+		meta, metaErr := c.fetchPRMeta(ctx, ref)
+		if metaErr != nil {
+			return nil, metaErr
+		}
+		var entry *cache.Writer
+		if key != "" {
+			entry = c.cache.Create(key)
+		}
+		return c.streamFilesDiff(ctx, ref, meta.Repository.PullRequest, entry), nil
 	}
-	stdOut, _, err := ghExec(args...)
+	if err != nil || key == "" {
+		return body, err
+	}
+	return c.cache.Tee(key, body), nil
+}
+
+// HeadFileFetcher looks up the PR head commit and returns a fetcher of
+// files at it. Files are fetched blobBatchSize at a time through GraphQL,
+// falling back to the contents API for files GraphQL cannot return. At
+// most maxConcurrentFetches files are fetched at once through the contents
+// API, and server errors and rate limits are retried with backoff.
+func (c *Client) HeadFileFetcher(ctx context.Context, repo, pr string) (FileFetcher, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
 	if err != nil {
 		return nil, err
 	}
-
-	var meta prMeta
-	if err := json.Unmarshal(stdOut.Bytes(), &meta); err != nil {
+	meta, err := c.fetchPRMeta(ctx, ref)
+	if err != nil {
 		return nil, err
 	}
 
-	nwo := meta.headRepositoryNameWithOwner()
-	sha := meta.HeadRefOid
-	if nwo == "" || sha == "" {
+	head := meta.Repository.PullRequest
+	owner, name, _ := strings.Cut(head.headRepositoryNameWithOwner(), "/")
+	sha := head.HeadRefOid
+	if owner == "" || name == "" || sha == "" {
 		return nil, fmt.Errorf("could not determine PR head")
 	}
 
+	c.local.fetchPR(ctx, ref, sha)
+	rest := newHeadFetcher(c, repoRef{host: ref.repo.host, owner: owner, name: name}, sha)
+	return newBlobFetcher(ctx, rest), nil
+}
+
+func (c *Client) FetchChangedFileContents(ctx context.Context, repo, pr, diffOutput string) (map[string][]byte, error) {
+	fetcher, err := c.HeadFileFetcher(ctx, repo, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fetcher.Close()
+
 	paths := internal.ExtractChangedPaths(diffOutput)
-	files := make(map[string][]byte, len(paths))
-	var failedPaths []string
 	for _, p := range paths {
-		data, _, err := c.fetchRawFileContent(withHost(host, nwo), p, sha)
-		if err != nil {
-			failedPaths = append(failedPaths, p)
+		fetcher.Prefetch(p)
+	}
+	contents := make([][]byte, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			contents[i], errs[i] = fetcher.Fetch(p)
+		}()
+	}
+	wg.Wait()
+
+	files := make(map[string][]byte, len(paths))
+	var failures []FileFailure
+	for i, p := range paths {
+		if errs[i] != nil {
+			failures = append(failures, FileFailure{Path: p, Reason: errs[i].Error()})
 			continue
 		}
-		files[p] = data
-	}
-	if len(failedPaths) > 0 {
-		return files, fmt.Errorf("failed to fetch %d changed file(s)", len(failedPaths))
+		files[p] = contents[i]
 	}
+	return files, newFileFetchError(failures)
+}
 
-	return files, nil
+// CollectOptions controls what Collect detects and attaches to each TODO.
+type CollectOptions struct {
+	// Types lists the TODO marker types to detect.
+	Types []string
+	// MarkdownParagraphs also reports plain "TODO:" lines in documentation
+	// files.
+	MarkdownParagraphs bool
+	// Docstrings also reports "TODO:" lines in documentation strings.
+	Docstrings bool
+	// Languages assigns languages to files by path pattern.
+	Languages []language.Mapping
+	// Encodings assigns encodings to files that are not UTF-8 by path
+	// pattern. Other files are detected from their content.
+	Encodings []charset.Mapping
+	// ContextLines is the number of head file lines attached before and
+	// after each TODO. Zero disables context.
+	ContextLines int
+	// Jobs is the number of files parsed concurrently. Zero or less uses
+	// runtime.GOMAXPROCS.
+	Jobs int
+	// MaxFileSize is the largest diff, in bytes, parsed for a single file.
+	// Zero means no limit.
+	MaxFileSize int64
+	// MaxMemory bounds the bytes of diff text buffered ahead of parsing.
+	// Zero means no limit.
+	MaxMemory int64
+	// Since keeps only the TODOs on lines added since this commit, usually
+	// an earlier head of the PR. The fetcher must implement
+	// RevisionComparer. Empty keeps every TODO.
+	Since string
+	// Attribute sets the commit that last changed each TODO line, when the
+	// fetcher implements Blamer.
+	Attribute bool
 }
 
 // CollectTODOs fetches and parses TODOs from a PR diff using the given
 // fetcher and the specified TODO marker types.
-func CollectTODOs(fetcher PRFetcher, repo, pr string, todoTypes []string) ([]types.TODO, error) {
-	diffOutput, err := fetcher.FetchDiff(repo, pr)
+func CollectTODOs(ctx context.Context, fetcher PRFetcher, repo, pr string, todoTypes []string) ([]types.TODO, error) {
+	return Collect(ctx, fetcher, repo, pr, CollectOptions{Types: todoTypes})
+}
+
+// Collect fetches and parses TODOs from a PR diff using the given fetcher
+// and options. Fetchers implementing DiffStreamer have the diff parsed as it
+// is read.
+func Collect(ctx context.Context, fetcher PRFetcher, repo, pr string, opts CollectOptions) ([]types.TODO, error) {
+	todos, err := collectPR(ctx, fetcher, repo, pr, opts)
+	if err == nil && opts.Since != "" {
+		todos, err = filterSince(ctx, fetcher, repo, pr, opts.Since, todos)
+	}
+	if err == nil && opts.Attribute {
+		attribute(ctx, fetcher, repo, pr, todos)
+	}
+	return todos, err
+}
+
+// collectPR collects every TODO of the PR diff.
+func collectPR(ctx context.Context, fetcher PRFetcher, repo, pr string, opts CollectOptions) ([]types.TODO, error) {
+	if s, ok := fetcher.(DiffStreamer); ok {
+		return collectStream(ctx, s, repo, pr, opts)
+	}
+
+	diffOutput, err := fetcher.FetchDiff(ctx, repo, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := fetcher.FetchChangedFileContents(ctx, repo, pr, diffOutput)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		warnMissingContents(err)
+	}
+	files = charset.DecodeFiles(files, opts.Encodings)
+
+	todos, skipped, _ := internal.ParseDiffStream(strings.NewReader(diffOutput), internal.MapSource(files), parseOptions(opts))
+	warnSkipped(skipped)
+	return todos, nil
+}
+
+// collectStream parses the diff streamed by s, fetching each changed file
+// when the parser reaches it.
+func collectStream(ctx context.Context, s DiffStreamer, repo, pr string, opts CollectOptions) ([]types.TODO, error) {
+	body, err := s.StreamDiff(ctx, repo, pr)
 	if err != nil {
 		return nil, err
 	}
 
-	files, err := fetcher.FetchChangedFileContents(repo, pr, diffOutput)
+	fetcher, err := s.HeadFileFetcher(ctx, repo, pr)
 	if err != nil {
+		warnMissingContents(err)
+	}
+	var mu sync.Mutex
+	var failures []FileFailure
+	source := func(path string) ([]byte, bool) {
+		if fetcher == nil {
+			return nil, false
+		}
+		data, err := fetcher.Fetch(path)
+		if err != nil {
+			mu.Lock()
+			failures = append(failures, FileFailure{Path: path, Reason: err.Error()})
+			mu.Unlock()
+			return nil, false
+		}
+		return charset.ToUTF8(path, data, opts.Encodings), true
+	}
+
+	parseOpts := parseOptions(opts)
+	if fetcher != nil {
+		parseOpts.Prefetch = fetcher.Prefetch
+	}
+	if d, ok := s.(TextDiffer); ok {
+		parseOpts.TextDiff = func(f diff.File) ([]diff.Hunk, bool) {
+			hunks, ok, err := d.TextDiff(ctx, repo, pr, f)
+			if err != nil {
+				mu.Lock()
+				failures = append(failures, FileFailure{Path: f.Path(), Reason: err.Error()})
+				mu.Unlock()
+			}
+			return hunks, ok
+		}
+	}
+	todos, skipped, err := internal.ParseDiffStream(body, source, parseOpts)
+	if fetcher != nil {
+		// Files skipped after being prefetched, such as those over
+		// MaxFileSize, are never fetched.
+		fetcher.Close()
+	}
+	if closeErr := body.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := newFileFetchError(failures); err != nil {
+		warnMissingContents(err)
+	}
+	warnSkipped(skipped)
+	return todos, nil
+}
+
+func parseOptions(opts CollectOptions) internal.ParseOptions {
+	return internal.ParseOptions{
+		Types:              opts.Types,
+		MarkdownParagraphs: opts.MarkdownParagraphs,
+		Docstrings:         opts.Docstrings,
+		Languages:          opts.Languages,
+		Encodings:          opts.Encodings,
+		Jobs:               opts.Jobs,
+		ContextLines:       opts.ContextLines,
+		MaxFileSize:        opts.MaxFileSize,
+		MaxMemory:          opts.MaxMemory,
+	}
+}
+
+// warnMissingContents reports that file contents could not be fetched,
+// listing each file that fell back to diff-only parsing and why.
+func warnMissingContents(err error) {
+	var fetchErr *FileFetchError
+	if !errors.As(err, &fetchErr) {
 		fmt.Fprintf(os.Stderr, "Warning: could not fetch changed file contents; falling back to diff-only parsing where needed: %v\n", err)
+		return
 	}
-	if files == nil {
-		files = make(map[string][]byte)
+	fmt.Fprintf(os.Stderr, "Warning: could not fetch %d changed file(s); falling back to diff-only parsing for:\n", len(fetchErr.Failures))
+	for _, f := range fetchErr.Failures {
+		fmt.Fprintf(os.Stderr, "  %s: %s\n", f.Path, f.Reason)
 	}
+}
 
-	return internal.ParseDiffWithContentsAndTypes(diffOutput, files, todoTypes), nil
+func warnSkipped(paths []string) {
+	if len(paths) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d file(s) whose diff exceeds the maximum file size: %s\n", len(paths), strings.Join(paths, ", "))
+	}
 }
@@ -1,17 +1,24 @@
 package github
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Suree33/gh-pr-todo/internal/charset"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 	"github.com/Suree33/gh-pr-todo/pkg/types"
+	"github.com/cli/go-gh/v2/pkg/api"
 )
 
 // defaultTypes is used as the todoTypes argument in CollectTODOs calls.
@@ -30,7 +37,7 @@ func TestPRMetaHeadRepositoryNameWithOwner(t *testing.T) {
 		expected string
 	}{
 		{
-			name: "uses nameWithOwner from gh pr view payload",
+			name: "uses nameWithOwner from the GraphQL payload",
 			payload: `{
 				"headRefOid": "b93811e17d1cb86894fc3196f00be046d483b26e",
 				"headRepository": {
@@ -88,19 +95,104 @@ func TestPRMetaHeadRepositoryNameWithOwner(t *testing.T) {
 	}
 }
 
-// withGhExec swaps the package-level ghExec for the duration of a test.
-// Tests that use this helper MUST NOT call t.Parallel(): the swap is a
-// global mutation and would race with concurrent subtests.
-func withGhExec(t *testing.T, fn func(args ...string) (bytes.Buffer, bytes.Buffer, error)) {
+// newTestClient returns a Client whose API requests are served by handler.
+// Requests keep their host and path, so REST requests for github.com arrive
+// as "/repos/..." and GraphQL requests as "/graphql", while those for other
+// hosts are under "/api/v3/" and "/api/graphql".
+func newTestClient(t *testing.T, handler http.Handler) *Client {
 	t.Helper()
-	original := ghExec
-	ghExec = fn
-	t.Cleanup(func() { ghExec = original })
+	return newClient(testClientOptions(t, handler))
+}
+
+// testClientOptions returns API client options sending every request to a
+// test server running handler, authenticated with "test-token".
+func testClientOptions(t *testing.T, handler http.Handler) api.ClientOptions {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	target, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("url.Parse() error = %v", err)
+	}
+	return api.ClientOptions{
+		Host:         "github.com",
+		AuthToken:    "test-token",
+		LogIgnoreEnv: true,
+		Transport:    redirectTransport{target: target},
+	}
+}
+
+// redirectTransport sends every request to a test server, leaving the Host
+// header as the original host.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// graphQLRequest is the body of a GraphQL request.
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func decodeGraphQL(t *testing.T, r *http.Request) graphQLRequest {
+	t.Helper()
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		t.Errorf("decoding GraphQL request: %v", err)
+	}
+	return req
+}
+
+// writeJSON writes body as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+// prMetaHandler answers the PR metadata query with a head at sha in
+// o/r, and every other request with the contents handler. Blob queries
+// return no files, so that every file is fetched by the contents handler.
+func prMetaHandler(t *testing.T, sha string, contents http.HandlerFunc) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(decodeGraphQL(t, r).Query, "object(") {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":{}}}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"data":{"repository":{"nameWithOwner":"o/r","pullRequest":{"headRefOid":"`+sha+`","headRepository":{"nameWithOwner":"o/r"}}}}}`)
+	})
+	mux.HandleFunc("/", contents)
+	return mux
+}
+
+// withoutSleep makes retry backoff return at once for the duration of a
+// test, recording the requested delays. Callers must not use t.Parallel().
+func withoutSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	var mu sync.Mutex
+	original := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		delays = append(delays, d)
+		mu.Unlock()
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = original })
+	return &delays
 }
 
 // captureStderr redirects os.Stderr while fn runs and returns the captured
-// output. Like withGhExec it mutates a global, so callers must not use
-// t.Parallel().
+// output. It mutates a global, so callers must not use t.Parallel().
 func captureStderr(t *testing.T, fn func()) string {
 	t.Helper()
 	original := os.Stderr
@@ -128,123 +220,89 @@ func captureStderr(t *testing.T, fn func()) string {
 
 func TestFetchDiff(t *testing.T) {
 	tests := []struct {
-		name        string
-		repo        string
-		pr          string
-		stdout      string
-		stderr      string
-		execErr     error
-		wantOut     string
-		wantErr     string
-		wantWarning string
-		wantArgs    []string
+		name     string
+		status   int
+		body     string
+		wantOut  string
+		wantKind error
 	}{
 		{
-			name:     "success without repo or pr",
-			stdout:   "diff --git a/x b/x\n",
-			wantOut:  "diff --git a/x b/x\n",
-			wantArgs: []string{"pr", "diff"},
+			name:    "success",
+			status:  http.StatusOK,
+			body:    "diff --git a/x b/x\n",
+			wantOut: "diff --git a/x b/x\n",
 		},
 		{
-			name:     "success with repo and pr",
-			repo:     "owner/repo",
-			pr:       "42",
-			stdout:   "diff body",
-			wantOut:  "diff body",
-			wantArgs: []string{"pr", "diff", "-R", "owner/repo", "42"},
+			name:     "not found",
+			status:   http.StatusNotFound,
+			body:     `{"message":"Not Found"}`,
+			wantKind: ErrNotFound,
 		},
 		{
-			name:    "error with stderr message",
-			stderr:  "  bad credentials  ",
-			execErr: errors.New("exit 1"),
-			wantErr: "bad credentials",
+			name:     "bad credentials",
+			status:   http.StatusUnauthorized,
+			body:     `{"message":"Bad credentials"}`,
+			wantKind: ErrAuth,
 		},
 		{
-			name:    "error with empty stderr falls back to err",
-			execErr: errors.New("exit 1"),
-			wantErr: "exit 1",
-		},
-		{
-			name:        "stderr warning printed on success",
-			stdout:      "ok",
-			stderr:      "deprecation notice",
-			wantOut:     "ok",
-			wantWarning: "Warning: deprecation notice\n",
-			wantArgs:    []string{"pr", "diff"},
+			name:     "forbidden",
+			status:   http.StatusForbidden,
+			body:     `{"message":"Resource not accessible by integration"}`,
+			wantKind: ErrPermission,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var gotArgs []string
-			withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-				gotArgs = args
-				return *bytes.NewBufferString(tt.stdout), *bytes.NewBufferString(tt.stderr), tt.execErr
-			})
-
-			c := NewClient()
-			var got string
-			var gotErr error
-			stderrOut := captureStderr(t, func() {
-				got, gotErr = c.FetchDiff(tt.repo, tt.pr)
-			})
-
-			if tt.wantErr != "" {
-				if gotErr == nil {
-					t.Fatalf("FetchDiff() error = nil, expected %q", tt.wantErr)
-				}
-				if gotErr.Error() != tt.wantErr {
-					t.Fatalf("FetchDiff() error = %q, expected %q", gotErr.Error(), tt.wantErr)
-				}
-				if stderrOut != "" {
-					t.Fatalf("stderr = %q, expected empty on error path", stderrOut)
+			var gotPath, gotAccept, gotAuth string
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotAccept, gotAuth = r.URL.Path, r.Header.Get("Accept"), r.Header.Get("Authorization")
+				writeJSON(w, tt.status, tt.body)
+			}))
+
+			got, err := c.FetchDiff(context.Background(), "owner/repo", "42")
+			if tt.wantKind != nil {
+				if !errors.Is(err, tt.wantKind) {
+					t.Fatalf("FetchDiff() error = %v, expected %v", err, tt.wantKind)
 				}
 				return
 			}
-			if gotErr != nil {
-				t.Fatalf("FetchDiff() unexpected error = %v", gotErr)
+			if err != nil {
+				t.Fatalf("FetchDiff() unexpected error = %v", err)
 			}
 			if got != tt.wantOut {
 				t.Fatalf("FetchDiff() = %q, expected %q", got, tt.wantOut)
 			}
-			if tt.wantArgs != nil && !reflect.DeepEqual(gotArgs, tt.wantArgs) {
-				t.Fatalf("ghExec args = %v, expected %v", gotArgs, tt.wantArgs)
-			}
-			if tt.wantWarning != "" && !strings.Contains(stderrOut, tt.wantWarning) {
-				t.Fatalf("stderr = %q, expected to contain %q", stderrOut, tt.wantWarning)
-			}
-			if tt.wantWarning == "" && stderrOut != "" {
-				t.Fatalf("stderr = %q, expected empty", stderrOut)
+			if gotPath != "/repos/owner/repo/pulls/42" || gotAccept != diffMediaType || gotAuth != "token test-token" {
+				t.Fatalf("request = %s Accept %q Authorization %q", gotPath, gotAccept, gotAuth)
 			}
 		})
 	}
 }
 
 func TestFetchRemoteConfigRefs(t *testing.T) {
-	var calls [][]string
-	withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-		copied := append([]string(nil), args...)
-		calls = append(calls, copied)
-		switch len(calls) {
-		case 1:
-			return *bytes.NewBufferString(`{"defaultBranchRef":{"name":"main"},"nameWithOwner":"owner/repo"}`), bytes.Buffer{}, nil
-		case 2:
-			return *bytes.NewBufferString(`{"baseRefName":"release","headRefOid":"abc123","headRepository":{"nameWithOwner":"fork/repo"}}`), bytes.Buffer{}, nil
-		default:
-			return bytes.Buffer{}, bytes.Buffer{}, errors.New("unexpected call")
-		}
-	})
-
-	refs, err := NewClient().FetchRemoteConfigRefs("github.example.com/owner/repo", "42")
+	var gotHost, gotPath string
+	var got graphQLRequest
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost, gotPath = r.Host, r.URL.Path
+		got = decodeGraphQL(t, r)
+		writeJSON(w, http.StatusOK, `{"data":{"repository":{
+			"nameWithOwner":"owner/repo",
+			"defaultBranchRef":{"name":"main"},
+			"pullRequest":{"baseRefName":"release","headRefOid":"abc123","headRepository":{"nameWithOwner":"fork/repo"}}
+		}}}`)
+	}))
+
+	refs, err := c.FetchRemoteConfigRefs(context.Background(), "github.example.com/owner/repo", "42")
 	if err != nil {
 		t.Fatalf("FetchRemoteConfigRefs() unexpected error: %v", err)
 	}
-	wantCalls := [][]string{
-		{"repo", "view", "github.example.com/owner/repo", "--json", "defaultBranchRef,nameWithOwner"},
-		{"pr", "view", "--json", "baseRefName,headRefOid,headRepository", "-R", "github.example.com/owner/repo", "42"},
+	if gotHost != "github.example.com" || gotPath != "/api/graphql" {
+		t.Fatalf("request sent to %s%s, expected github.example.com/api/graphql", gotHost, gotPath)
 	}
-	if !reflect.DeepEqual(calls, wantCalls) {
-		t.Fatalf("ghExec calls = %v, expected %v", calls, wantCalls)
+	wantVars := map[string]any{"owner": "owner", "name": "repo", "number": float64(42)}
+	if !reflect.DeepEqual(got.Variables, wantVars) {
+		t.Fatalf("variables = %v, expected %v", got.Variables, wantVars)
 	}
 	if refs.DefaultRepo != "github.example.com/owner/repo" || refs.BaseRepo != "github.example.com/owner/repo" || refs.HeadRepo != "github.example.com/fork/repo" {
 		t.Fatalf("refs repos = default %q base %q head %q", refs.DefaultRepo, refs.BaseRepo, refs.HeadRepo)
@@ -256,13 +314,13 @@ func TestFetchRemoteConfigRefs(t *testing.T) {
 
 func TestFetchFileAtRef(t *testing.T) {
 	t.Run("fetches raw content with escaped path and ref", func(t *testing.T) {
-		var gotArgs []string
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			gotArgs = args
-			return *bytes.NewBufferString("severity:\n  TODO: error\n"), bytes.Buffer{}, nil
-		})
+		var gotPath, gotQuery, gotAccept string
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotPath, gotQuery, gotAccept = r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("Accept")
+			_, _ = io.WriteString(w, "severity:\n  TODO: error\n")
+		}))
 
-		got, found, err := NewClient().FetchFileAtRef("owner/repo", ".github/gh pr-todo.yml", "feature/config")
+		got, found, err := c.FetchFileAtRef(context.Background(), "owner/repo", ".github/gh pr-todo.yml", "feature/config")
 		if err != nil {
 			t.Fatalf("FetchFileAtRef() unexpected error: %v", err)
 		}
@@ -272,38 +330,36 @@ func TestFetchFileAtRef(t *testing.T) {
 		if string(got) != "severity:\n  TODO: error\n" {
 			t.Fatalf("FetchFileAtRef() data = %q", got)
 		}
-		wantArgs := []string{"api", "repos/owner/repo/contents/.github/gh%20pr-todo.yml?ref=feature%2Fconfig", "-H", "Accept: application/vnd.github.raw+json"}
-		if !reflect.DeepEqual(gotArgs, wantArgs) {
-			t.Fatalf("ghExec args = %v, expected %v", gotArgs, wantArgs)
+		if gotPath != "/repos/owner/repo/contents/.github/gh%20pr-todo.yml" || gotQuery != "ref=feature%2Fconfig" || gotAccept != rawMediaType {
+			t.Fatalf("request = %s?%s Accept %q", gotPath, gotQuery, gotAccept)
 		}
 	})
 
-	t.Run("host-qualified repo passes hostname to gh api", func(t *testing.T) {
-		var gotArgs []string
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			gotArgs = args
-			return *bytes.NewBufferString("severity: {}\n"), bytes.Buffer{}, nil
-		})
+	t.Run("host-qualified repo uses the enterprise API", func(t *testing.T) {
+		var gotHost, gotPath string
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotHost, gotPath = r.Host, r.URL.Path
+			_, _ = io.WriteString(w, "severity: {}\n")
+		}))
 
-		_, found, err := NewClient().FetchFileAtRef("github.example.com/owner/repo", ".gh-pr-todo.yml", "main")
+		_, found, err := c.FetchFileAtRef(context.Background(), "github.example.com/owner/repo", ".gh-pr-todo.yml", "main")
 		if err != nil {
 			t.Fatalf("FetchFileAtRef() unexpected error: %v", err)
 		}
 		if !found {
 			t.Fatal("FetchFileAtRef() found = false, expected true")
 		}
-		wantArgs := []string{"api", "repos/owner/repo/contents/.gh-pr-todo.yml?ref=main", "-H", "Accept: application/vnd.github.raw+json", "--hostname", "github.example.com"}
-		if !reflect.DeepEqual(gotArgs, wantArgs) {
-			t.Fatalf("ghExec args = %v, expected %v", gotArgs, wantArgs)
+		if gotHost != "github.example.com" || gotPath != "/api/v3/repos/owner/repo/contents/.gh-pr-todo.yml" {
+			t.Fatalf("request sent to %s%s", gotHost, gotPath)
 		}
 	})
 
 	t.Run("not found returns found false without error", func(t *testing.T) {
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			return bytes.Buffer{}, *bytes.NewBufferString("HTTP 404: Not Found"), errors.New("exit 1")
-		})
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+		}))
 
-		got, found, err := NewClient().FetchFileAtRef("owner/repo", ".gh-pr-todo.yml", "main")
+		got, found, err := c.FetchFileAtRef(context.Background(), "owner/repo", ".gh-pr-todo.yml", "main")
 		if err != nil {
 			t.Fatalf("FetchFileAtRef() unexpected error: %v", err)
 		}
@@ -314,6 +370,17 @@ func TestFetchFileAtRef(t *testing.T) {
 			t.Fatalf("FetchFileAtRef() data = %q, expected nil", got)
 		}
 	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusForbidden, `{"message":"Must have admin rights"}`)
+		}))
+
+		_, _, err := c.FetchFileAtRef(context.Background(), "owner/repo", ".gh-pr-todo.yml", "main")
+		if !errors.Is(err, ErrPermission) {
+			t.Fatalf("FetchFileAtRef() error = %v, expected ErrPermission", err)
+		}
+	})
 }
 
 const sampleDiff = `diff --git a/foo.go b/foo.go
@@ -342,85 +409,55 @@ index 0000000..2222222 100644
 `
 
 func TestFetchChangedFileContents(t *testing.T) {
-	metaJSON := `{"headRefOid":"abc123","headRepository":{"nameWithOwner":"o/r"}}`
+	withoutSleep(t)
 
-	t.Run("returns pr view exec error", func(t *testing.T) {
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			return bytes.Buffer{}, bytes.Buffer{}, errors.New("boom")
-		})
-		c := NewClient()
-		got, err := c.FetchChangedFileContents("", "", sampleDiff)
-		if err == nil || err.Error() != "boom" {
-			t.Fatalf("expected boom error, got %v", err)
+	t.Run("returns PR lookup error", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve to a Repository with the name 'o/r'."}]}`)
+		}))
+		got, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", sampleDiff)
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
 		}
 		if got != nil {
 			t.Fatalf("expected nil files, got %v", got)
 		}
 	})
 
-	t.Run("invalid json", func(t *testing.T) {
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			return *bytes.NewBufferString("not-json"), bytes.Buffer{}, nil
-		})
-		c := NewClient()
-		_, err := c.FetchChangedFileContents("", "", sampleDiff)
-		if err == nil {
-			t.Fatal("expected json error, got nil")
-		}
-	})
-
-	t.Run("empty meta", func(t *testing.T) {
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			return *bytes.NewBufferString(`{}`), bytes.Buffer{}, nil
-		})
-		c := NewClient()
-		_, err := c.FetchChangedFileContents("", "", sampleDiff)
+	t.Run("missing head repository", func(t *testing.T) {
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":{"pullRequest":{"headRefOid":"abc123","headRepository":null}}}}`)
+		}))
+		_, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", sampleDiff)
 		if err == nil || err.Error() != "could not determine PR head" {
 			t.Fatalf("expected PR head error, got %v", err)
 		}
 	})
 
 	t.Run("success with repo and pr", func(t *testing.T) {
-		var calls [][]string
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			calls = append(calls, args)
-			if args[0] == "pr" {
-				return *bytes.NewBufferString(metaJSON), bytes.Buffer{}, nil
-			}
-			return *bytes.NewBufferString("file contents"), bytes.Buffer{}, nil
-		})
-		c := NewClient()
-		got, err := c.FetchChangedFileContents("o/r", "1", sampleDiff)
+		var paths []string
+		c := newTestClient(t, prMetaHandler(t, "abc123", func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+			_, _ = io.WriteString(w, "file contents")
+		}))
+		got, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", sampleDiff)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 		if string(got["foo.go"]) != "file contents" {
 			t.Fatalf("got %v", got)
 		}
-		if len(calls) != 2 {
-			t.Fatalf("expected 2 ghExec calls, got %d: %v", len(calls), calls)
-		}
-		expectedFirst := []string{"pr", "view", "--json", "headRefOid,headRepository", "-R", "o/r", "1"}
-		if !reflect.DeepEqual(calls[0], expectedFirst) {
-			t.Fatalf("first call args = %v, expected %v", calls[0], expectedFirst)
-		}
-		expectedSecond := []string{"api", "repos/o/r/contents/foo.go?ref=abc123", "-H", "Accept: application/vnd.github.raw+json"}
-		if !reflect.DeepEqual(calls[1], expectedSecond) {
-			t.Fatalf("second call args = %v, expected %v", calls[1], expectedSecond)
+		if want := []string{"/repos/o/r/contents/foo.go?ref=abc123"}; !reflect.DeepEqual(paths, want) {
+			t.Fatalf("contents requests = %v, expected %v", paths, want)
 		}
 	})
 
 	t.Run("escapes changed file path and ref when fetching raw contents", func(t *testing.T) {
-		metaJSON := `{"headRefOid":"feature/sha","headRepository":{"nameWithOwner":"o/r"}}`
-		var calls [][]string
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			copied := append([]string(nil), args...)
-			calls = append(calls, copied)
-			if args[0] == "pr" {
-				return *bytes.NewBufferString(metaJSON), bytes.Buffer{}, nil
-			}
-			return *bytes.NewBufferString("file contents"), bytes.Buffer{}, nil
-		})
+		var paths []string
+		c := newTestClient(t, prMetaHandler(t, "feature/sha", func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+			_, _ = io.WriteString(w, "file contents")
+		}))
 
 		diffWithEscapedPath := `diff --git a/.github/gh pr-todo.yml b/.github/gh pr-todo.yml
 index 0000000..1111111 100644
@@ -430,34 +467,27 @@ index 0000000..1111111 100644
  severity:
 +  TODO: error
 `
-		got, err := NewClient().FetchChangedFileContents("github.example.com/o/r", "1", diffWithEscapedPath)
+		got, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", diffWithEscapedPath)
 		if err != nil {
 			t.Fatalf("FetchChangedFileContents() unexpected error: %v", err)
 		}
 		if string(got[".github/gh pr-todo.yml"]) != "file contents" {
 			t.Fatalf("got %v", got)
 		}
-		if len(calls) != 2 {
-			t.Fatalf("expected 2 calls, got %d: %v", len(calls), calls)
-		}
-		want := []string{"api", "repos/o/r/contents/.github/gh%20pr-todo.yml?ref=feature%2Fsha", "-H", "Accept: application/vnd.github.raw+json", "--hostname", "github.example.com"}
-		if !reflect.DeepEqual(calls[1], want) {
-			t.Fatalf("second call args = %v, expected %v", calls[1], want)
+		if want := []string{"/repos/o/r/contents/.github/gh%20pr-todo.yml?ref=feature%2Fsha"}; !reflect.DeepEqual(paths, want) {
+			t.Fatalf("contents requests = %v, expected %v", paths, want)
 		}
 	})
 
 	t.Run("partial failure returns error and partial files", func(t *testing.T) {
-		withGhExec(t, func(args ...string) (bytes.Buffer, bytes.Buffer, error) {
-			if args[0] == "pr" {
-				return *bytes.NewBufferString(metaJSON), bytes.Buffer{}, nil
-			}
-			if strings.Contains(args[1], "/foo.go") {
-				return *bytes.NewBufferString("foo contents"), bytes.Buffer{}, nil
+		c := newTestClient(t, prMetaHandler(t, "abc123", func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/foo.go") {
+				_, _ = io.WriteString(w, "foo contents")
+				return
 			}
-			return bytes.Buffer{}, bytes.Buffer{}, errors.New("404")
-		})
-		c := NewClient()
-		got, err := c.FetchChangedFileContents("", "", twoFileDiff)
+			writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+		}))
+		got, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", twoFileDiff)
 		if err == nil || !strings.Contains(err.Error(), "failed to fetch 1") {
 			t.Fatalf("expected failed-to-fetch error, got %v", err)
 		}
@@ -483,12 +513,12 @@ type stubFetcher struct {
 	fetchFCCalled bool
 }
 
-func (s *stubFetcher) FetchDiff(repo, pr string) (string, error) {
+func (s *stubFetcher) FetchDiff(_ context.Context, repo, pr string) (string, error) {
 	s.gotRepoFD, s.gotPRFD = repo, pr
 	return s.diff, s.diffErr
 }
 
-func (s *stubFetcher) FetchChangedFileContents(repo, pr, diff string) (map[string][]byte, error) {
+func (s *stubFetcher) FetchChangedFileContents(_ context.Context, repo, pr, diff string) (map[string][]byte, error) {
 	s.fetchFCCalled = true
 	s.gotRepoFC, s.gotPRFC, s.gotDiffFC = repo, pr, diff
 	return s.files, s.filesErr
@@ -502,7 +532,7 @@ func TestCollectTODOs(t *testing.T) {
 			err   error
 		)
 		stderrOut := captureStderr(t, func() {
-			todos, err = CollectTODOs(s, "o/r", "1", defaultTypes)
+			todos, err = CollectTODOs(context.Background(), s, "o/r", "1", defaultTypes)
 		})
 		if err == nil || err.Error() != "diff failed" {
 			t.Fatalf("expected diff failed error, got %v", err)
@@ -534,7 +564,7 @@ func TestCollectTODOs(t *testing.T) {
 		var todos []types.TODO
 		var err error
 		stderrOut := captureStderr(t, func() {
-			todos, err = CollectTODOs(s, "o/r", "2", defaultTypes)
+			todos, err = CollectTODOs(context.Background(), s, "o/r", "2", defaultTypes)
 		})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -557,7 +587,7 @@ func TestCollectTODOs(t *testing.T) {
 			err   error
 		)
 		stderrOut := captureStderr(t, func() {
-			todos, err = CollectTODOs(s, "o/r", "3", defaultTypes)
+			todos, err = CollectTODOs(context.Background(), s, "o/r", "3", defaultTypes)
 		})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -579,6 +609,59 @@ func TestCollectTODOs(t *testing.T) {
 		}
 	})
 
+	t.Run("context lines attached from file contents", func(t *testing.T) {
+		s := &stubFetcher{
+			diff:  sampleDiff,
+			files: map[string][]byte{"foo.go": []byte("package foo\n// TODO: add bar\n")},
+		}
+		todos, err := Collect(context.Background(), s, "o/r", "5", CollectOptions{Types: defaultTypes, ContextLines: 3})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := expectedTODO
+		want.Context = []types.ContextLine{
+			{Line: 1, Text: "package foo"},
+			{Line: 2, Text: "// TODO: add bar", Added: true},
+		}
+		if !reflect.DeepEqual(todos, []types.TODO{want}) {
+			t.Fatalf("todos = %#v, expected %#v", todos, []types.TODO{want})
+		}
+	})
+
+	t.Run("file contents decoded to UTF-8", func(t *testing.T) {
+		diff := "diff --git a/legacy.c b/legacy.c\n" +
+			"--- a/legacy.c\n" +
+			"+++ b/legacy.c\n" +
+			"@@ -1,1 +1,2 @@\n" +
+			" int x;\n" +
+			"+// TODO: \x82\xa0\n"
+		s := &stubFetcher{
+			diff:  diff,
+			files: map[string][]byte{"legacy.c": []byte("int x;\n// TODO: \x82\xa0\n")},
+		}
+		todos, err := Collect(context.Background(), s, "o/r", "6", CollectOptions{
+			Types:        defaultTypes,
+			Encodings:    []charset.Mapping{{Pattern: "*.c", Encoding: "shift_jis"}},
+			ContextLines: 1,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := []types.TODO{{
+			Filename: "legacy.c",
+			Line:     2,
+			Comment:  "// TODO: あ",
+			Type:     "TODO",
+			Context: []types.ContextLine{
+				{Line: 1, Text: "int x;"},
+				{Line: 2, Text: "// TODO: あ", Added: true},
+			},
+		}}
+		if !reflect.DeepEqual(todos, want) {
+			t.Fatalf("todos = %#v, expected %#v", todos, want)
+		}
+	})
+
 	t.Run("custom marker type flows through collection", func(t *testing.T) {
 		diff := "diff --git a/security.go b/security.go\n" +
 			"index 0000000..1111111 100644\n" +
@@ -591,7 +674,7 @@ func TestCollectTODOs(t *testing.T) {
 			diff:  diff,
 			files: map[string][]byte{"security.go": []byte("package security\n// SECURITY: review token handling\n")},
 		}
-		todos, err := CollectTODOs(s, "o/r", "4", []string{"TODO", "SECURITY"})
+		todos, err := CollectTODOs(context.Background(), s, "o/r", "4", []string{"TODO", "SECURITY"})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -606,3 +689,154 @@ func TestCollectTODOs(t *testing.T) {
 		}
 	})
 }
+
+func TestStreamDiff(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/pulls/42" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, "diff body")
+	}))
+
+	body, err := c.StreamDiff(context.Background(), "owner/repo", "#42")
+	if err != nil {
+		t.Fatalf("StreamDiff() unexpected error = %v", err)
+	}
+	defer body.Close()
+	got, _ := io.ReadAll(body)
+	if string(got) != "diff body" {
+		t.Fatalf("StreamDiff() = %q, expected %q", got, "diff body")
+	}
+}
+
+// streamingFetcher is a stubFetcher that also implements DiffStreamer.
+type streamingFetcher struct {
+	stubFetcher
+	closeErr   error
+	fetcherErr error
+	closed     bool
+}
+
+type closeFunc struct {
+	io.Reader
+	close func() error
+}
+
+func (c closeFunc) Close() error { return c.close() }
+
+func (s *streamingFetcher) StreamDiff(_ context.Context, repo, pr string) (io.ReadCloser, error) {
+	if s.diffErr != nil {
+		return nil, s.diffErr
+	}
+	return closeFunc{strings.NewReader(s.diff), func() error {
+		s.closed = true
+		return s.closeErr
+	}}, nil
+}
+
+func (s *streamingFetcher) HeadFileFetcher(_ context.Context, repo, pr string) (FileFetcher, error) {
+	if s.fetcherErr != nil {
+		return nil, s.fetcherErr
+	}
+	return mapFetcher(s.files), nil
+}
+
+// mapFetcher is a FileFetcher of the files in a map.
+type mapFetcher map[string][]byte
+
+func (m mapFetcher) Prefetch(string) {}
+
+func (m mapFetcher) Close() {}
+
+func (m mapFetcher) Fetch(path string) ([]byte, error) {
+	if data, ok := m[path]; ok {
+		return data, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestCollectStream(t *testing.T) {
+	diff := "diff --git a/foo.go b/foo.go\n" +
+		"--- a/foo.go\n" +
+		"+++ b/foo.go\n" +
+		"@@ -1,1 +1,2 @@\n" +
+		" package foo\n" +
+		"+// TODO: add bar\n" +
+		"diff --git a/gone.go b/gone.go\n" +
+		"--- a/gone.go\n" +
+		"+++ b/gone.go\n" +
+		"@@ -1,0 +1,1 @@\n" +
+		"+// FIXME: from hunk\n"
+	files := map[string][]byte{"foo.go": []byte("package foo\n// TODO: add bar\n")}
+	want := []types.TODO{
+		{Filename: "foo.go", Line: 2, Comment: "// TODO: add bar", Type: "TODO"},
+		{Filename: "gone.go", Line: 1, Comment: "// FIXME: from hunk", Type: "FIXME"},
+	}
+
+	t.Run("files fetched as the diff is parsed", func(t *testing.T) {
+		s := &streamingFetcher{stubFetcher: stubFetcher{diff: diff, files: files}}
+		var todos []types.TODO
+		var err error
+		stderrOut := captureStderr(t, func() {
+			todos, err = Collect(context.Background(), s, "o/r", "1", CollectOptions{Types: defaultTypes})
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(todos, want) {
+			t.Fatalf("todos = %#v, expected %#v", todos, want)
+		}
+		if !s.closed {
+			t.Fatal("diff stream was not closed")
+		}
+		if s.fetchFCCalled {
+			t.Fatal("FetchChangedFileContents called for a streaming fetcher")
+		}
+		if !strings.Contains(stderrOut, "could not fetch 1 changed file(s)") || !strings.Contains(stderrOut, "  gone.go: not found\n") {
+			t.Fatalf("stderr = %q, expected a fetch warning", stderrOut)
+		}
+	})
+
+	t.Run("head lookup failure falls back to hunks", func(t *testing.T) {
+		s := &streamingFetcher{stubFetcher: stubFetcher{diff: diff}, fetcherErr: errors.New("no head")}
+		var todos []types.TODO
+		stderrOut := captureStderr(t, func() {
+			todos, _ = Collect(context.Background(), s, "o/r", "1", CollectOptions{Types: defaultTypes})
+		})
+		if len(todos) != 2 {
+			t.Fatalf("todos = %#v, expected 2 from hunk text", todos)
+		}
+		if !strings.Contains(stderrOut, "no head") {
+			t.Fatalf("stderr = %q, expected the head lookup error", stderrOut)
+		}
+	})
+
+	t.Run("stream close error returned", func(t *testing.T) {
+		s := &streamingFetcher{stubFetcher: stubFetcher{diff: diff, files: files}, closeErr: errors.New("stream failed")}
+		var err error
+		captureStderr(t, func() {
+			_, err = Collect(context.Background(), s, "o/r", "1", CollectOptions{Types: defaultTypes})
+		})
+		if err == nil || err.Error() != "stream failed" {
+			t.Fatalf("err = %v, expected stream failed", err)
+		}
+	})
+
+	t.Run("oversized files skipped with a warning", func(t *testing.T) {
+		s := &streamingFetcher{stubFetcher: stubFetcher{diff: diff, files: map[string][]byte{
+			"foo.go":  files["foo.go"],
+			"gone.go": []byte("// FIXME: from hunk\n"),
+		}}}
+		var todos []types.TODO
+		stderrOut := captureStderr(t, func() {
+			todos, _ = Collect(context.Background(), s, "o/r", "1", CollectOptions{Types: defaultTypes, MaxFileSize: 100})
+		})
+		if !reflect.DeepEqual(todos, want[1:]) {
+			t.Fatalf("todos = %#v, expected %#v", todos, want[1:])
+		}
+		if !strings.Contains(stderrOut, "skipped 1 file(s) whose diff exceeds the maximum file size: foo.go") {
+			t.Fatalf("stderr = %q, expected a skip warning", stderrOut)
+		}
+	})
+}
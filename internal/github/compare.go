@@ -0,0 +1,102 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SetCompareBase sets the branch that branches without an open PR are
+// compared with; empty means the repository's default branch.
+func (c *Client) SetCompareBase(base string) {
+	c.compareBase = base
+}
+
+const compareQuery = `query($owner: String!, $name: String!, $base: String!, $head: String!) {
+  repository(owner: $owner, name: $name) {
+    nameWithOwner
+    defaultBranchRef { name target { oid } }
+    base: ref(qualifiedName: $base) { name target { oid } }
+    head: ref(qualifiedName: $head) { name target { oid } }
+  }
+}`
+
+// branchRef is a branch returned by compareQuery.
+type branchRef struct {
+	Name   string `json:"name"`
+	Target struct {
+		Oid string `json:"oid"`
+	} `json:"target"`
+}
+
+// fetchCompareMeta returns the metadata of the comparison pr, with its
+// branches and their commits standing in for a PR's base and head.
+func (c *Client) fetchCompareMeta(ctx context.Context, pr prRef) (repoMeta, error) {
+	var meta repoMeta
+	var resp struct {
+		Repository struct {
+			NameWithOwner    string     `json:"nameWithOwner"`
+			DefaultBranchRef *branchRef `json:"defaultBranchRef"`
+			Base             *branchRef `json:"base"`
+			Head             *branchRef `json:"head"`
+		} `json:"repository"`
+	}
+	vars := map[string]any{"owner": pr.repo.owner, "name": pr.repo.name, "base": "refs/heads/" + pr.base, "head": "refs/heads/" + pr.head}
+	if err := c.query(ctx, pr.repo.host, compareQuery, vars, &resp); err != nil {
+		return meta, err
+	}
+	repo := resp.Repository
+	if repo.Head == nil {
+		return meta, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("no open pull requests found for branch %q, and it has not been pushed to %s", pr.head, pr.repo.nameWithOwner())}
+	}
+	base := repo.Base
+	if pr.base == "" {
+		base = repo.DefaultBranchRef
+	}
+	if base == nil {
+		return meta, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("base branch %q not found in %s", pr.base, pr.repo.nameWithOwner())}
+	}
+	if base.Name == repo.Head.Name || base.Target.Oid == repo.Head.Target.Oid {
+		return meta, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("no open pull requests found for branch %q, and it has no changes to compare with %q", pr.head, base.Name)}
+	}
+	fmt.Fprintf(os.Stderr, "No open pull request for branch %q; comparing it with %q\n", pr.head, base.Name)
+
+	meta.Repository.NameWithOwner = repo.NameWithOwner
+	if repo.DefaultBranchRef != nil {
+		meta.Repository.DefaultBranchRef.Name = repo.DefaultBranchRef.Name
+	}
+	head := &prMeta{BaseRefName: base.Name, BaseRefOid: base.Target.Oid, HeadRefOid: repo.Head.Target.Oid}
+	head.HeadRepository.NameWithOwner = repo.NameWithOwner
+	meta.Repository.PullRequest = head
+	return meta, nil
+}
+
+// streamCompareDiff streams the diff of the comparison pr: the changes on
+// its head branch since it diverged from the base branch, as a PR would
+// show them. It is computed by local git when the client's local
+// repository has both commits, and otherwise fetched from the compare API
+// and cached like a PR diff.
+func (c *Client) streamCompareDiff(ctx context.Context, pr prRef) (io.ReadCloser, error) {
+	meta, err := c.fetchPRMeta(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	head := meta.Repository.PullRequest
+	c.local.fetchPR(ctx, pr, head.HeadRefOid)
+	c.local.fetch(ctx, pr.repo, "refs/heads/"+head.BaseRefName, head.BaseRefOid)
+	if body, ok := c.local.diff(ctx, head.BaseRefOid, head.HeadRefOid); ok {
+		return body, nil
+	}
+
+	key := diffKey(pr, head)
+	if body, ok := c.cache.Open(key); ok {
+		return body, nil
+	}
+	path := fmt.Sprintf("repos/%s/compare/%s...%s", pr.repo.nameWithOwner(), head.BaseRefOid, head.HeadRefOid)
+	body, err := c.get(ctx, pr.repo.host, path, diffMediaType)
+	if err != nil {
+		return nil, err
+	}
+	return c.cache.Tee(key, body), nil
+}
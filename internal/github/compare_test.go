@@ -0,0 +1,165 @@
+package github
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/config"
+)
+
+// compareHandler serves a repository whose branches are given by name and
+// commit, with "main" as the default branch and no open PRs. Compare
+// diffs are served by diff.
+func compareHandler(t *testing.T, branches map[string]string, diff http.HandlerFunc) http.Handler {
+	t.Helper()
+	ref := func(name string) string {
+		oid, ok := branches[strings.TrimPrefix(name, "refs/heads/")]
+		if !ok {
+			return "null"
+		}
+		return `{"name":"` + strings.TrimPrefix(name, "refs/heads/") + `","target":{"oid":"` + oid + `"}}`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		req := decodeGraphQL(t, r)
+		if strings.Contains(req.Query, "pullRequests(") {
+			writeJSON(w, http.StatusOK, `{"data":{"repository":{"pullRequests":{"nodes":[]}}}}`)
+			return
+		}
+		base, _ := req.Variables["base"].(string)
+		head, _ := req.Variables["head"].(string)
+		writeJSON(w, http.StatusOK, `{"data":{"repository":{"nameWithOwner":"o/r","defaultBranchRef":`+ref("main")+`,"base":`+ref(base)+`,"head":`+ref(head)+`}}}`)
+	})
+	mux.HandleFunc("GET /repos/o/r/compare/", diff)
+	return mux
+}
+
+func TestStreamCompareDiff(t *testing.T) {
+	branches := map[string]string{"main": testBaseSHA, "develop": testMergeSHA, "feature": testSHA, "merged": testBaseSHA}
+	tests := []struct {
+		name     string
+		head     string
+		base     string
+		want     string
+		wantRefs config.RemoteConfigRefs
+		wantErr  string
+	}{
+		{
+			name:     "default branch",
+			want:     "/repos/o/r/compare/" + testBaseSHA + "..." + testSHA,
+			wantRefs: config.RemoteConfigRefs{DefaultBranchRef: "main", DefaultRepo: "o/r", BaseBranchRef: "main", BaseRepo: "o/r", HeadRefOid: testSHA, HeadRepo: "o/r"},
+		},
+		{
+			name:     "explicit base",
+			base:     "develop",
+			want:     "/repos/o/r/compare/" + testMergeSHA + "..." + testSHA,
+			wantRefs: config.RemoteConfigRefs{DefaultBranchRef: "main", DefaultRepo: "o/r", BaseBranchRef: "develop", BaseRepo: "o/r", HeadRefOid: testSHA, HeadRepo: "o/r"},
+		},
+		{name: "missing base", base: "gone", wantErr: `base branch "gone" not found`},
+		{name: "base branch itself", head: "main", wantErr: `no changes to compare with "main"`},
+		{name: "branch at the base commit", head: "merged", wantErr: `no changes to compare with "main"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, compareHandler(t, branches, func(w http.ResponseWriter, r *http.Request) {
+				if accept := r.Header.Get("Accept"); accept != diffMediaType {
+					t.Errorf("Accept = %q, expected %q", accept, diffMediaType)
+				}
+				_, _ = io.WriteString(w, r.URL.Path)
+			}))
+			c.SetCompareBase(tt.base)
+
+			var got string
+			var err error
+			stderr := captureStderr(t, func() {
+				var body io.ReadCloser
+				if body, err = c.StreamDiff(context.Background(), "o/r", cmp.Or(tt.head, "feature")); err == nil {
+					data, _ := io.ReadAll(body)
+					body.Close()
+					got = string(data)
+				}
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, ErrNotFound) {
+					t.Fatalf("StreamDiff() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("StreamDiff() = %q, %v, expected %q", got, err, tt.want)
+			}
+			if !strings.Contains(stderr, `No open pull request for branch "feature"; comparing it with "`+tt.wantRefs.BaseBranchRef+`"`) {
+				t.Errorf("stderr = %q, expected a note about the comparison", stderr)
+			}
+
+			refs, err := c.FetchRemoteConfigRefs(context.Background(), "o/r", "feature")
+			if err != nil || refs != tt.wantRefs {
+				t.Errorf("FetchRemoteConfigRefs() = %+v, %v, expected %+v", refs, err, tt.wantRefs)
+			}
+		})
+	}
+
+	t.Run("current branch config", func(t *testing.T) {
+		original := currentBranch
+		currentBranch = func(context.Context) (string, error) { return "feature", nil }
+		t.Cleanup(func() { currentBranch = original })
+		c := newTestClient(t, compareHandler(t, branches, http.NotFound))
+		c.SetCompareBase("develop")
+
+		var refs config.RemoteConfigRefs
+		var err error
+		captureStderr(t, func() { refs, err = c.FetchRemoteConfigRefs(context.Background(), "o/r", "") })
+		want := config.RemoteConfigRefs{DefaultBranchRef: "main", DefaultRepo: "o/r", BaseBranchRef: "develop", BaseRepo: "o/r", HeadRefOid: testSHA, HeadRepo: "o/r"}
+		if err != nil || refs != want {
+			t.Errorf("FetchRemoteConfigRefs() = %+v, %v, expected %+v", refs, err, want)
+		}
+	})
+
+	t.Run("unpushed branch", func(t *testing.T) {
+		c := newTestClient(t, compareHandler(t, branches, http.NotFound))
+		_, err := c.StreamDiff(context.Background(), "o/r", "local-only")
+		if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "has not been pushed") {
+			t.Errorf("StreamDiff() error = %v, expected the branch to be missing", err)
+		}
+	})
+}
+
+func TestStreamCompareDiffLocal(t *testing.T) {
+	dir, base := gitRepo(t, map[string]string{"a.go": "package a\n"})
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n// TODO: compare\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-am", "feature")
+	head := git(t, dir, "rev-parse", "HEAD")
+
+	c := newTestClient(t, compareHandler(t, map[string]string{"main": base, "feature": head}, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected compare request %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	c.SetLocalRepo(dir, false)
+	defer c.Close()
+
+	var data []byte
+	captureStderr(t, func() {
+		body, err := c.StreamDiff(context.Background(), "o/r", "feature")
+		if err != nil {
+			t.Fatalf("StreamDiff() error = %v", err)
+		}
+		data, err = io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			t.Fatalf("reading the diff: %v", err)
+		}
+	})
+	if got, want := summarizeDiff(string(data)), []string{"a.go 2: 3:// TODO: compare"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamDiff() = %q, expected %q\n%s", got, want, data)
+	}
+}
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// Errors classifying failed GitHub API requests. Test for them with
+// errors.Is.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrAuth        = errors.New("authentication failed")
+	ErrRateLimited = errors.New("rate limit exceeded")
+	ErrPermission  = errors.New("permission denied")
+)
+
+// APIError is a failed GitHub API request. errors.Is matches it against its
+// Kind, and errors.As reaches the underlying *api.HTTPError or
+// *api.GraphQLError.
+type APIError struct {
+	// Kind is ErrNotFound, ErrAuth, ErrRateLimited, ErrPermission, or nil
+	// for other failures.
+	Kind error
+	// StatusCode is the HTTP status of the response, or 0 for GraphQL
+	// errors and requests that were never sent.
+	StatusCode int
+	// Header holds the response headers, if there was a response.
+	Header http.Header
+	Err    error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError wraps the HTTP and GraphQL errors returned by go-gh in an
+// APIError. Other errors, such as network failures, are returned unchanged.
+func classifyError(err error) error {
+	var httpErr *api.HTTPError
+	if errors.As(err, &httpErr) {
+		return &APIError{
+			Kind:       statusKind(httpErr.StatusCode, httpErr.Headers),
+			StatusCode: httpErr.StatusCode,
+			Header:     httpErr.Headers,
+			Err:        err,
+		}
+	}
+	var gqlErr *api.GraphQLError
+	if errors.As(err, &gqlErr) {
+		return &APIError{Kind: graphQLKind(gqlErr), Err: err}
+	}
+	return err
+}
+
+// statusKind classifies an HTTP error status.
+func statusKind(status int, header http.Header) error {
+	switch {
+	case status == http.StatusUnauthorized:
+		return ErrAuth
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case rateLimited(status, header):
+		return ErrRateLimited
+	case status == http.StatusForbidden:
+		return ErrPermission
+	}
+	return nil
+}
+
+// rateLimited reports whether a response rejected the request for exceeding
+// a primary or secondary rate limit.
+func rateLimited(status int, header http.Header) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		return header.Get("Retry-After") != "" || header.Get("X-Ratelimit-Remaining") == "0"
+	}
+	return false
+}
+
+// graphQLKind classifies a GraphQL error by the type of its first error
+// with a known type.
+func graphQLKind(err *api.GraphQLError) error {
+	for _, e := range err.Errors {
+		switch e.Type {
+		case "NOT_FOUND":
+			return ErrNotFound
+		case "FORBIDDEN", "INSUFFICIENT_SCOPES":
+			return ErrPermission
+		case "RATE_LIMITED":
+			return ErrRateLimited
+		}
+	}
+	return nil
+}
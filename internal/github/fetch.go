@@ -0,0 +1,203 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxConcurrentFetches bounds the requests fetching changed files at once.
+const maxConcurrentFetches = 8
+
+// fetchAttempts is the number of times a changed file is requested before
+// it falls back to diff-only parsing.
+const fetchAttempts = 4
+
+// Backoff between attempts starts at minRetryDelay and doubles. Waits the
+// API asks for that are longer than maxRetryDelay are not worth making.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Replaced in tests.
+var (
+	sleep = sleepContext
+	now   = time.Now
+)
+
+// FileFailure records why a changed file could not be fetched.
+type FileFailure struct {
+	Path   string
+	Reason string
+}
+
+// FileFetchError lists the changed files that could not be fetched, sorted
+// by path.
+type FileFetchError struct {
+	Failures []FileFailure
+}
+
+// newFileFetchError returns a FileFetchError for failures, or nil if there
+// are none.
+func newFileFetchError(failures []FileFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Path < failures[j].Path })
+	return &FileFetchError{Failures: failures}
+}
+
+func (e *FileFetchError) Error() string {
+	reasons := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		reasons[i] = fmt.Sprintf("%s (%s)", f.Path, f.Reason)
+	}
+	return fmt.Sprintf("failed to fetch %d changed file(s): %s", len(e.Failures), strings.Join(reasons, ", "))
+}
+
+// retryAfter returns how long a response asks the client to wait, from its
+// Retry-After header or, for an exhausted rate limit, its reset time.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now()), 0), true
+		}
+	}
+	if header.Get("X-Ratelimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now()), 0), true
+		}
+	}
+	return 0, false
+}
+
+// retryDelay decides whether a failed request is worth repeating and how
+// long to wait first. Requests that got no response, server errors, and
+// rate limits are retried; client errors, and waits longer than
+// maxRetryDelay, are not.
+func retryDelay(err error, attempt int) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode < 500 && !errors.Is(apiErr, ErrRateLimited) {
+			return 0, false
+		}
+		if d, ok := retryAfter(apiErr.Header); ok {
+			return d, d <= maxRetryDelay
+		}
+	}
+	return min(minRetryDelay<<(attempt-1), maxRetryDelay), true
+}
+
+// fetchFailure describes a failed request for a FileFailure.
+func fetchFailure(err error, attempts int) string {
+	reason := err.Error()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		reason = fmt.Sprintf("HTTP %d %s", apiErr.StatusCode, http.StatusText(apiErr.StatusCode))
+		if d, ok := retryAfter(apiErr.Header); ok && d > maxRetryDelay {
+			return fmt.Sprintf("%s; rate limited for %s", reason, d.Round(time.Second))
+		}
+	}
+	if attempts > 1 {
+		return fmt.Sprintf("%s after %d attempts", reason, attempts)
+	}
+	return reason
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pause holds back every request while the API is asking clients to slow
+// down, so that one rate-limited request delays its siblings too.
+type pause struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// wait sleeps until the pause is over.
+func (p *pause) wait(ctx context.Context) error {
+	p.mu.Lock()
+	d := p.until.Sub(now())
+	p.mu.Unlock()
+	if d > 0 {
+		return sleep(ctx, d)
+	}
+	return nil
+}
+
+// extend makes the pause last at least d from now.
+func (p *pause) extend(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := now().Add(d); until.After(p.until) {
+		p.until = until
+	}
+}
+
+// headFetcher fetches changed files at the PR head with bounded concurrency,
+// retrying transient failures.
+type headFetcher struct {
+	client *Client
+	repo   repoRef
+	ref    string
+	slots  chan struct{}
+	pause  pause
+}
+
+func newHeadFetcher(c *Client, repo repoRef, ref string) *headFetcher {
+	return &headFetcher{client: c, repo: repo, ref: ref, slots: make(chan struct{}, maxConcurrentFetches)}
+}
+
+// fetch returns the contents of path. Server errors and rate limits are
+// retried with backoff, honoring Retry-After; other failures are returned
+// at once. The error describes the last failure.
+func (f *headFetcher) fetch(ctx context.Context, path string) ([]byte, error) {
+	select {
+	case f.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-f.slots }()
+
+	for attempt := 1; ; attempt++ {
+		if err := f.pause.wait(ctx); err != nil {
+			return nil, err
+		}
+		data, err := f.client.fetchFile(ctx, f.repo, path, f.ref)
+		if err == nil {
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		delay, retry := retryDelay(err, attempt)
+		if !retry || attempt == fetchAttempts {
+			return nil, errors.New(fetchFailure(err, attempt))
+		}
+		if errors.Is(err, ErrRateLimited) {
+			f.pause.extend(delay)
+		} else if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
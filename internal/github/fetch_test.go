@@ -0,0 +1,229 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// withNow fixes the package clock for the duration of a test. Callers must
+// not use t.Parallel().
+func withNow(t *testing.T, at time.Time) {
+	t.Helper()
+	original := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = original })
+}
+
+// httpError returns the error of a request answered with status and header.
+func httpError(status int, header http.Header) error {
+	return classifyError(&api.HTTPError{StatusCode: status, Headers: header})
+}
+
+func TestRetryDelay(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	withNow(t, at)
+	resetIn := func(d time.Duration) string { return fmt.Sprint(at.Add(d).Unix()) }
+
+	tests := []struct {
+		name      string
+		err       error
+		attempt   int
+		want      time.Duration
+		wantRetry bool
+	}{
+		{name: "no response", err: errors.New("connection reset"), attempt: 1, want: time.Second, wantRetry: true},
+		{name: "server error backs off exponentially", err: httpError(502, nil), attempt: 3, want: 4 * time.Second, wantRetry: true},
+		{name: "backoff is capped", err: httpError(500, nil), attempt: 20, want: maxRetryDelay, wantRetry: true},
+		{name: "not found", err: httpError(404, nil), attempt: 1},
+		{name: "forbidden", err: httpError(403, http.Header{}), attempt: 1},
+		{name: "missing token", err: &APIError{Kind: ErrAuth, Err: errors.New("no token")}, attempt: 1},
+		{
+			name:    "server error with Retry-After seconds",
+			err:     httpError(503, http.Header{"Retry-After": {"12"}}),
+			attempt: 1, want: 12 * time.Second, wantRetry: true,
+		},
+		{
+			name:    "Retry-After date",
+			err:     httpError(429, http.Header{"Retry-After": {at.Add(30 * time.Second).Format(http.TimeFormat)}}),
+			attempt: 1, want: 30 * time.Second, wantRetry: true,
+		},
+		{
+			name:    "secondary rate limit",
+			err:     httpError(403, http.Header{"Retry-After": {"60"}}),
+			attempt: 2, want: time.Minute, wantRetry: true,
+		},
+		{
+			name:    "primary rate limit resets soon",
+			err:     httpError(403, http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {resetIn(20 * time.Second)}}),
+			attempt: 1, want: 20 * time.Second, wantRetry: true,
+		},
+		{
+			name:    "primary rate limit resets too late",
+			err:     httpError(403, http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {resetIn(time.Hour)}}),
+			attempt: 1, want: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, retry := retryDelay(tt.err, tt.attempt)
+			if retry != tt.wantRetry || (retry || tt.want != 0) && got != tt.want {
+				t.Errorf("retryDelay() = %v, %v, expected %v, %v", got, retry, tt.want, tt.wantRetry)
+			}
+		})
+	}
+}
+
+// response is a canned HTTP response of a test server.
+type response struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func TestHeadFetcherFetch(t *testing.T) {
+	withNow(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	tests := []struct {
+		name       string
+		responses  []response
+		want       string
+		wantErr    string
+		wantDelays []time.Duration
+	}{
+		{
+			name: "transient server errors are retried",
+			responses: []response{
+				{status: 502, body: "{}"},
+				{status: 502, body: "{}"},
+				{status: 200, body: "package main\n"},
+			},
+			want:       "package main\n",
+			wantDelays: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:      "client errors are not retried",
+			responses: []response{{status: 404, body: "{}"}},
+			wantErr:   "HTTP 404 Not Found",
+		},
+		{
+			name:       "persistent server errors give up",
+			responses:  []response{{status: 503, body: "{}"}},
+			wantErr:    "HTTP 503 Service Unavailable after 4 attempts",
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name: "secondary rate limit pauses for Retry-After",
+			responses: []response{
+				{status: 403, header: http.Header{"Retry-After": {"3"}}, body: "{}"},
+				{status: 200, body: "ok"},
+			},
+			want:       "ok",
+			wantDelays: []time.Duration{3 * time.Second},
+		},
+		{
+			name:      "long rate limit gives up",
+			responses: []response{{status: 429, header: http.Header{"Retry-After": {"600"}}, body: "{}"}},
+			wantErr:   "HTTP 429 Too Many Requests; rate limited for 10m0s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delays := withoutSleep(t)
+			calls := 0
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp := tt.responses[min(calls, len(tt.responses)-1)]
+				calls++
+				for k, v := range resp.header {
+					w.Header()[k] = v
+				}
+				writeJSON(w, resp.status, resp.body)
+			}))
+
+			got, err := newHeadFetcher(c, repoRef{host: "github.com", owner: "o", name: "r"}, "abc").fetch(context.Background(), "main.go")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("fetch() error = %v, expected %q", err, tt.wantErr)
+				}
+			} else if err != nil || string(got) != tt.want {
+				t.Fatalf("fetch() = %q, %v, expected %q", got, err, tt.want)
+			}
+			if !reflect.DeepEqual(*delays, tt.wantDelays) {
+				t.Errorf("delays = %v, expected %v", *delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestHeadFetcherFetchCanceled(t *testing.T) {
+	withoutSleep(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		cancel()
+		writeJSON(w, http.StatusBadGateway, "{}")
+	}))
+
+	_, err := newHeadFetcher(c, repoRef{host: "github.com", owner: "o", name: "r"}, "abc").fetch(ctx, "main.go")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("fetch() error = %v, expected context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("requests = %d, expected no retries after cancellation", calls)
+	}
+}
+
+func TestFetchChangedFileContentsConcurrency(t *testing.T) {
+	var diff strings.Builder
+	for i := range 30 {
+		fmt.Fprintf(&diff, "diff --git a/f%02d.go b/f%02d.go\n--- a/f%02d.go\n+++ b/f%02d.go\n@@ -1,0 +1,1 @@\n+x\n", i, i, i, i)
+	}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	release := make(chan struct{})
+	c := newTestClient(t, prMetaHandler(t, "abc", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		if running == maxConcurrentFetches {
+			close(release)
+		}
+		mu.Unlock()
+		<-release
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "f07.go") {
+			writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+			return
+		}
+		_, _ = io.WriteString(w, r.URL.Path)
+	}))
+
+	files, err := c.FetchChangedFileContents(context.Background(), "o/r", "1", diff.String())
+	if peak != maxConcurrentFetches {
+		t.Errorf("peak concurrent fetches = %d, expected %d", peak, maxConcurrentFetches)
+	}
+	if len(files) != 29 || string(files["f12.go"]) != "/repos/o/r/contents/f12.go" {
+		t.Errorf("FetchChangedFileContents() fetched %d files, expected 29 with their own contents", len(files))
+	}
+	var fetchErr *FileFetchError
+	if !errors.As(err, &fetchErr) || !reflect.DeepEqual(fetchErr.Failures, []FileFailure{{Path: "f07.go", Reason: "HTTP 404 Not Found"}}) {
+		t.Fatalf("FetchChangedFileContents() error = %v, expected a FileFetchError for f07.go", err)
+	}
+	if want := "failed to fetch 1 changed file(s): f07.go (HTTP 404 Not Found)"; err.Error() != want {
+		t.Errorf("Error() = %q, expected %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,279 @@
+package github
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+	"github.com/Suree33/gh-pr-todo/internal/charset"
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+const (
+	// filesPerPage is the page size of the pull request files API.
+	filesPerPage = 100
+	// maxListedFiles is the most files the pull request files API lists.
+	maxListedFiles = 3000
+	// diffContext is the number of context lines around changes in diffs
+	// computed from file contents, as in git's default.
+	diffContext = 3
+)
+
+// diffTooLarge reports whether err is GitHub refusing to render a diff
+// that exceeds its size or file count limits.
+func diffTooLarge(err error) bool {
+	var httpErr *api.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	if httpErr.StatusCode == http.StatusNotAcceptable {
+		return true
+	}
+	for _, item := range httpErr.Errors {
+		if item.Code == "too_large" {
+			return true
+		}
+	}
+	return false
+}
+
+// prFile is an entry of the pull request files API.
+type prFile struct {
+	Filename         string `json:"filename"`
+	PreviousFilename string `json:"previous_filename"`
+	Status           string `json:"status"`
+	Changes          int    `json:"changes"`
+	// Patch is omitted for binary files and for files whose diff is too
+	// large.
+	Patch *string `json:"patch"`
+}
+
+// file returns the diff header of f.
+func (f prFile) file() diff.File {
+	d := diff.File{OldPath: f.Filename, NewPath: f.Filename}
+	switch f.Status {
+	case "added":
+		d.OldPath, d.IsNew = "", true
+	case "removed":
+		d.NewPath, d.IsDeleted = "", true
+	case "renamed", "copied":
+		d.OldPath = f.PreviousFilename
+		d.IsRename, d.IsCopy = f.Status == "renamed", f.Status == "copied"
+	}
+	return d
+}
+
+// streamFilesDiff returns a diff of the PR built from the pull request
+// files API, for PRs whose diff GitHub refuses to render. Files come with
+// their own patch, except binary files and files whose patch is too large,
+// which are compared locally from their contents at the merge base and the
+// head commit. Files that cannot be compared are left without hunks and
+// reported once the diff has been read. The diff is also written to entry,
+// which is committed only if every file was compared.
+func (c *Client) streamFilesDiff(ctx context.Context, pr prRef, meta *prMeta, entry *cache.Writer) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		b := bufio.NewWriter(io.MultiWriter(w, entry))
+		failures, err := c.writeFilesDiff(ctx, b, pr, meta)
+		if err == nil {
+			err = b.Flush()
+		}
+		if err == nil && len(failures) == 0 {
+			entry.Commit()
+		} else {
+			entry.Abort()
+		}
+		if len(failures) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: could not diff %d changed file(s) whose patch GitHub omitted:\n", len(failures))
+			for _, f := range failures {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", f.Path, f.Reason)
+			}
+		}
+		w.CloseWithError(err)
+	}()
+	return r
+}
+
+// writeFilesDiff writes the diff of the PR's files to w, page by page.
+func (c *Client) writeFilesDiff(ctx context.Context, w io.Writer, pr prRef, meta *prMeta) ([]FileFailure, error) {
+	base := ""
+	var failures []FileFailure
+	listed := 0
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("repos/%s/pulls/%d/files?per_page=%d&page=%d", pr.repo.nameWithOwner(), pr.number, filesPerPage, page)
+		data, err := c.getBytes(ctx, pr.repo.host, path, jsonMediaType)
+		if err != nil {
+			return failures, fmt.Errorf("listing changed files: %w", err)
+		}
+		var files []prFile
+		if err := json.Unmarshal(data, &files); err != nil {
+			return failures, fmt.Errorf("listing changed files: %w", err)
+		}
+
+		for _, f := range files {
+			header := f.file()
+			if f.Patch != nil {
+				if err := writePatch(w, header, *f.Patch); err != nil {
+					return failures, err
+				}
+				continue
+			}
+			if f.Status == "removed" || f.Changes == 0 {
+				// Nothing was added.
+				if err := diff.WriteHeader(w, header); err != nil {
+					return failures, err
+				}
+				continue
+			}
+			if base == "" && !header.IsNew {
+				if base, err = c.mergeBase(ctx, pr.repo, meta); err != nil {
+					return failures, err
+				}
+			}
+			c.local.fetchPR(ctx, pr, meta.HeadRefOid)
+			header.Hunks, header.IsBinary, err = c.compareFile(ctx, pr.repo, header, base, meta.HeadRefOid)
+			if err != nil {
+				if ctx.Err() != nil {
+					return failures, ctx.Err()
+				}
+				failures = append(failures, FileFailure{Path: header.Path(), Reason: err.Error()})
+			}
+			if err := diff.Write(w, header); err != nil {
+				return failures, err
+			}
+		}
+
+		listed += len(files)
+		if len(files) < filesPerPage {
+			return failures, nil
+		}
+		if listed >= maxListedFiles {
+			fmt.Fprintf(os.Stderr, "Warning: GitHub lists only the first %d changed files of a pull request; the rest are not checked\n", maxListedFiles)
+			return failures, nil
+		}
+	}
+}
+
+// writePatch writes the diff of a file whose hunks are given as patch
+// text.
+func writePatch(w io.Writer, header diff.File, patch string) error {
+	if err := diff.WriteHeader(w, header); err != nil {
+		return err
+	}
+	if patch == "" {
+		return nil
+	}
+	if !strings.HasSuffix(patch, "\n") {
+		patch += "\n"
+	}
+	_, err := io.WriteString(w, patch)
+	return err
+}
+
+// mergeBaseKey identifies the merge base of two commits of a repository.
+type mergeBaseKey struct {
+	repo       repoRef
+	base, head string
+}
+
+// mergeBase returns the commit the PR's changes are relative to: the
+// merge base of its base and head. Results are cached, so that the
+// binary files of a PR share one compare request.
+func (c *Client) mergeBase(ctx context.Context, r repoRef, meta *prMeta) (string, error) {
+	key := mergeBaseKey{repo: r, base: meta.BaseRefOid, head: meta.HeadRefOid}
+	c.mu.Lock()
+	base, ok := c.bases[key]
+	c.mu.Unlock()
+	if ok {
+		return base, nil
+	}
+
+	path := fmt.Sprintf("repos/%s/compare/%s...%s?per_page=1", r.nameWithOwner(), meta.BaseRefOid, meta.HeadRefOid)
+	data, err := c.getBytes(ctx, r.host, path, jsonMediaType)
+	if err != nil {
+		return "", fmt.Errorf("finding the merge base: %w", err)
+	}
+	var resp struct {
+		MergeBaseCommit struct {
+			SHA string `json:"sha"`
+		} `json:"merge_base_commit"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.MergeBaseCommit.SHA == "" {
+		return "", fmt.Errorf("finding the merge base: unexpected response")
+	}
+	c.mu.Lock()
+	c.bases[key] = resp.MergeBaseCommit.SHA
+	c.mu.Unlock()
+	return resp.MergeBaseCommit.SHA, nil
+}
+
+// TextDiff returns the hunks of a file git reported as binary, computed
+// from its contents at the merge base and the PR head, when the head
+// contents are UTF-16 text. Both sides are converted to UTF-8 first, as the
+// head contents are before parsing.
+func (c *Client) TextDiff(ctx context.Context, repo, pr string, f diff.File) ([]diff.Hunk, bool, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
+	if err != nil {
+		return nil, false, err
+	}
+	meta, err := c.fetchPRMeta(ctx, ref)
+	if err != nil {
+		return nil, false, err
+	}
+	head := meta.Repository.PullRequest
+	c.local.fetchPR(ctx, ref, head.HeadRefOid)
+	new, err := c.fetchCachedFile(ctx, ref.repo, f.NewPath, head.HeadRefOid)
+	if err != nil {
+		return nil, false, err
+	}
+	if !charset.IsUTF16(new) {
+		return nil, false, nil
+	}
+
+	var old []byte
+	if !f.IsNew {
+		base, err := c.mergeBase(ctx, ref.repo, head)
+		if err != nil {
+			return nil, false, err
+		}
+		if old, err = c.fetchCachedFile(ctx, ref.repo, f.OldPath, base); err != nil {
+			return nil, false, err
+		}
+		switch {
+		case charset.IsUTF16(old):
+			old = charset.ToUTF8(f.OldPath, old, nil)
+		case diff.IsBinary(old):
+			return nil, false, nil
+		}
+	}
+	return diff.Compare(old, charset.ToUTF8(f.NewPath, new, nil), diffContext), true, nil
+}
+
+// compareFile returns the hunks of f computed from its contents at the
+// base and head commits, or whether either is binary. The PR's repository
+// holds the head commit even when it was pushed to a fork.
+func (c *Client) compareFile(ctx context.Context, r repoRef, f diff.File, base, head string) ([]diff.Hunk, bool, error) {
+	var old []byte
+	if !f.IsNew {
+		var err error
+		if old, err = c.fetchCachedFile(ctx, r, f.OldPath, base); err != nil {
+			return nil, false, err
+		}
+	}
+	new, err := c.fetchCachedFile(ctx, r, f.NewPath, head)
+	if err != nil {
+		return nil, false, err
+	}
+	if diff.IsBinary(old) || diff.IsBinary(new) {
+		return nil, true, nil
+	}
+	return diff.Compare(old, new, diffContext), false, nil
+}
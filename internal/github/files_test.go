@@ -0,0 +1,253 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+)
+
+const (
+	testBaseSHA  = "1111111111111111111111111111111111111111"
+	testMergeSHA = "2222222222222222222222222222222222222222"
+)
+
+// filesHandler serves a PR whose diff is too large to render, listing files
+// from the pull request files API and serving contents from contents, keyed
+// by "path@ref".
+func filesHandler(t *testing.T, files []string, contents map[string]string) http.Handler {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"data":{"repository":{"nameWithOwner":"o/r","pullRequest":{"headRefOid":"`+testSHA+`","baseRefOid":"`+testBaseSHA+`","headRepository":{"nameWithOwner":"o/r"}}}}}`)
+	})
+	mux.HandleFunc("GET /repos/o/r/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotAcceptable, `{"message":"Sorry, the diff exceeded the maximum number of lines (20000)","errors":[{"resource":"PullRequest","field":"diff","code":"too_large"}]}`)
+	})
+	mux.HandleFunc("GET /repos/o/r/pulls/1/files", func(w http.ResponseWriter, r *http.Request) {
+		page := 0
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		start := min((page-1)*filesPerPage, len(files))
+		end := min(start+filesPerPage, len(files))
+		writeJSON(w, http.StatusOK, "["+strings.Join(files[start:end], ",")+"]")
+	})
+	mux.HandleFunc("GET /repos/o/r/compare/", func(w http.ResponseWriter, r *http.Request) {
+		if want := "/repos/o/r/compare/" + testBaseSHA + "..." + testSHA; r.URL.Path != want {
+			t.Errorf("compare path = %s, expected %s", r.URL.Path, want)
+		}
+		writeJSON(w, http.StatusOK, `{"merge_base_commit":{"sha":"`+testMergeSHA+`"}}`)
+	})
+	mux.HandleFunc("GET /repos/o/r/contents/", func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/repos/o/r/contents/") + "@" + r.URL.Query().Get("ref")
+		data, ok := contents[key]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+			return
+		}
+		_, _ = io.WriteString(w, data)
+	})
+	return mux
+}
+
+// summarizeDiff describes each file of a diff on one line, with its added
+// lines as "NEWLINE:TEXT".
+func summarizeDiff(data string) []string {
+	var summaries []string
+	for _, f := range diff.Parse(data) {
+		s := f.Path()
+		switch {
+		case f.IsRename:
+			s = f.OldPath + " => " + s
+		case f.IsNew:
+			s += " (new)"
+		case f.IsDeleted:
+			s += " (deleted)"
+		}
+		if f.IsBinary {
+			s += " (binary)"
+		}
+		for _, l := range f.AddedLines() {
+			s += fmt.Sprintf(" %d:%s", l.NewLine, l.Text)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+func TestStreamDiffTooLarge(t *testing.T) {
+	files := []string{
+		`{"filename":"patched.go","status":"modified","changes":1,"patch":"@@ -1 +1,2 @@\n package a\n+// TODO: patched"}`,
+		`{"filename":"big.go","status":"modified","changes":2000}`,
+		`{"filename":"big_new.go","status":"added","changes":2000}`,
+		`{"filename":"gone.go","status":"removed","changes":10}`,
+		`{"filename":"moved.go","previous_filename":"old.go","status":"renamed","changes":0}`,
+		`{"filename":"logo.png","status":"modified","changes":0}`,
+		`{"filename":"image.png","status":"modified","changes":2}`,
+	}
+	contents := map[string]string{
+		"big.go@" + testMergeSHA:    "package a\n\nfunc a() {}\n",
+		"big.go@" + testSHA:         "package a\n\n// TODO: big\nfunc a() {}\n",
+		"big_new.go@" + testSHA:     "package b\n// FIXME: new\n",
+		"image.png@" + testMergeSHA: "\x89PNG\x00",
+		"image.png@" + testSHA:      "\x89PNG\x00\x01",
+	}
+	c := newTestClient(t, filesHandler(t, files, contents))
+
+	body, err := c.StreamDiff(context.Background(), "o/r", "1")
+	if err != nil {
+		t.Fatalf("StreamDiff() error = %v", err)
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("reading the diff: %v", err)
+	}
+
+	want := []string{
+		"patched.go 2:// TODO: patched",
+		"big.go 3:// TODO: big",
+		"big_new.go (new) 1:package b 2:// FIXME: new",
+		"gone.go (deleted)",
+		"old.go => moved.go",
+		"logo.png",
+		"image.png (binary)",
+	}
+	if got := summarizeDiff(string(data)); !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamDiff() =\n%+v\nexpected\n%+v\ndiff:\n%s", got, want, data)
+	}
+}
+
+func TestStreamDiffTooLargePaginated(t *testing.T) {
+	var files []string
+	for i := range filesPerPage + 5 {
+		files = append(files, fmt.Sprintf(`{"filename":"f%d.go","status":"modified","changes":1,"patch":"@@ -1 +1 @@\n-a\n+// TODO: %d"}`, i, i))
+	}
+	c := newTestClient(t, filesHandler(t, files, nil))
+
+	body, err := c.StreamDiff(context.Background(), "o/r", "1")
+	if err != nil {
+		t.Fatalf("StreamDiff() error = %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if got := len(diff.Parse(string(data))); got != len(files) {
+		t.Errorf("StreamDiff() has %d files, expected %d", got, len(files))
+	}
+}
+
+func TestStreamDiffTooLargeFailures(t *testing.T) {
+	files := []string{
+		`{"filename":"big.go","status":"modified","changes":2000}`,
+		`{"filename":"ok.go","status":"modified","changes":1,"patch":"@@ -1 +1 @@\n-a\n+// TODO: ok"}`,
+	}
+	contents := map[string]string{
+		"big.go@" + testMergeSHA: "a\n",
+		"big.go@" + testSHA:      "a\n// TODO: big\n",
+	}
+	dir := t.TempDir()
+	stream := func(broken bool) (string, string) {
+		handler := filesHandler(t, files, contents)
+		c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if broken && strings.Contains(r.URL.Path, "/contents/") {
+				writeJSON(w, http.StatusInternalServerError, `{"message":"Server Error"}`)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}))
+		c.SetCache(cache.New(dir, 0))
+		var data []byte
+		stderr := captureStderr(t, func() {
+			body, err := c.StreamDiff(context.Background(), "o/r", "1")
+			if err != nil {
+				t.Fatalf("StreamDiff() error = %v", err)
+			}
+			data, _ = io.ReadAll(body)
+			body.Close()
+		})
+		return string(data), stderr
+	}
+
+	data, stderr := stream(true)
+	if !strings.Contains(stderr, "could not diff 1 changed file(s)") || !strings.Contains(stderr, "big.go:") {
+		t.Errorf("stderr = %q, expected a warning about big.go", stderr)
+	}
+	want := []string{"big.go", "ok.go 1:// TODO: ok"}
+	if got := summarizeDiff(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("StreamDiff() =\n%+v\nexpected\n%+v", got, want)
+	}
+
+	// The incomplete diff was not cached, so a later run compares big.go.
+	data, stderr = stream(false)
+	if stderr != "" || !strings.Contains(data, "+// TODO: big") {
+		t.Errorf("StreamDiff() = %q with stderr %q, expected big.go to be compared", data, stderr)
+	}
+}
+
+// utf16LE encodes s as UTF-16LE with a byte order mark, as Windows editors
+// save files.
+func utf16LE(s string) string {
+	b := []byte{0xFF, 0xFE}
+	for _, r := range s {
+		b = append(b, byte(r), byte(r>>8))
+	}
+	return string(b)
+}
+
+func TestCollectUTF16(t *testing.T) {
+	contents := map[string]string{
+		"a.cs@" + testMergeSHA: utf16LE("class A {}\n"),
+		"a.cs@" + testSHA:      utf16LE("class A {}\n// TODO: utf16\n"),
+		"b.cs@" + testMergeSHA: utf16LE("class B {}\n"),
+		"b.cs@" + testSHA:      utf16LE("class B {}\n// TODO: b\n"),
+		"logo.png@" + testSHA:  "\x89PNG\r\n\x1a\n\x00\x00",
+	}
+	var compares atomic.Int32
+	handler := prMetaHandler(t, testSHA, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/o/r/pulls/1":
+			_, _ = io.WriteString(w, "diff --git a/a.cs b/a.cs\nindex 1111111..2222222 100644\nBinary files a/a.cs and b/a.cs differ\n"+
+				"diff --git a/b.cs b/b.cs\nindex 1111111..2222222 100644\nBinary files a/b.cs and b/b.cs differ\n"+
+				"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n")
+		case strings.HasPrefix(r.URL.Path, "/repos/o/r/compare/"):
+			compares.Add(1)
+			writeJSON(w, http.StatusOK, `{"merge_base_commit":{"sha":"`+testMergeSHA+`"}}`)
+		case strings.HasPrefix(r.URL.Path, "/repos/o/r/contents/"):
+			path := strings.TrimPrefix(r.URL.Path, "/repos/o/r/contents/")
+			if path == "logo.png" {
+				t.Errorf("fetched %s, which cannot be source text", path)
+			}
+			data, ok := contents[path+"@"+r.URL.Query().Get("ref")]
+			if !ok {
+				writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+				return
+			}
+			_, _ = io.WriteString(w, data)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+	c := newTestClient(t, handler)
+
+	todos, err := Collect(context.Background(), c, "o/r", "1", CollectOptions{Types: []string{"TODO"}})
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	var got []string
+	for _, todo := range todos {
+		got = append(got, fmt.Sprintf("%s:%d %s", todo.Filename, todo.Line, todo.Comment))
+	}
+	if want := []string{"a.cs:2 // TODO: utf16", "b.cs:2 // TODO: b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect() = %q, expected %q", got, want)
+	}
+	if n := compares.Load(); n != 1 {
+		t.Errorf("merge base requested %d times, expected once", n)
+	}
+}
@@ -0,0 +1,434 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// localRepo reads files from a local git repository, such as the checkout a
+// run starts in, so that commits already present there need no API
+// requests. Objects are read by one long-running `git cat-file --batch`.
+// A nil *localRepo, or one outside a git repository, has no commits.
+type localRepo struct {
+	dir string
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	broken  bool
+	commits map[string]bool
+	// fetches holds the commits fetched or being fetched, each with a
+	// channel closed once its fetch is over.
+	fetches map[string]chan struct{}
+	// fetchMissing enables fetching missing PR heads into the repository.
+	fetchMissing bool
+}
+
+func newLocalRepo(dir string) *localRepo {
+	return &localRepo{dir: dir, commits: make(map[string]bool), fetches: make(map[string]chan struct{})}
+}
+
+// SetLocalRepo makes c read files at commits present in the git repository
+// containing dir before fetching them through the API. If fetch is set, the
+// head of a PR missing from the repository is fetched into it from a remote
+// of the PR's repository; otherwise the repository is only read. Close
+// releases the git process this starts.
+func (c *Client) SetLocalRepo(dir string, fetch bool) {
+	c.local = newLocalRepo(dir)
+	c.local.fetchMissing = fetch
+}
+
+// Close releases the resources of c.
+func (c *Client) Close() error {
+	return c.local.close()
+}
+
+func (l *localRepo) close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.broken = true
+	if l.cmd == nil {
+		return nil
+	}
+	l.stdin.Close()
+	err := l.cmd.Wait()
+	l.cmd = nil
+	return err
+}
+
+// start starts git cat-file, reporting whether it is running. A failure to
+// start it, such as outside a git repository, disables l. The caller must
+// hold l.mu.
+func (l *localRepo) start() bool {
+	if l.cmd != nil || l.broken {
+		return !l.broken
+	}
+	l.broken = true
+	if err := exec.Command("git", "-C", l.dir, "rev-parse", "--git-dir").Run(); err != nil {
+		return false
+	}
+	cmd := exec.Command("git", "-C", l.dir, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return false
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+	l.cmd, l.stdin, l.stdout, l.broken = cmd, stdin, bufio.NewReader(stdout), false
+	return true
+}
+
+// object returns the type and contents of the object named by name, or an
+// empty type if there is no such object. The caller must hold l.mu.
+func (l *localRepo) object(name string) (string, []byte, error) {
+	if !l.start() {
+		return "", nil, errors.New("no local repository")
+	}
+	typ, data, err := l.readObject(name)
+	if err != nil {
+		// The protocol is out of step; stop using it.
+		l.broken = true
+		l.stdin.Close()
+		_ = l.cmd.Wait()
+		l.cmd = nil
+	}
+	return typ, data, err
+}
+
+func (l *localRepo) readObject(name string) (string, []byte, error) {
+	if _, err := io.WriteString(l.stdin, name+"\n"); err != nil {
+		return "", nil, err
+	}
+	header, err := l.stdout.ReadString('\n')
+	if err != nil {
+		return "", nil, err
+	}
+	// "OID TYPE SIZE", or "NAME missing" and "NAME ambiguous", where NAME
+	// may contain spaces.
+	header = strings.TrimSuffix(header, "\n")
+	if strings.HasSuffix(header, " missing") || strings.HasSuffix(header, " ambiguous") {
+		return "", nil, nil
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return "", nil, fmt.Errorf("unexpected git cat-file output %q", header)
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", nil, fmt.Errorf("unexpected git cat-file output %q", header)
+	}
+	// The contents are followed by a newline.
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(l.stdout, data); err != nil {
+		return "", nil, err
+	}
+	return fields[1], data[:size], nil
+}
+
+// hasCommit reports whether the commit sha is present. The caller must
+// hold l.mu.
+func (l *localRepo) hasCommit(sha string) bool {
+	if has, ok := l.commits[sha]; ok {
+		return has
+	}
+	typ, _, err := l.object(sha)
+	has := err == nil && typ == "commit"
+	l.commits[sha] = has
+	return has
+}
+
+// has reports whether the commit ref is present.
+func (l *localRepo) has(ref string) bool {
+	if l == nil || !isCommitSHA(ref) {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.hasCommit(ref)
+}
+
+// commit returns the full SHA of the commit ref, which may be an
+// abbreviated SHA, if it is present.
+func (l *localRepo) commit(ref string) (string, bool) {
+	if l == nil || len(ref) < 4 || strings.Trim(ref, "0123456789abcdef") != "" {
+		return "", false
+	}
+	out, err := exec.Command("git", "-C", l.dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Output()
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(out)), true
+}
+
+// file returns the contents of path at the commit ref. ok is false if the
+// commit is not present or the file cannot be read, leaving it to the API;
+// otherwise found reports whether the commit has the file.
+func (l *localRepo) file(ref, path string) (data []byte, found, ok bool) {
+	if l == nil || !isCommitSHA(ref) || strings.ContainsAny(path, "\n") {
+		return nil, false, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.hasCommit(ref) {
+		return nil, false, false
+	}
+	typ, data, err := l.object(ref + ":" + path)
+	switch {
+	case err != nil:
+		return nil, false, false
+	case typ == "":
+		return nil, false, true
+	case typ != "blob":
+		// Directories and submodules are left to the API to describe.
+		return nil, false, false
+	}
+	return data, true, true
+}
+
+// fetchPR fetches the head of pr, the commit sha, from a remote of the
+// PR's repository unless it is already present or fetching is disabled.
+// Failures leave the head to the API.
+func (l *localRepo) fetchPR(ctx context.Context, pr prRef, sha string) {
+	l.fetch(ctx, pr.repo, pr.headRef(), sha)
+}
+
+// fetch fetches ref, whose commit is sha, from a remote of r unless the
+// commit is already present or fetching is disabled. git runs without
+// l.mu held, so that files of other commits can be read meanwhile; a
+// second fetch of the same commit waits for the first.
+func (l *localRepo) fetch(ctx context.Context, r repoRef, ref, sha string) {
+	if l == nil || !l.fetchMissing || !isCommitSHA(sha) {
+		return
+	}
+	l.mu.Lock()
+	if l.hasCommit(sha) {
+		l.mu.Unlock()
+		return
+	}
+	if done, ok := l.fetches[sha]; ok {
+		l.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		return
+	}
+	done := make(chan struct{})
+	l.fetches[sha] = done
+	l.mu.Unlock()
+	defer close(done)
+
+	remote := l.remoteFor(r)
+	if remote == "" {
+		return
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", l.dir, "fetch", "--quiet", "--no-tags", remote, ref)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if err := cmd.Run(); err != nil {
+		return
+	}
+	l.mu.Lock()
+	delete(l.commits, sha)
+	l.mu.Unlock()
+}
+
+// diff returns the diff of head since its merge base with base, if both
+// commits and their merge base are present.
+func (l *localRepo) diff(ctx context.Context, base, head string) (io.ReadCloser, bool) {
+	if !l.has(base) || !l.has(head) {
+		return nil, false
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", l.dir, "merge-base", base, head).Output()
+	if err != nil {
+		// Shallow clones may lack the history joining the two.
+		return nil, false
+	}
+	mergeBase := strings.TrimSpace(string(out))
+
+	cmd := exec.CommandContext(ctx, "git", "-C", l.dir, "-c", "core.quotePath=true",
+		"diff", "--no-color", "--no-ext-diff", "--no-textconv", "--no-relative", "--submodule=short",
+		"--find-renames", "--src-prefix=a/", "--dst-prefix=b/", mergeBase, head)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, false
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, false
+	}
+	return &cmdReader{cmd: cmd, stdout: stdout, stderr: &stderr}, true
+}
+
+// canBlame reports whether files at the commit head can be blamed: the
+// commit must be present with its history, since blame in a shallow clone
+// attributes every older line to the oldest commit fetched.
+func (l *localRepo) canBlame(ctx context.Context, head string) bool {
+	if !l.has(head) {
+		return false
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", l.dir, "rev-parse", "--is-shallow-repository").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "false"
+}
+
+// blame returns the blame of path at the commit head.
+func (l *localRepo) blame(ctx context.Context, head, path string) ([]BlameRange, bool) {
+	out, err := exec.CommandContext(ctx, "git", "-C", l.dir, "blame", "--porcelain", head, "--", path).Output()
+	if err != nil {
+		return nil, false
+	}
+	return parseBlame(out), true
+}
+
+// parseBlame parses the output of `git blame --porcelain` into ranges of
+// lines last changed by the same commit. The author and summary of a
+// commit are only given the first time it appears.
+func parseBlame(out []byte) []BlameRange {
+	commits := make(map[string]*types.Commit)
+	var ranges []BlameRange
+	var commit *types.Commit
+	line := 0
+	for _, text := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(text, "\t") {
+			if commit == nil {
+				continue
+			}
+			if n := len(ranges); n > 0 && ranges[n-1].End == line-1 && ranges[n-1].Commit.SHA == commit.SHA {
+				ranges[n-1].End = line
+			} else {
+				ranges = append(ranges, BlameRange{Start: line, End: line, Commit: types.Commit{SHA: commit.SHA}})
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(text, " ")
+		switch {
+		case isCommitSHA(key):
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				continue
+			}
+			line, _ = strconv.Atoi(fields[1])
+			if commit = commits[key]; commit == nil {
+				commit = &types.Commit{SHA: key}
+				commits[key] = commit
+			}
+		case commit == nil:
+		case key == "author":
+			commit.Author = value
+		case key == "summary":
+			commit.Subject = value
+		}
+	}
+	for i := range ranges {
+		ranges[i].Commit = *commits[ranges[i].Commit.SHA]
+	}
+	return ranges
+}
+
+// cmdReader reads the output of a command, failing at the end of it if the
+// command failed.
+type cmdReader struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *strings.Builder
+	done   bool
+}
+
+func (r *cmdReader) Read(p []byte) (int, error) {
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		if waitErr := r.wait(); waitErr != nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *cmdReader) wait() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	err := r.cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("git diff: %s", strings.TrimSpace(r.stderr.String()))
+	}
+	return err
+}
+
+func (r *cmdReader) Close() error {
+	if r.done {
+		return nil
+	}
+	_ = r.cmd.Process.Kill()
+	_ = r.wait()
+	return nil
+}
+
+// localNotFound returns the error of a file missing from a local commit,
+// matching the API's.
+func localNotFound(path, ref string) error {
+	return &APIError{Kind: ErrNotFound, StatusCode: http.StatusNotFound, Err: fmt.Errorf("%s not found at %s", path, ref)}
+}
+
+// remoteFor returns the name of a remote of r, or "".
+func (l *localRepo) remoteFor(r repoRef) string {
+	out, err := exec.Command("git", "-C", l.dir, "config", "--get-regexp", `^remote\..*\.url$`).Output()
+	if err != nil {
+		return ""
+	}
+	for line := range bytes.Lines(out) {
+		key, value, _ := strings.Cut(strings.TrimSpace(string(line)), " ")
+		if remoteMatches(value, r) {
+			return strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		}
+	}
+	return ""
+}
+
+// remoteMatches reports whether the remote URL s, in URL or scp-like
+// "USER@HOST:PATH" form, points at r.
+func remoteMatches(s string, r repoRef) bool {
+	var host, path string
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		var ok bool
+		host, path, ok = strings.Cut(s, ":")
+		if !ok {
+			return false
+		}
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	return strings.EqualFold(host, r.host) && strings.EqualFold(path, r.nameWithOwner())
+}
@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gitRepo creates a git repository in a new directory with one commit of
+// files, returning the directory and the commit.
+func gitRepo(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git(t, dir, "init", "--quiet")
+	git(t, dir, "add", ".")
+	git(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "test")
+	return dir, git(t, dir, "rev-parse", "HEAD")
+}
+
+// git runs git in dir and returns its trimmed output.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestHeadFileFetcherLocal(t *testing.T) {
+	dir, sha := gitRepo(t, map[string]string{"a.go": "package a\n", "my file.go": "package b\n"})
+	var mu sync.Mutex
+	var requests []string
+	c := newTestClient(t, prMetaHandler(t, sha, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+	}))
+	c.SetLocalRepo(dir, false)
+	defer c.Close()
+
+	f, err := c.HeadFileFetcher(context.Background(), "o/r", "1")
+	if err != nil {
+		t.Fatalf("HeadFileFetcher() error = %v", err)
+	}
+	for path, want := range map[string]string{"a.go": "package a\n", "my file.go": "package b\n"} {
+		f.Prefetch(path)
+		if got, err := f.Fetch(path); err != nil || string(got) != want {
+			t.Errorf("Fetch(%q) = %q, %v, expected %q", path, got, err, want)
+		}
+	}
+	if _, err := f.Fetch("missing.go"); err == nil {
+		t.Error("Fetch(missing.go) succeeded, expected an error")
+	}
+	if len(requests) != 0 {
+		t.Errorf("requests = %v, expected the local repository to serve every file", requests)
+	}
+}
+
+func TestLocalRepoFetchPR(t *testing.T) {
+	remote, sha := gitRepo(t, map[string]string{"a.go": "package a\n"})
+	git(t, remote, "update-ref", "refs/pull/7/head", sha)
+	dir, _ := gitRepo(t, nil)
+	git(t, dir, "remote", "add", "origin", "https://github.com/O/R.git")
+	git(t, dir, "config", "url."+remote+".insteadOf", "https://github.com/O/R.git")
+
+	l := newLocalRepo(dir)
+	defer l.close()
+	pr := prRef{repo: repoRef{host: "github.com", owner: "o", name: "r"}, number: 7}
+	l.fetchPR(context.Background(), pr, sha)
+	if l.has(sha) {
+		t.Fatal("has() = true after fetching was left disabled")
+	}
+	l.fetchMissing = true
+	l.fetchPR(context.Background(), pr, sha)
+	if data, found, ok := l.file(sha, "a.go"); !ok || !found || string(data) != "package a\n" {
+		t.Errorf("file(a.go) = %q, %v, %v after fetching, expected the file", data, found, ok)
+	}
+	if _, found, ok := l.file(sha, "missing.go"); !ok || found {
+		t.Errorf("file(missing.go) = %v, %v, expected the commit to lack it", found, ok)
+	}
+
+	outside := newLocalRepo(t.TempDir())
+	if _, _, ok := outside.file(sha, "a.go"); ok {
+		t.Error("file() outside a repository = ok, expected it to defer to the API")
+	}
+}
+
+func TestRemoteMatches(t *testing.T) {
+	r := repoRef{host: "github.com", owner: "octo", name: "repo"}
+	for url, want := range map[string]bool{
+		"https://github.com/octo/repo.git":      true,
+		"https://github.com/Octo/Repo":          true,
+		"https://user@github.com/octo/repo/":    true,
+		"git@github.com:octo/repo.git":          true,
+		"ssh://git@github.com:22/octo/repo.git": true,
+		"https://github.com/octo/repo-fork.git": false,
+		"git@ghe.example.com:octo/repo.git":     false,
+		"/home/me/src/repo":                     false,
+	} {
+		if got := remoteMatches(url, r); got != want {
+			t.Errorf("remoteMatches(%q) = %v, expected %v", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const orgReposQuery = `query($login: String!, $after: String) {
+  organization(login: $login) {
+    repositories(first: 100, after: $after, isArchived: false, orderBy: {field: NAME, direction: ASC}) {
+      nodes { nameWithOwner }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+// FetchOrgRepos returns the repositories of the organization org, given as
+// [HOST/]ORG, in [HOST/]OWNER/REPO form sorted by name. Archived
+// repositories, which take no new changes, are left out.
+func (c *Client) FetchOrgRepos(ctx context.Context, org string) ([]string, error) {
+	host, login := splitHostOrg(org)
+	queryHost := host
+	if queryHost == "" {
+		queryHost = c.defaultHost()
+	}
+
+	var repos []string
+	var after any
+	for {
+		var resp struct {
+			Organization *struct {
+				Repositories struct {
+					Nodes []struct {
+						NameWithOwner string `json:"nameWithOwner"`
+					} `json:"nodes"`
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+				} `json:"repositories"`
+			} `json:"organization"`
+		}
+		if err := c.query(ctx, queryHost, orgReposQuery, map[string]any{"login": login, "after": after}, &resp); err != nil {
+			return nil, fmt.Errorf("listing repositories of %s: %w", org, err)
+		}
+		if resp.Organization == nil {
+			return nil, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("organization %q not found", login)}
+		}
+		for _, n := range resp.Organization.Repositories.Nodes {
+			repos = append(repos, withHost(host, n.NameWithOwner))
+		}
+		if !resp.Organization.Repositories.PageInfo.HasNextPage {
+			return repos, nil
+		}
+		after = resp.Organization.Repositories.PageInfo.EndCursor
+	}
+}
+
+// splitHostOrg splits an [HOST/]ORG argument into its host, empty if not
+// given, and organization login.
+func splitHostOrg(org string) (string, string) {
+	if i := strings.LastIndex(org, "/"); i >= 0 {
+		return org[:i], org[i+1:]
+	}
+	return "", org
+}
@@ -0,0 +1,35 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFetchOrgRepos(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := decodeGraphQL(t, r)
+		switch {
+		case req.Variables["login"] != "acme":
+			writeJSON(w, http.StatusOK, `{"data":{"organization":null},"errors":[{"type":"NOT_FOUND","message":"Could not resolve to an Organization"}]}`)
+		case req.Variables["after"] == nil:
+			writeJSON(w, http.StatusOK, `{"data":{"organization":{"repositories":{"nodes":[{"nameWithOwner":"acme/api"}],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}}`)
+		default:
+			writeJSON(w, http.StatusOK, `{"data":{"organization":{"repositories":{"nodes":[{"nameWithOwner":"acme/web"}],"pageInfo":{"hasNextPage":false,"endCursor":"c2"}}}}}`)
+		}
+	}))
+
+	got, err := c.FetchOrgRepos(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("FetchOrgRepos() error = %v", err)
+	}
+	if want := []string{"acme/api", "acme/web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FetchOrgRepos() = %q, expected %q", got, want)
+	}
+
+	if _, err := c.FetchOrgRepos(context.Background(), "nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchOrgRepos() error = %v, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,156 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// maxSearchResults is the most results the GitHub search API returns for
+// a query.
+const maxSearchResults = 1000
+
+// searchConcurrency is the number of PRs CollectAll collects at once. Each
+// PR parses its files on several goroutines already, so a few PRs at a
+// time keep the API busy without tripping secondary rate limits.
+const searchConcurrency = 4
+
+const searchQuery = `query($q: String!, $after: String) {
+  search(query: $q, type: ISSUE, first: 100, after: $after) {
+    issueCount
+    nodes { ... on PullRequest { number title url baseRefName headRefName repository { nameWithOwner } } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+// SearchPRs returns the PRs matching a GitHub search query, such as
+// "is:open author:@me". Unless the query names repositories, owners, or
+// organizations with repo:, user:, or org:, it is limited to the
+// repository repo, which defaults to the current directory's.
+func (c *Client) SearchPRs(ctx context.Context, repo, query string) ([]types.PullRequest, error) {
+	q := query
+	if !hasQualifier(q, "is:pr", "type:pr") {
+		q = "is:pr " + q
+	}
+	var host string
+	switch r, err := c.resolveRepo(repo); {
+	case err == nil:
+		host = r.host
+		if !NamesRepositories(q) {
+			q += " repo:" + r.nameWithOwner()
+		}
+	case repo == "" && NamesRepositories(q):
+		host = c.defaultHost()
+	default:
+		return nil, err
+	}
+
+	var prs []types.PullRequest
+	var after any
+	for {
+		var resp struct {
+			Search struct {
+				IssueCount int `json:"issueCount"`
+				Nodes      []struct {
+					Number      int    `json:"number"`
+					Title       string `json:"title"`
+					URL         string `json:"url"`
+					BaseRefName string `json:"baseRefName"`
+					HeadRefName string `json:"headRefName"`
+					Repository  struct {
+						NameWithOwner string `json:"nameWithOwner"`
+					} `json:"repository"`
+				} `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"search"`
+		}
+		if err := c.query(ctx, host, searchQuery, map[string]any{"q": q, "after": after}, &resp); err != nil {
+			return nil, fmt.Errorf("searching for %q: %w", q, err)
+		}
+		for _, n := range resp.Search.Nodes {
+			// Nodes other than PRs decode as empty.
+			if n.Number == 0 {
+				continue
+			}
+			prs = append(prs, types.PullRequest{Repository: n.Repository.NameWithOwner, Number: n.Number, Title: n.Title, URL: n.URL, BaseRefName: n.BaseRefName, HeadRefName: n.HeadRefName})
+		}
+		if !resp.Search.PageInfo.HasNextPage {
+			if resp.Search.IssueCount > maxSearchResults {
+				fmt.Fprintf(os.Stderr, "Warning: %d PRs match %q, but GitHub search returns only the first %d\n", resp.Search.IssueCount, q, maxSearchResults)
+			}
+			return prs, nil
+		}
+		after = resp.Search.PageInfo.EndCursor
+	}
+}
+
+// NamesRepositories reports whether a search query picks its own
+// repositories, owners, or organizations with repo:, user:, or org:, so
+// that SearchPRs does not limit it to one repository.
+func NamesRepositories(query string) bool {
+	return hasQualifier(query, "repo:", "user:", "org:")
+}
+
+// hasQualifier reports whether the search query uses any of the
+// qualifiers, given with their colon, such as "repo:".
+func hasQualifier(query string, qualifiers ...string) bool {
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		term = strings.TrimPrefix(term, "-")
+		for _, q := range qualifiers {
+			if strings.HasPrefix(term, q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CollectAll collects the TODOs of each of prs, a few PRs at a time,
+// attributing each to its PR, in the order of prs. A PR that cannot be
+// collected is skipped with a warning, so that one PR does not spoil a
+// report across many; only if every PR fails is the first error returned.
+func CollectAll(ctx context.Context, fetcher PRFetcher, prs []types.PullRequest, opts CollectOptions) ([]types.TODO, error) {
+	layers := make([][]types.TODO, len(prs))
+	errs := make([]error, len(prs))
+	sem := make(chan struct{}, searchConcurrency)
+	var wg sync.WaitGroup
+	for i := range prs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			layers[i], errs[i] = Collect(ctx, fetcher, "", prs[i].URL, opts)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var todos []types.TODO
+	failed := 0
+	for i := range prs {
+		pr := &prs[i]
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s#%d: %v\n", pr.Repository, pr.Number, errs[i])
+			continue
+		}
+		for j := range layers[i] {
+			layers[i][j].PR = pr
+		}
+		todos = append(todos, layers[i]...)
+	}
+	if failed > 0 && failed == len(prs) {
+		return nil, errs[0]
+	}
+	return todos, nil
+}
@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestSearchPRs(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "limited to the repository", query: "is:open author:@me", want: "is:pr is:open author:@me repo:o/r"},
+		{name: "own repository", query: "is:open repo:x/y", want: "is:pr is:open repo:x/y"},
+		{name: "own organization", query: "type:pr org:acme review-requested:@me", want: "type:pr org:acme review-requested:@me"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := decodeGraphQL(t, r)
+				if q := req.Variables["q"]; q != tt.want {
+					t.Errorf("query = %q, expected %q", q, tt.want)
+				}
+				if req.Variables["after"] == nil {
+					writeJSON(w, http.StatusOK, `{"data":{"search":{"issueCount":3,"nodes":[`+
+						`{"number":1,"title":"One","url":"https://github.com/o/r/pull/1","baseRefName":"main","headRefName":"one","repository":{"nameWithOwner":"o/r"}},{}],`+
+						`"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}`)
+					return
+				}
+				writeJSON(w, http.StatusOK, `{"data":{"search":{"issueCount":3,"nodes":[`+
+					`{"number":7,"title":"Seven","url":"https://github.com/x/y/pull/7","baseRefName":"dev","headRefName":"seven","repository":{"nameWithOwner":"x/y"}}],`+
+					`"pageInfo":{"hasNextPage":false,"endCursor":"c2"}}}}`)
+			}))
+			got, err := c.SearchPRs(context.Background(), "o/r", tt.query)
+			if err != nil {
+				t.Fatalf("SearchPRs() error = %v", err)
+			}
+			want := []types.PullRequest{
+				{Repository: "o/r", Number: 1, Title: "One", URL: "https://github.com/o/r/pull/1", BaseRefName: "main", HeadRefName: "one"},
+				{Repository: "x/y", Number: 7, Title: "Seven", URL: "https://github.com/x/y/pull/7", BaseRefName: "dev", HeadRefName: "seven"},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("SearchPRs() = %+v, expected %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCollectAll(t *testing.T) {
+	prs := []types.PullRequest{
+		{Repository: "o/r", Number: 1, URL: "https://github.com/o/r/pull/1"},
+		{Repository: "o/r", Number: 2, URL: "https://github.com/o/r/pull/2"},
+		{Repository: "x/y", Number: 1, URL: "https://github.com/x/y/pull/1"},
+	}
+	diff := func(comment string) string {
+		return "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,2 @@\n package a\n+" + comment + "\n"
+	}
+	fetcher := prFetcher{prs[0].URL: diff("// TODO: first"), prs[2].URL: diff("// FIXME: other repo")}
+	opts := CollectOptions{Types: []string{"TODO", "FIXME"}}
+
+	var todos []types.TODO
+	var err error
+	stderr := captureStderr(t, func() {
+		todos, err = CollectAll(context.Background(), fetcher, prs, opts)
+	})
+	if err != nil {
+		t.Fatalf("CollectAll() error = %v", err)
+	}
+	var got []string
+	for _, todo := range todos {
+		got = append(got, fmt.Sprintf("%s#%d %s", todo.PR.Repository, todo.PR.Number, todo.Comment))
+	}
+	if want := []string{"o/r#1 // TODO: first", "x/y#1 // FIXME: other repo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectAll() = %q, expected %q", got, want)
+	}
+	if !strings.Contains(stderr, "Warning: skipping o/r#2") {
+		t.Errorf("stderr = %q, expected a warning about the failed PR", stderr)
+	}
+
+	captureStderr(t, func() {
+		_, err = CollectAll(context.Background(), prFetcher{}, prs, opts)
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("CollectAll() error = %v, expected the error of every PR failing", err)
+	}
+}
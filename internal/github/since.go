@@ -0,0 +1,171 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// RevisionComparer is implemented by fetchers that can compare the PR head
+// with an earlier commit, such as the head at the last review. Collect
+// uses it to keep only the TODOs added since CollectOptions.Since.
+type RevisionComparer interface {
+	// AddedSince returns the lines of each of paths at the PR head that
+	// were added or changed since the commit since.
+	AddedSince(ctx context.Context, repo, pr, since string, paths []string) (map[string]map[int]bool, error)
+}
+
+// filterSince returns the todos on lines added since the commit since:
+// the PR diff intersected with the diff between the two head revisions.
+// Comparing file contents rather than asking for a diff of the commits
+// keeps changes pulled in by rebasing the PR out, since only lines of the
+// PR diff can be reported.
+func filterSince(ctx context.Context, fetcher PRFetcher, repo, pr, since string, todos []types.TODO) ([]types.TODO, error) {
+	c, ok := fetcher.(RevisionComparer)
+	if !ok {
+		return nil, errors.New("comparing with an earlier commit is not supported")
+	}
+	if len(todos) == 0 {
+		return todos, nil
+	}
+	added, err := c.AddedSince(ctx, repo, pr, since, todoPaths(todos))
+	if err != nil {
+		return nil, err
+	}
+	var kept []types.TODO
+	for _, todo := range todos {
+		if added[todo.Filename][todo.Line] {
+			kept = append(kept, todo)
+		}
+	}
+	return kept, nil
+}
+
+// AddedSince returns the lines of each of paths at the PR head that were
+// added or changed since the commit since, which is usually an earlier
+// head of the PR. A file missing at since was added in full.
+func (c *Client) AddedSince(ctx context.Context, repo, pr, since string, paths []string) (map[string]map[int]bool, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := c.fetchPRMeta(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	head := meta.Repository.PullRequest
+	sha, err := c.resolveCommit(ctx, ref.repo, since)
+	if err != nil {
+		return nil, err
+	}
+	c.local.fetchPR(ctx, ref, head.HeadRefOid)
+
+	added := make(map[string]map[int]bool, len(paths))
+	for _, path := range paths {
+		old, err := c.fetchCachedFile(ctx, ref.repo, path, sha)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("fetching %s at %s: %w", path, since, err)
+		}
+		current, err := c.fetchCachedFile(ctx, ref.repo, path, head.HeadRefOid)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s at the PR head: %w", path, err)
+		}
+		lines := make(map[int]bool)
+		for _, h := range diff.Compare(old, current, 0) {
+			for _, l := range h.Lines {
+				if l.Kind == diff.Added {
+					lines[l.NewLine] = true
+				}
+			}
+		}
+		added[path] = lines
+	}
+	return added, nil
+}
+
+// resolveCommit returns the full SHA of the commit ref in r, which may be
+// an abbreviated SHA. Commits present in the local repository are resolved
+// there rather than looked up.
+func (c *Client) resolveCommit(ctx context.Context, r repoRef, ref string) (string, error) {
+	if sha, ok := c.local.commit(ref); ok {
+		return sha, nil
+	}
+	data, err := c.getBytes(ctx, r.host, fmt.Sprintf("repos/%s/commits/%s", r.nameWithOwner(), ref), jsonMediaType)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusUnprocessableEntity) {
+		return "", &APIError{Kind: ErrNotFound, StatusCode: apiErr.StatusCode, Err: fmt.Errorf("commit %q not found in %s", ref, r.nameWithOwner())}
+	}
+	if err != nil {
+		return "", fmt.Errorf("looking up commit %q: %w", ref, err)
+	}
+	var resp struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.SHA == "" {
+		return "", fmt.Errorf("looking up commit %q: unexpected response", ref)
+	}
+	return resp.SHA, nil
+}
+
+const lastReviewQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  viewer { login }
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviews(last: 100, states: [APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED]) {
+        nodes { author { login } commit { oid } }
+      }
+    }
+  }
+}`
+
+// FetchLastReviewCommit returns the head commit of the PR that the
+// authenticated user last reviewed.
+func (c *Client) FetchLastReviewCommit(ctx context.Context, repo, pr string) (string, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
+	if err != nil {
+		return "", err
+	}
+	if ref.number == 0 {
+		return "", &APIError{Kind: ErrNotFound, Err: fmt.Errorf("no open pull requests found for branch %q to find a review of", ref.head)}
+	}
+
+	var resp struct {
+		Viewer struct {
+			Login string `json:"login"`
+		} `json:"viewer"`
+		Repository struct {
+			PullRequest *struct {
+				Reviews struct {
+					Nodes []struct {
+						Author *struct {
+							Login string `json:"login"`
+						} `json:"author"`
+						Commit *struct {
+							Oid string `json:"oid"`
+						} `json:"commit"`
+					} `json:"nodes"`
+				} `json:"reviews"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	vars := map[string]any{"owner": ref.repo.owner, "name": ref.repo.name, "number": ref.number}
+	if err := c.query(ctx, ref.repo.host, lastReviewQuery, vars, &resp); err != nil {
+		return "", err
+	}
+	if resp.Repository.PullRequest == nil {
+		return "", &APIError{Kind: ErrNotFound, Err: fmt.Errorf("pull request %s#%d not found", ref.repo.nameWithOwner(), ref.number)}
+	}
+	reviews := resp.Repository.PullRequest.Reviews.Nodes
+	for i := len(reviews) - 1; i >= 0; i-- {
+		review := reviews[i]
+		if review.Author != nil && review.Author.Login == resp.Viewer.Login && review.Commit != nil {
+			return review.Commit.Oid, nil
+		}
+	}
+	return "", &APIError{Kind: ErrNotFound, Err: fmt.Errorf("%s has not reviewed %s#%d", resp.Viewer.Login, ref.repo.nameWithOwner(), ref.number)}
+}
@@ -0,0 +1,138 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollectSince(t *testing.T) {
+	const sinceSHA = "89abcdef0123456789abcdef0123456789abcdef"
+	contents := map[string]string{
+		"a.go@" + sinceSHA: "package a\n\n// TODO: old\n",
+		"a.go@" + testSHA:  "package a\n\n// TODO: old\n// TODO: new\n",
+		"b.go@" + testSHA:  "// FIXME: added\n",
+	}
+	handler := prMetaHandler(t, testSHA, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/o/r/pulls/1":
+			_, _ = io.WriteString(w, "diff --git a/a.go b/a.go\nnew file mode 100644\n--- /dev/null\n+++ b/a.go\n@@ -0,0 +1,4 @@\n+package a\n+\n+// TODO: old\n+// TODO: new\n"+
+				"diff --git a/b.go b/b.go\nnew file mode 100644\n--- /dev/null\n+++ b/b.go\n@@ -0,0 +1 @@\n+// FIXME: added\n")
+		case r.URL.Path == "/repos/o/r/commits/89abcde":
+			writeJSON(w, http.StatusOK, `{"sha":"`+sinceSHA+`"}`)
+		case strings.HasPrefix(r.URL.Path, "/repos/o/r/commits/"):
+			writeJSON(w, http.StatusUnprocessableEntity, `{"message":"No commit found for SHA"}`)
+		case strings.HasPrefix(r.URL.Path, "/repos/o/r/contents/"):
+			data, ok := contents[strings.TrimPrefix(r.URL.Path, "/repos/o/r/contents/")+"@"+r.URL.Query().Get("ref")]
+			if !ok {
+				writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+				return
+			}
+			_, _ = io.WriteString(w, data)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+	opts := CollectOptions{Types: []string{"TODO", "FIXME"}}
+
+	t.Run("earlier head", func(t *testing.T) {
+		c := newTestClient(t, handler)
+		opts := opts
+		opts.Since = "89abcde"
+		todos, err := Collect(context.Background(), c, "o/r", "1", opts)
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		var got []string
+		for _, todo := range todos {
+			got = append(got, fmt.Sprintf("%s:%d %s", todo.Filename, todo.Line, todo.Comment))
+		}
+		want := []string{"a.go:4 // TODO: new", "b.go:1 // FIXME: added"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Collect() = %q, expected %q", got, want)
+		}
+	})
+
+	t.Run("unknown commit", func(t *testing.T) {
+		c := newTestClient(t, handler)
+		opts := opts
+		opts.Since = "fedcba9"
+		_, err := Collect(context.Background(), c, "o/r", "1", opts)
+		if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), `commit "fedcba9" not found`) {
+			t.Errorf("Collect() error = %v, expected the commit to be missing", err)
+		}
+	})
+
+	t.Run("fetcher without comparison", func(t *testing.T) {
+		fetcher := &stubFetcher{diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,2 @@\n package a\n+// TODO: x\n"}
+		opts := opts
+		opts.Since = "89abcde"
+		if _, err := Collect(context.Background(), fetcher, "o/r", "1", opts); err == nil {
+			t.Error("Collect() succeeded, expected an error")
+		}
+	})
+}
+
+func TestResolveCommitLocal(t *testing.T) {
+	dir, sha := gitRepo(t, map[string]string{"a.go": "package a\n"})
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+		http.NotFound(w, r)
+	}))
+	c.SetLocalRepo(dir, false)
+	defer c.Close()
+
+	got, err := c.resolveCommit(context.Background(), repoRef{host: "github.com", owner: "o", name: "r"}, sha[:7])
+	if err != nil {
+		t.Fatalf("resolveCommit() error = %v", err)
+	}
+	if got != sha {
+		t.Errorf("resolveCommit() = %q, expected %q", got, sha)
+	}
+}
+
+func TestFetchLastReviewCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		reviews string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "latest review by the viewer",
+			reviews: `{"author":{"login":"me"},"commit":{"oid":"aaa"}},{"author":{"login":"me"},"commit":{"oid":"bbb"}},{"author":{"login":"other"},"commit":{"oid":"ccc"}},{"author":null,"commit":{"oid":"ddd"}}`,
+			want:    "bbb",
+		},
+		{
+			name:    "no review by the viewer",
+			reviews: `{"author":{"login":"other"},"commit":{"oid":"ccc"}}`,
+			wantErr: "me has not reviewed o/r#1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if number := decodeGraphQL(t, r).Variables["number"]; number != float64(1) {
+					t.Errorf("number = %v, expected 1", number)
+				}
+				writeJSON(w, http.StatusOK, `{"data":{"viewer":{"login":"me"},"repository":{"pullRequest":{"reviews":{"nodes":[`+tt.reviews+`]}}}}}`)
+			}))
+			got, err := c.FetchLastReviewCommit(context.Background(), "o/r", "1")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, ErrNotFound) {
+					t.Fatalf("FetchLastReviewCommit() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("FetchLastReviewCommit() = %q, %v, expected %q", got, err, tt.want)
+			}
+		})
+	}
+}
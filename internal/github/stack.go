@@ -0,0 +1,154 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// maxStackDepth bounds the PRs followed in each direction from the selected
+// PR, in case branches are stacked in a cycle.
+const maxStackDepth = 50
+
+const stackPRQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) { number title url baseRefName headRefName isCrossRepository }
+  }
+}`
+
+const stackNeighborsQuery = `query($owner: String!, $name: String!, $head: String, $base: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(headRefName: $head, baseRefName: $base, states: OPEN, first: 30, orderBy: {field: CREATED_AT, direction: ASC}) {
+      nodes { number title url baseRefName headRefName isCrossRepository }
+    }
+  }
+}`
+
+// stackNode is a PR returned by stackPRQuery and stackNeighborsQuery.
+type stackNode struct {
+	Number            int    `json:"number"`
+	Title             string `json:"title"`
+	URL               string `json:"url"`
+	BaseRefName       string `json:"baseRefName"`
+	HeadRefName       string `json:"headRefName"`
+	IsCrossRepository bool   `json:"isCrossRepository"`
+}
+
+func (n stackNode) pullRequest() types.PullRequest {
+	return types.PullRequest{Number: n.Number, Title: n.Title, URL: n.URL, BaseRefName: n.BaseRefName, HeadRefName: n.HeadRefName}
+}
+
+// FetchStack returns the stack of PRs the selected PR belongs to, from the
+// bottom up. Below a PR is the open PR whose head branch is its base
+// branch, and above it the open PR based on its head branch. A PR with
+// several PRs based on it ends the stack, since the PRs above it no longer
+// form a chain.
+func (c *Client) FetchStack(ctx context.Context, repo, pr string) ([]types.PullRequest, error) {
+	ref, err := c.resolvePR(ctx, repo, pr)
+	if err != nil {
+		return nil, err
+	}
+	if ref.number == 0 {
+		return nil, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("no open pull requests found for branch %q to find a stack from", ref.head)}
+	}
+
+	var resp struct {
+		Repository struct {
+			PullRequest *stackNode `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	vars := map[string]any{"owner": ref.repo.owner, "name": ref.repo.name, "number": ref.number}
+	if err := c.query(ctx, ref.repo.host, stackPRQuery, vars, &resp); err != nil {
+		return nil, err
+	}
+	selected := resp.Repository.PullRequest
+	if selected == nil {
+		return nil, &APIError{Kind: ErrNotFound, Err: fmt.Errorf("pull request %s#%d not found", ref.repo.nameWithOwner(), ref.number)}
+	}
+
+	seen := map[int]bool{selected.Number: true}
+	var below []types.PullRequest
+	for n := *selected; len(below) < maxStackDepth; {
+		parents, err := c.stackNeighbors(ctx, ref.repo, n.BaseRefName, "")
+		if err != nil {
+			return nil, err
+		}
+		// A PR from a fork has no head branch in the repository to stack on.
+		i := slices.IndexFunc(parents, func(p stackNode) bool { return !p.IsCrossRepository && !seen[p.Number] })
+		if i < 0 {
+			break
+		}
+		n = parents[i]
+		seen[n.Number] = true
+		below = append(below, n.pullRequest())
+	}
+
+	stack := append(below, selected.pullRequest())
+	slices.Reverse(stack[:len(below)])
+	for n := *selected; !n.IsCrossRepository && len(stack) < len(below)+1+maxStackDepth; {
+		children, err := c.stackNeighbors(ctx, ref.repo, "", n.HeadRefName)
+		if err != nil {
+			return nil, err
+		}
+		if len(children) > 1 {
+			numbers := make([]string, len(children))
+			for i, child := range children {
+				numbers[i] = fmt.Sprintf("#%d", child.Number)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: several PRs are based on #%d (%s); the stack ends there\n", n.Number, strings.Join(numbers, ", "))
+		}
+		if len(children) != 1 || seen[children[0].Number] {
+			break
+		}
+		n = children[0]
+		seen[n.Number] = true
+		stack = append(stack, n.pullRequest())
+	}
+	return stack, nil
+}
+
+// stackNeighbors returns the open PRs of r whose head branch is head, or
+// whose base branch is base, oldest first.
+func (c *Client) stackNeighbors(ctx context.Context, r repoRef, head, base string) ([]stackNode, error) {
+	var resp struct {
+		Repository struct {
+			PullRequests struct {
+				Nodes []stackNode `json:"nodes"`
+			} `json:"pullRequests"`
+		} `json:"repository"`
+	}
+	vars := map[string]any{"owner": r.owner, "name": r.name, "head": nil, "base": nil}
+	if head != "" {
+		vars["head"] = head
+	}
+	if base != "" {
+		vars["base"] = base
+	}
+	if err := c.query(ctx, r.host, stackNeighborsQuery, vars, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Repository.PullRequests.Nodes, nil
+}
+
+// CollectStack collects the TODOs of each PR of stack, attributing each to
+// its PR. Every PR's diff is relative to the PR below it, so a TODO is
+// attributed to the PR that introduced it.
+func CollectStack(ctx context.Context, fetcher PRFetcher, stack []types.PullRequest, opts CollectOptions) ([]types.TODO, error) {
+	var todos []types.TODO
+	for i := range stack {
+		pr := &stack[i]
+		layer, err := Collect(ctx, fetcher, "", pr.URL, opts)
+		if err != nil {
+			return nil, fmt.Errorf("#%d: %w", pr.Number, err)
+		}
+		for j := range layer {
+			layer[j].PR = pr
+		}
+		todos = append(todos, layer...)
+	}
+	return todos, nil
+}
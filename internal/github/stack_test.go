@@ -0,0 +1,136 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// stackHandler serves the open PRs nodes of o/r to the stack queries.
+func stackHandler(t *testing.T, nodes []stackNode) http.Handler {
+	t.Helper()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := decodeGraphQL(t, r)
+		var data any
+		if number, ok := req.Variables["number"].(float64); ok {
+			var found *stackNode
+			for i := range nodes {
+				if nodes[i].Number == int(number) {
+					found = &nodes[i]
+				}
+			}
+			data = map[string]any{"repository": map[string]any{"pullRequest": found}}
+		} else {
+			head, _ := req.Variables["head"].(string)
+			base, _ := req.Variables["base"].(string)
+			matches := []stackNode{}
+			for _, n := range nodes {
+				if (head == "" || n.HeadRefName == head) && (base == "" || n.BaseRefName == base) {
+					matches = append(matches, n)
+				}
+			}
+			data = map[string]any{"repository": map[string]any{"pullRequests": map[string]any{"nodes": matches}}}
+		}
+		body, _ := json.Marshal(map[string]any{"data": data})
+		writeJSON(w, http.StatusOK, string(body))
+	})
+}
+
+func TestFetchStack(t *testing.T) {
+	pr := func(n int, base, head string) stackNode {
+		return stackNode{Number: n, Title: fmt.Sprintf("PR %d", n), URL: fmt.Sprintf("https://github.com/o/r/pull/%d", n), BaseRefName: base, HeadRefName: head}
+	}
+	fork := pr(6, "main", "a")
+	fork.IsCrossRepository = true
+	nodes := []stackNode{
+		fork,
+		pr(1, "main", "a"),
+		pr(2, "a", "b"),
+		pr(3, "b", "c"),
+		pr(4, "c", "d1"),
+		pr(5, "c", "d2"),
+		pr(7, "main", "alone"),
+		pr(8, "x", "y"),
+		pr(9, "y", "x"),
+	}
+	tests := []struct {
+		name        string
+		pr          string
+		want        []int
+		wantWarning string
+	}{
+		{name: "middle of a stack", pr: "2", want: []int{1, 2, 3}, wantWarning: "several PRs are based on #3 (#4, #5)"},
+		{name: "bottom of a stack", pr: "1", want: []int{1, 2, 3}},
+		{name: "top of a branching stack", pr: "4", want: []int{1, 2, 3, 4}},
+		{name: "single PR", pr: "7", want: []int{7}},
+		{name: "cycle", pr: "8", want: []int{9, 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, stackHandler(t, nodes))
+			var stack []types.PullRequest
+			var err error
+			stderr := captureStderr(t, func() {
+				stack, err = c.FetchStack(context.Background(), "o/r", tt.pr)
+			})
+			if err != nil {
+				t.Fatalf("FetchStack() error = %v", err)
+			}
+			var got []int
+			for _, pr := range stack {
+				got = append(got, pr.Number)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FetchStack() = %v, expected %v", got, tt.want)
+			}
+			if !strings.Contains(stderr, tt.wantWarning) {
+				t.Errorf("stderr = %q, expected %q", stderr, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// prFetcher serves the diff of each PR by PR argument, failing for other
+// PRs.
+type prFetcher map[string]string
+
+func (f prFetcher) FetchDiff(_ context.Context, repo, pr string) (string, error) {
+	diff, ok := f[pr]
+	if !ok {
+		return "", &APIError{Kind: ErrNotFound, Err: fmt.Errorf("%s not found", pr)}
+	}
+	return diff, nil
+}
+
+func (f prFetcher) FetchChangedFileContents(context.Context, string, string, string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func TestCollectStack(t *testing.T) {
+	stack := []types.PullRequest{
+		{Number: 1, URL: "https://github.com/o/r/pull/1"},
+		{Number: 2, URL: "https://github.com/o/r/pull/2"},
+	}
+	fetcher := prFetcher{
+		stack[0].URL: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,2 @@\n package a\n+// TODO: first\n",
+		stack[1].URL: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -2 +2,2 @@\n // TODO: first\n+// FIXME: second\n",
+	}
+	todos, err := CollectStack(context.Background(), fetcher, stack, CollectOptions{Types: []string{"TODO", "FIXME"}})
+	if err != nil {
+		t.Fatalf("CollectStack() error = %v", err)
+	}
+	var got []string
+	for _, todo := range todos {
+		got = append(got, fmt.Sprintf("#%d %s:%d %s", todo.PR.Number, todo.Filename, todo.Line, todo.Comment))
+	}
+	want := []string{"#1 a.go:2 // TODO: first", "#2 a.go:3 // FIXME: second"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectStack() = %q, expected %q", got, want)
+	}
+}
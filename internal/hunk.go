@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"github.com/Suree33/gh-pr-todo/internal/charset"
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// hunkRegions returns the new side of each hunk in a file's diff, context
+// and added lines, as regions. Each line is mapped to its line in the new
+// file and converted to UTF-8. The encoding is detected once from all of
+// the file's hunk text, since a line alone is often too short to tell
+// Shift_JIS from Windows-1252, and lines of a file must decode alike.
+func hunkRegions(f diff.File, encodings []charset.Mapping) []sourceRegion {
+	var text []byte
+	for _, h := range f.Hunks {
+		for _, line := range h.Lines {
+			if line.Kind != diff.Deleted {
+				text = append(text, line.Text...)
+				text = append(text, '\n')
+			}
+		}
+	}
+	// Only the first line of the hunk text can start with a byte order
+	// mark.
+	enc, bom := charset.Detect(f.NewPath, text, encodings)
+
+	var regions []sourceRegion
+	for _, h := range f.Hunks {
+		r := sourceRegion{filename: f.NewPath}
+		for _, line := range h.Lines {
+			if line.Kind == diff.Deleted {
+				continue
+			}
+			r.lines = append(r.lines, string(charset.Decode(enc, []byte(line.Text)[bom:])))
+			r.fileLines = append(r.fileLines, line.NewLine)
+			bom = 0
+		}
+		if len(r.lines) > 0 {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
+
+// needsFileContents reports whether TODOs in filename can only be found with
+// the whole file, because its structure (notebook cells, code fences,
+// template comments, or embedded blocks) cannot be recovered from a hunk.
+func needsFileContents(filename string) bool {
+	if _, _, ok := templateSyntax(filename); ok {
+		return true
+	}
+	return isNotebook(filename) || isDocumentation(filename) || isEmbeddedDocument(filename)
+}
+
+// parseHunkTODOs extracts TODO comments from a file whose contents are
+// unavailable by parsing the text of each hunk on its own. Tree-sitter
+// tolerates the partial source, producing error nodes where a hunk cuts
+// through a construct, so comments are still told apart from code and
+// strings.
+func parseHunkTODOs(fc fileChange, regions []sourceRegion, p markerPatterns) []types.TODO {
+	var todos []types.TODO
+	for _, r := range regions {
+		todos = append(todos, parseRegionTODOs(fc, r, p)...)
+	}
+	return todos
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestParseDiffWithContentsMissingFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     string
+		expected []types.TODO
+	}{
+		{
+			name: "Lines of each hunk map to the new file",
+			diff: `diff --git a/main.go b/main.go
+index 1234567..abcdefg 100644
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++// NOTE: first hunk
+ 
+@@ -40,3 +41,4 @@ func helper() {
+ 	x := 1
+-	y := 2
++	// FIXME: second hunk
++	y := 3
+ 	return`,
+			expected: []types.TODO{
+				{Filename: "main.go", Line: 2, Comment: "// NOTE: first hunk", Type: "NOTE"},
+				{Filename: "main.go", Line: 42, Comment: "// FIXME: second hunk", Type: "FIXME"},
+			},
+		},
+		{
+			name: "Markers in string literals are ignored",
+			diff: `diff --git a/main.go b/main.go
+index 1234567..abcdefg 100644
+--- a/main.go
++++ b/main.go
+@@ -9,4 +9,6 @@
+ func main() {
+ 	fmt.Println("start")
++	fmt.Println("// TODO: not a comment")
++	// TODO: real comment
+ }`,
+			expected: []types.TODO{
+				{Filename: "main.go", Line: 12, Comment: "// TODO: real comment", Type: "TODO", Symbol: "main"},
+			},
+		},
+		{
+			name: "Markdown falls back to line matching",
+			diff: `diff --git a/README.md b/README.md
+index 1234567..abcdefg 100644
+--- a/README.md
++++ b/README.md
+@@ -1,2 +1,3 @@
+ # Title
++<!-- TODO: write intro -->
+ Text`,
+			expected: []types.TODO{
+				{Filename: "README.md", Line: 2, Comment: "<!-- TODO: write intro -->", Type: "TODO"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseDiffWithContents(tt.diff, map[string][]byte{})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithContents() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHunkRegionsEncoding(t *testing.T) {
+	// "。" alone (0x81 0x42) decodes as Windows-1252 as well as Shift_JIS;
+	// the Japanese text of the other line settles it for the whole file.
+	files := diff.Parse("diff --git a/a.py b/a.py\n--- a/a.py\n+++ b/a.py\n@@ -1 +1,3 @@\n x = 1\n+# \x93\xfa\x96\x7b\x8c\xea\n+# TODO: fix\x81\x42\n")
+	regions := hunkRegions(files[0], nil)
+	var got []string
+	for _, r := range regions {
+		got = append(got, r.lines...)
+	}
+	if want := []string{"x = 1", "# 日本語", "# TODO: fix。"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hunkRegions() lines = %q, expected %q", got, want)
+	}
+}
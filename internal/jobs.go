@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// taskPool runs tasks on a fixed number of goroutines and collects their
+// results in the order the tasks were submitted.
+type taskPool struct {
+	next chan func()
+	wg   sync.WaitGroup
+
+	mu      sync.Mutex
+	results [][]types.TODO
+}
+
+// newTaskPool starts a pool of jobs goroutines. Zero or fewer jobs uses
+// runtime.GOMAXPROCS.
+func newTaskPool(jobs int) *taskPool {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	p := &taskPool{next: make(chan func())}
+	for range jobs {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for run := range p.next {
+				run()
+			}
+		}()
+	}
+	return p
+}
+
+// submit queues task, blocking until a goroutine is free to run it.
+func (p *taskPool) submit(task func() []types.TODO) {
+	p.mu.Lock()
+	i := len(p.results)
+	p.results = append(p.results, nil)
+	p.mu.Unlock()
+
+	p.next <- func() {
+		found := task()
+		p.mu.Lock()
+		p.results[i] = found
+		p.mu.Unlock()
+	}
+}
+
+// wait stops the pool once the submitted tasks are done and returns their
+// results.
+func (p *taskPool) wait() [][]types.TODO {
+	close(p.next)
+	p.wg.Wait()
+	return p.results
+}
@@ -0,0 +1,249 @@
+// Package language describes the programming languages whose TODO comments
+// gh-pr-todo can find, and identifies the language of a file from its
+// content or from configured path patterns.
+package language
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Language is a language with its canonical name, the file extension that
+// selects its grammar, other names it is known by, and its comment syntax.
+type Language struct {
+	Name      string
+	Extension string
+	Aliases   []string
+	Comments  []string
+}
+
+var (
+	cComments   = []string{"//", "/* */"}
+	hashComment = []string{"#"}
+	markup      = []string{"<!-- -->"}
+)
+
+// languages lists every known language, sorted by name.
+var languages = []Language{
+	{Name: "astro", Extension: ".astro", Comments: []string{"<!-- -->", "{/* */}"}},
+	{Name: "c", Extension: ".c", Comments: cComments},
+	{Name: "cpp", Extension: ".cpp", Aliases: []string{"c++"}, Comments: cComments},
+	{Name: "csharp", Extension: ".cs", Aliases: []string{"c#", "cs"}, Comments: cComments},
+	{Name: "css", Extension: ".css", Aliases: []string{"pcss", "postcss"}, Comments: []string{"/* */"}},
+	{Name: "dart", Extension: ".dart", Comments: cComments},
+	{Name: "elixir", Extension: ".ex", Aliases: []string{"ex", "exs"}, Comments: hashComment},
+	{Name: "erlang", Extension: ".erl", Aliases: []string{"erl"}, Comments: []string{"%"}},
+	{Name: "go", Extension: ".go", Aliases: []string{"golang"}, Comments: cComments},
+	{Name: "graphql", Extension: ".graphql", Aliases: []string{"gql"}, Comments: hashComment},
+	{Name: "groovy", Extension: ".groovy", Aliases: []string{"gradle"}, Comments: cComments},
+	{Name: "haskell", Extension: ".hs", Aliases: []string{"hs"}, Comments: []string{"--", "{- -}"}},
+	{Name: "hcl", Extension: ".hcl", Comments: []string{"#", "//", "/* */"}},
+	{Name: "html", Extension: ".html", Aliases: []string{"htm", "xhtml"}, Comments: markup},
+	{Name: "java", Extension: ".java", Comments: cComments},
+	{Name: "javascript", Extension: ".js", Aliases: []string{"bun", "deno", "js", "node", "nodejs"}, Comments: cComments},
+	{Name: "json", Extension: ".json"},
+	{Name: "jsx", Extension: ".jsx", Comments: []string{"//", "/* */", "{/* */}"}},
+	{Name: "julia", Extension: ".jl", Aliases: []string{"jl"}, Comments: []string{"#", "#= =#"}},
+	{Name: "kotlin", Extension: ".kt", Aliases: []string{"kt"}, Comments: cComments},
+	{Name: "less", Extension: ".less", Comments: cComments},
+	{Name: "lua", Extension: ".lua", Comments: []string{"--", "--[[ ]]"}},
+	{Name: "objc", Extension: ".m", Aliases: []string{"objective-c"}, Comments: cComments},
+	{Name: "ocaml", Extension: ".ml", Comments: []string{"(* *)"}},
+	{Name: "perl", Extension: ".pl", Comments: hashComment},
+	{Name: "php", Extension: ".php", Comments: []string{"//", "#", "/* */"}},
+	{Name: "powershell", Extension: ".ps1", Aliases: []string{"ps1", "pwsh"}, Comments: []string{"#", "<# #>"}},
+	{Name: "protobuf", Extension: ".proto", Aliases: []string{"proto"}, Comments: cComments},
+	{Name: "python", Extension: ".py", Aliases: []string{"ipython", "ipython3", "py", "python3"}, Comments: hashComment},
+	{Name: "r", Extension: ".r", Aliases: []string{"rscript"}, Comments: hashComment},
+	{Name: "ruby", Extension: ".rb", Aliases: []string{"rb"}, Comments: []string{"#", "=begin =end"}},
+	{Name: "rust", Extension: ".rs", Aliases: []string{"rs"}, Comments: cComments},
+	{Name: "scala", Extension: ".scala", Comments: cComments},
+	{Name: "scss", Extension: ".scss", Aliases: []string{"sass"}, Comments: cComments},
+	{Name: "shell", Extension: ".sh", Aliases: []string{"ash", "bash", "dash", "ksh", "sh", "zsh"}, Comments: hashComment},
+	{Name: "sql", Extension: ".sql", Comments: []string{"--", "/* */"}},
+	{Name: "svelte", Extension: ".svelte", Comments: markup},
+	{Name: "swift", Extension: ".swift", Comments: cComments},
+	{Name: "terraform", Extension: ".tf", Aliases: []string{"tf"}, Comments: []string{"#", "//", "/* */"}},
+	{Name: "toml", Extension: ".toml", Comments: hashComment},
+	{Name: "tsx", Extension: ".tsx", Comments: []string{"//", "/* */", "{/* */}"}},
+	{Name: "typescript", Extension: ".ts", Aliases: []string{"ts", "ts-node"}, Comments: cComments},
+	{Name: "vue", Extension: ".vue", Comments: markup},
+	{Name: "xml", Extension: ".xml", Aliases: []string{"svg"}, Comments: markup},
+	{Name: "yaml", Extension: ".yaml", Aliases: []string{"yml"}, Comments: hashComment},
+	{Name: "zig", Extension: ".zig", Comments: []string{"//"}},
+}
+
+// byName indexes languages by lowercase name and alias.
+var byName = func() map[string]Language {
+	m := make(map[string]Language)
+	for _, l := range languages {
+		m[l.Name] = l
+		for _, alias := range l.Aliases {
+			m[alias] = l
+		}
+	}
+	return m
+}()
+
+// All returns every known language, sorted by name.
+func All() []Language {
+	return append([]Language(nil), languages...)
+}
+
+// Lookup returns the language with the given name or alias, ignoring case
+// and surrounding whitespace.
+func Lookup(name string) (Language, bool) {
+	l, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+	return l, ok
+}
+
+// ByExtension returns the language whose grammar is selected by ext, such as
+// ".py".
+func ByExtension(ext string) (Language, bool) {
+	ext = strings.ToLower(ext)
+	for _, l := range languages {
+		if l.Extension == ext {
+			return l, true
+		}
+	}
+	return Language{}, false
+}
+
+// CommentLeaders returns the text that starts each of the language's
+// comments, such as "--" for Lua's "--" and "{-" for Haskell's "{- -}".
+func (l Language) CommentLeaders() []string {
+	leaders := make([]string, 0, len(l.Comments))
+	for _, c := range l.Comments {
+		if fields := strings.Fields(c); len(fields) > 0 {
+			leaders = append(leaders, fields[0])
+		}
+	}
+	return leaders
+}
+
+// Mapping assigns a language to the files whose path matches Pattern, a glob
+// in path.Match syntax. A pattern without a slash matches the file name in
+// any directory.
+type Mapping struct {
+	Pattern  string
+	Language string
+}
+
+// Matches reports whether filename matches the mapping's pattern.
+func (m Mapping) Matches(filename string) bool {
+	target := filename
+	if !strings.Contains(m.Pattern, "/") {
+		target = path.Base(filename)
+	}
+	ok, err := path.Match(m.Pattern, target)
+	return err == nil && ok
+}
+
+// Match returns the language of the first mapping whose pattern matches
+// filename.
+func Match(mappings []Mapping, filename string) (Language, bool) {
+	for _, m := range mappings {
+		if m.Matches(filename) {
+			return Lookup(m.Language)
+		}
+	}
+	return Language{}, false
+}
+
+var (
+	// versionSuffixRegex matches the version at the end of an interpreter
+	// name, such as "3.11" in "python3.11".
+	versionSuffixRegex = regexp.MustCompile(`[\d.]+$`)
+	// vimModelineRegex matches a Vim modeline and captures its settings.
+	vimModelineRegex = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex):\s*(?:set?\s+)?(.*)`)
+	// vimFiletypeRegex captures the filetype or syntax setting of a Vim
+	// modeline.
+	vimFiletypeRegex = regexp.MustCompile(`(?:^|[\s:])(?:filetype|ft|syntax|syn)=([\w+#-]+)`)
+	// emacsModeRegex matches an Emacs file variables line and captures its
+	// contents.
+	emacsModeRegex = regexp.MustCompile(`-\*-\s*(.*?)\s*-\*-`)
+	// emacsModeVarRegex captures the mode variable of an Emacs file
+	// variables line.
+	emacsModeVarRegex = regexp.MustCompile(`(?i)(?:^|;)\s*mode:\s*([\w+#-]+)`)
+)
+
+// modelineLines is the number of lines at the start and end of a file
+// searched for a Vim modeline, matching Vim's default 'modelines' setting.
+const modelineLines = 5
+
+// Detect identifies the language of a file from its content: a "#!" line
+// naming the interpreter, then a Vim modeline or Emacs mode line.
+func Detect(content []byte) (Language, bool) {
+	lines := strings.Split(string(bytes.TrimRight(content, "\n")), "\n")
+	if l, ok := fromShebang(lines[0]); ok {
+		return l, true
+	}
+	return fromModeline(lines)
+}
+
+// fromShebang returns the language of the interpreter named on a "#!" line,
+// skipping env and its options.
+func fromShebang(line string) (Language, bool) {
+	rest, ok := strings.CutPrefix(line, "#!")
+	if !ok {
+		return Language{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return Language{}, false
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "-") || strings.Contains(f, "=") {
+				continue
+			}
+			interpreter = path.Base(f)
+			break
+		}
+	}
+	if l, ok := Lookup(interpreter); ok {
+		return l, true
+	}
+	return Lookup(versionSuffixRegex.ReplaceAllString(interpreter, ""))
+}
+
+// fromModeline returns the language named by an Emacs mode line on the
+// first line (or the second, after a "#!" line), or by a Vim modeline near
+// the start or end of the file.
+func fromModeline(lines []string) (Language, bool) {
+	for i := 0; i < len(lines) && i < 2; i++ {
+		if m := emacsModeRegex.FindStringSubmatch(lines[i]); m != nil {
+			mode := m[1]
+			if strings.Contains(mode, ":") {
+				mode = ""
+				if v := emacsModeVarRegex.FindStringSubmatch(m[1]); v != nil {
+					mode = v[1]
+				}
+			}
+			if l, ok := Lookup(mode); ok {
+				return l, true
+			}
+		}
+	}
+
+	candidates := lines
+	if len(lines) > 2*modelineLines {
+		candidates = append(append([]string(nil), lines[:modelineLines]...), lines[len(lines)-modelineLines:]...)
+	}
+	for _, line := range candidates {
+		m := vimModelineRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if ft := vimFiletypeRegex.FindStringSubmatch(" " + m[1]); ft != nil {
+			if l, ok := Lookup(ft[1]); ok {
+				return l, true
+			}
+		}
+	}
+	return Language{}, false
+}
@@ -0,0 +1,81 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"env shebang", "#!/usr/bin/env python3\nprint()\n", "python"},
+		{"versioned interpreter", "#!/usr/local/bin/python3.11\n", "python"},
+		{"env with options", "#!/usr/bin/env -S deno run --allow-net\n", "javascript"},
+		{"env with variable", "#!/usr/bin/env NODE_ENV=test node\n", "javascript"},
+		{"absolute shebang", "#!/bin/bash\nset -e\n", "shell"},
+		{"unknown interpreter", "#!/usr/bin/awk -f\n", ""},
+		{"vim modeline at end", "x = 1\n\n# vim: set ft=ruby:\n", "ruby"},
+		{"vim modeline without set", "// vim: ts=4 filetype=groovy\npipeline {}\n", "groovy"},
+		{"emacs mode line", "# -*- mode: python; coding: utf-8 -*-\n", "python"},
+		{"emacs bare mode after shebang", "#!/bin/sh\n# -*- perl -*-\n", "shell"},
+		{"emacs mode on second line", "# generated\n# -*- mode: yaml -*-\n", "yaml"},
+		{"shebang wins over modeline", "#!/usr/bin/env ruby\n# vim: ft=python\n", "ruby"},
+		{"no hints", "just text\n", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Detect([]byte(tt.content))
+			if got.Name != tt.want || ok != (tt.want != "") {
+				t.Errorf("Detect() = %q, %v, want %q", got.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectVimModelineOutsideSearchedLines(t *testing.T) {
+	content := "a\nb\nc\nd\ne\n# vim: ft=python\nf\ng\nh\ni\nj\nk\n"
+	if got, ok := Detect([]byte(content)); ok {
+		t.Errorf("Detect() = %q, want no match for a modeline in the middle of a file", got.Name)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	mappings := []Mapping{
+		{Pattern: "Jenkinsfile", Language: "groovy"},
+		{Pattern: "scripts/*", Language: "bash"},
+		{Pattern: "*.tpl", Language: "go"},
+		{Pattern: "*", Language: "python"},
+	}
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"Jenkinsfile", "groovy"},
+		{"ci/Jenkinsfile", "groovy"},
+		{"scripts/deploy", "shell"},
+		{"tools/scripts/deploy", "python"},
+		{"web/page.tpl", "go"},
+		{"README", "python"},
+	}
+	for _, tt := range tests {
+		got, ok := Match(mappings, tt.filename)
+		if !ok || got.Name != tt.want {
+			t.Errorf("Match(%q) = %q, %v, want %q", tt.filename, got.Name, ok, tt.want)
+		}
+	}
+	if got, ok := Match(mappings[:3], "main.go"); ok {
+		t.Errorf("Match(main.go) = %q, want no match", got.Name)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"python", "Python3", " py ", "ipython3"} {
+		if l, ok := Lookup(name); !ok || l.Name != "python" || l.Extension != ".py" {
+			t.Errorf("Lookup(%q) = %+v, %v, want python", name, l, ok)
+		}
+	}
+	if _, ok := Lookup("cobol"); ok {
+		t.Error("Lookup(cobol) found a language")
+	}
+}
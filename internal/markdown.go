@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/language"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// htmlDelimiters are the comment delimiters recognized in HTML and Markdown.
+var htmlDelimiters = []commentDelimiter{{open: "<!--", close: "-->"}}
+
+// jsxDelimiters are the comment delimiters recognized in MDX and Astro
+// markup, which also allow JSX expression comments.
+var jsxDelimiters = []commentDelimiter{{open: "<!--", close: "-->"}, {open: "{/*", close: "*/}"}}
+
+// fenceOpenRegex matches the opening line of a fenced code block: up to three
+// spaces of indentation, at least three backticks or tildes, and an optional
+// info string.
+var fenceOpenRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*(.*)$")
+
+// isDocumentation reports whether filename is a Markdown, MDX, or
+// reStructuredText document.
+func isDocumentation(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".md", ".markdown", ".mdx", ".rst":
+		return true
+	}
+	return false
+}
+
+// parseDocumentationTODOs extracts TODO comments from a documentation file:
+// markers in comments, markers in fenced code blocks parsed with the fence's
+// language grammar, and, if enabled, plain "TODO:" paragraphs.
+func parseDocumentationTODOs(fc fileChange, content []byte, p markerPatterns, opts ParseOptions) []types.TODO {
+	r := fileRegion(fc.path, content)
+
+	if strings.EqualFold(path.Ext(fc.path), ".rst") {
+		return parseRSTRegionTODOs(fc, r, p, opts)
+	}
+	return parseMarkdownRegionTODOs(fc, r, p, opts)
+}
+
+// parseMarkdownRegionTODOs extracts TODO comments from a Markdown region on
+// lines that map to added file lines. HTML comments (and JSX comments in
+// MDX) may span lines; fenced code blocks with a known language are parsed
+// with that language's grammar.
+func parseMarkdownRegionTODOs(fc fileChange, r sourceRegion, p markerPatterns, opts ParseOptions) []types.TODO {
+	local := regionChange(fc, r)
+	if len(local.addedRanges) == 0 {
+		return nil
+	}
+
+	scanner := delimitedCommentScanner{delimiters: htmlDelimiters}
+	if strings.EqualFold(path.Ext(r.filename), ".mdx") {
+		scanner.delimiters = jsxDelimiters
+	}
+
+	var todos []types.TODO
+	var fence *markdownFence
+	for i, line := range r.lines {
+		lineNumber := i + 1
+
+		if fence != nil {
+			if fence.closes(line) {
+				todos = append(todos, fence.parse(local, p)...)
+				fence = nil
+			} else {
+				fence.add(line, lineNumber)
+			}
+			continue
+		}
+
+		if !scanner.inComment() {
+			if m := fenceOpenRegex.FindStringSubmatch(line); m != nil {
+				fence = &markdownFence{marker: m[1], language: fenceLanguage(m[2])}
+				continue
+			}
+		}
+
+		added := lineInRanges(lineNumber, local.addedRanges)
+		inComment := scanner.inComment()
+		segments := scanner.scan(line)
+		if !added {
+			continue
+		}
+		if len(segments) > 0 {
+			todos = append(todos, commentSegmentTODOs(segments, lineNumber, p.body)...)
+			continue
+		}
+		if opts.MarkdownParagraphs && !inComment {
+			todos = append(todos, paragraphTODOs(line, lineNumber, p.paragraph)...)
+		}
+	}
+	// An unterminated fence runs to the end of the document.
+	if fence != nil {
+		todos = append(todos, fence.parse(local, p)...)
+	}
+
+	return r.remap(fc.path, todos)
+}
+
+// commentSegmentTODOs returns a TODO for each comment segment whose text
+// starts with a marker.
+func commentSegmentTODOs(segments []commentSegment, line int, body *regexp.Regexp) []types.TODO {
+	var todos []types.TODO
+	for _, seg := range segments {
+		if matches := body.FindStringSubmatch(seg.text); len(matches) > 2 {
+			comment := seg.display()
+			if seg.open == "" {
+				// Continuation line: report the text from the marker on.
+				comment = strings.TrimSpace(matches[1] + seg.close)
+			}
+			todos = append(todos, types.TODO{
+				Line:    line,
+				Comment: comment,
+				Type:    strings.ToUpper(matches[2]),
+			})
+		}
+	}
+	return todos
+}
+
+// paragraphTODOs returns a TODO if line is a plain-text "MARKER:" line.
+func paragraphTODOs(line string, lineNumber int, paragraph *regexp.Regexp) []types.TODO {
+	matches := paragraph.FindStringSubmatch(line)
+	if len(matches) <= 2 {
+		return nil
+	}
+	return []types.TODO{{
+		Line:    lineNumber,
+		Comment: strings.TrimSpace(matches[1]),
+		Type:    matches[2],
+	}}
+}
+
+// markdownFence accumulates the contents of a fenced code block.
+type markdownFence struct {
+	marker   string
+	language string
+	lines    []string
+	lineNums []int
+}
+
+func (f *markdownFence) add(line string, lineNumber int) {
+	f.lines = append(f.lines, line)
+	f.lineNums = append(f.lineNums, lineNumber)
+}
+
+// closes reports whether line closes the fence: the same fence character
+// repeated at least as many times, with nothing else but whitespace.
+func (f *markdownFence) closes(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if len(line)-len(strings.TrimLeft(line, " ")) > 3 || len(trimmed) < len(f.marker) {
+		return false
+	}
+	return strings.Trim(trimmed, f.marker[:1]) == ""
+}
+
+// parse extracts TODO comments from the fence contents using the grammar of
+// its language. Fences without a recognized language are skipped, since
+// their contents (console output, prose, diagrams) are not code.
+func (f *markdownFence) parse(local fileChange, p markerPatterns) []types.TODO {
+	if _, ok := language.Lookup(f.language); !ok {
+		return nil
+	}
+	r := sourceRegion{
+		filename:  regionFilename(f.language),
+		lines:     f.lines,
+		fileLines: f.lineNums,
+	}
+	return parseRegionTODOs(local, r, p)
+}
+
+// fenceLanguage extracts the language name from a fence info string, such
+// as "go", "{python}", ".js", or "ts title=app.ts".
+func fenceLanguage(info string) string {
+	fields := strings.Fields(strings.NewReplacer("{", " ", "}", " ", ",", " ").Replace(info))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(fields[0], "."))
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// newFileDiff returns a diff that adds path with the given content.
+func newFileDiff(path, content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\nnew file mode 100644\nindex 0000000..abcdefg\n--- /dev/null\n+++ b/%s\n", path, path, path)
+	fmt.Fprintf(&b, "@@ -0,0 +1,%d @@\n", len(lines))
+	for _, line := range lines {
+		b.WriteString("+" + line + "\n")
+	}
+	return b.String()
+}
+
+func TestParseDiffWithOptionsMarkdown(t *testing.T) {
+	defaultTypes := []string{"TODO", "FIXME", "HACK", "NOTE", "XXX", "BUG"}
+
+	tests := []struct {
+		name       string
+		path       string
+		content    string
+		paragraphs bool
+		expected   []types.TODO
+	}{
+		{
+			name:    "HTML comments on one and several lines",
+			path:    "docs/guide.md",
+			content: "# Guide\n\n<!-- FIXME: outdated screenshot -->\n\n<!--\n  TODO: document the flags\n-->\nText <!-- NOTE: inline --> more\n",
+			expected: []types.TODO{
+				{Filename: "docs/guide.md", Line: 3, Comment: "<!-- FIXME: outdated screenshot -->", Type: "FIXME"},
+				{Filename: "docs/guide.md", Line: 6, Comment: "TODO: document the flags", Type: "TODO"},
+				{Filename: "docs/guide.md", Line: 8, Comment: "<!-- NOTE: inline -->", Type: "NOTE"},
+			},
+		},
+		{
+			name:    "fenced code blocks use the fence language",
+			path:    "README.md",
+			content: "# Usage\n\n```python\nimport os\n# TODO: handle errors\n```\n\n~~~ts title=app.ts\n// HACK: cast\n~~~\n",
+			expected: []types.TODO{
+				{Filename: "README.md", Line: 5, Comment: "# TODO: handle errors", Type: "TODO"},
+				{Filename: "README.md", Line: 9, Comment: "// HACK: cast", Type: "HACK"},
+			},
+		},
+		{
+			name:     "fences without a known language are skipped",
+			path:     "README.md",
+			content:  "```\n# TODO: not code\n```\n\n```console\n$ make # FIXME: prompt\n```\n",
+			expected: nil,
+		},
+		{
+			name:     "comment markers in fences are not HTML comments",
+			path:     "README.md",
+			content:  "```html\n<p>hi</p>\n```\n\n```text\n<!-- TODO: example markup -->\n```\n",
+			expected: nil,
+		},
+		{
+			name:     "paragraphs are ignored by default",
+			path:     "README.md",
+			content:  "# Title\n\nTODO: write the introduction\n",
+			expected: nil,
+		},
+		{
+			name:       "paragraphs reported when enabled",
+			path:       "README.md",
+			content:    "# Title\n\nTODO: write the introduction\n- FIXME(alice): broken link\n> NOTE: quoted\nNote: prose is not a marker\nA TODO: in the middle\n\n```sh\nTODO: not a paragraph\n```\n<!--\nBUG: still a comment\n-->\n",
+			paragraphs: true,
+			expected: []types.TODO{
+				{Filename: "README.md", Line: 3, Comment: "TODO: write the introduction", Type: "TODO"},
+				{Filename: "README.md", Line: 4, Comment: "FIXME(alice): broken link", Type: "FIXME"},
+				{Filename: "README.md", Line: 5, Comment: "NOTE: quoted", Type: "NOTE"},
+				{Filename: "README.md", Line: 13, Comment: "BUG: still a comment", Type: "BUG"},
+			},
+		},
+		{
+			name:    "MDX JSX comments",
+			path:    "docs/intro.mdx",
+			content: "import Tabs from './tabs'\n\n{/* TODO: add tabs */}\n<!-- XXX: legacy -->\n",
+			expected: []types.TODO{
+				{Filename: "docs/intro.mdx", Line: 3, Comment: "{/* TODO: add tabs */}", Type: "TODO"},
+				{Filename: "docs/intro.mdx", Line: 4, Comment: "<!-- XXX: legacy -->", Type: "XXX"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{tt.path: []byte(tt.content)}
+			result := ParseDiffWithOptions(newFileDiff(tt.path, tt.content), files, ParseOptions{
+				Types:              defaultTypes,
+				MarkdownParagraphs: tt.paragraphs,
+			})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithOptions() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDiffWithContentsMarkdownOnlyAddedLines(t *testing.T) {
+	diff := `diff --git a/README.md b/README.md
+index 1234567..abcdefg 100644
+--- a/README.md
++++ b/README.md
+@@ -1,5 +1,6 @@
+ <!--
+ TODO: existing
++FIXME: new continuation
+ -->
+ 
+ ` + "```go" + `
+`
+	content := "<!--\nTODO: existing\nFIXME: new continuation\n-->\n\n```go\n// TODO: existing code\n```\n"
+	expected := []types.TODO{
+		{Filename: "README.md", Line: 3, Comment: "FIXME: new continuation", Type: "FIXME"},
+	}
+
+	result := ParseDiffWithContents(diff, map[string][]byte{"README.md": []byte(content)})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ParseDiffWithContents() = %+v, expected %+v", result, expected)
+	}
+}
+
+func TestFenceLanguage(t *testing.T) {
+	tests := map[string]string{
+		"go":               "go",
+		"Python":           "python",
+		"{.js}":            "js",
+		"ts title=app.ts":  "ts",
+		"{python, eval=F}": "python",
+		"":                 "",
+	}
+	for info, want := range tests {
+		if got := fenceLanguage(info); got != want {
+			t.Errorf("fenceLanguage(%q) = %q, expected %q", info, got, want)
+		}
+	}
+}
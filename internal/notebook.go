@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// notebookDefaultLanguage is assumed when a notebook does not declare its
+// kernel language.
+const notebookDefaultLanguage = "python"
+
+// notebookCell is a single Jupyter notebook cell with each source line mapped
+// to the notebook JSON line it was read from.
+type notebookCell struct {
+	cellType  string
+	lines     []string
+	jsonLines []int
+}
+
+// notebook is the subset of a Jupyter notebook needed to find TODOs.
+type notebook struct {
+	language string
+	cells    []notebookCell
+}
+
+// isNotebook reports whether path is a Jupyter notebook.
+func isNotebook(filename string) bool {
+	return strings.EqualFold(path.Ext(filename), ".ipynb")
+}
+
+// parseNotebookTODOs extracts TODO comments from a Jupyter notebook cell by
+// cell. Code cells are parsed with the grammar of the kernel language and
+// markdown cells with the same rules as Markdown files. Returns nil if
+// content is not a valid notebook.
+func parseNotebookTODOs(fc fileChange, content []byte, p markerPatterns, opts ParseOptions) []types.TODO {
+	nb, err := parseNotebook(content)
+	if err != nil {
+		return nil
+	}
+
+	todos := make([]types.TODO, 0)
+	for i, cell := range nb.cells {
+		r := sourceRegion{
+			lines:        cell.lines,
+			fileLines:    cell.jsonLines,
+			notebookCell: i + 1,
+		}
+		switch cell.cellType {
+		case "code":
+			r.filename = regionFilename(nb.language)
+			todos = append(todos, parseRegionTODOs(fc, r, p)...)
+		case "markdown":
+			r.filename = "region.md"
+			todos = append(todos, parseMarkdownRegionTODOs(fc, r, p, opts)...)
+		}
+	}
+	return todos
+}
+
+// parseNotebook decodes a notebook, recording the JSON line of every cell
+// source string so that findings can be mapped back to the diff.
+func parseNotebook(content []byte) (notebook, error) {
+	nb := notebook{language: notebookDefaultLanguage}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	lineAt := newLineIndex(content)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return notebook{}, err
+	}
+	for dec.More() {
+		key, err := decodeKey(dec)
+		if err != nil {
+			return notebook{}, err
+		}
+		switch key {
+		case "cells":
+			cells, err := decodeNotebookCells(dec, lineAt)
+			if err != nil {
+				return notebook{}, err
+			}
+			nb.cells = cells
+		case "metadata":
+			var meta struct {
+				Kernelspec struct {
+					Language string `json:"language"`
+				} `json:"kernelspec"`
+				LanguageInfo struct {
+					Name string `json:"name"`
+				} `json:"language_info"`
+			}
+			if err := dec.Decode(&meta); err != nil {
+				return notebook{}, err
+			}
+			if meta.Kernelspec.Language != "" {
+				nb.language = meta.Kernelspec.Language
+			} else if meta.LanguageInfo.Name != "" {
+				nb.language = meta.LanguageInfo.Name
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return notebook{}, err
+			}
+		}
+	}
+	return nb, expectDelim(dec, '}')
+}
+
+// decodeNotebookCells decodes the "cells" array of a notebook.
+func decodeNotebookCells(dec *json.Decoder, lineAt func(int64) int) ([]notebookCell, error) {
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
+	}
+	var cells []notebookCell
+	for dec.More() {
+		if err := expectDelim(dec, '{'); err != nil {
+			return nil, err
+		}
+		var cell notebookCell
+		for dec.More() {
+			key, err := decodeKey(dec)
+			if err != nil {
+				return nil, err
+			}
+			switch key {
+			case "cell_type":
+				if err := dec.Decode(&cell.cellType); err != nil {
+					return nil, err
+				}
+			case "source":
+				if err := decodeCellSource(dec, lineAt, &cell); err != nil {
+					return nil, err
+				}
+			default:
+				var skip json.RawMessage
+				if err := dec.Decode(&skip); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return nil, err
+		}
+		cells = append(cells, cell)
+	}
+	return cells, expectDelim(dec, ']')
+}
+
+// decodeCellSource decodes a cell source, which nbformat allows to be either
+// a single string or a list of strings, into lines. Each line records the
+// JSON line of the string it starts in; lines of a single-string source all
+// map to that string's line.
+func decodeCellSource(dec *json.Decoder, lineAt func(int64) int, cell *notebookCell) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	var current strings.Builder
+	currentLine := 0
+	appendText := func(text string, jsonLine int) {
+		for text != "" {
+			if currentLine == 0 {
+				currentLine = jsonLine
+			}
+			before, after, found := strings.Cut(text, "\n")
+			current.WriteString(before)
+			if !found {
+				return
+			}
+			cell.lines = append(cell.lines, strings.TrimSuffix(current.String(), "\r"))
+			cell.jsonLines = append(cell.jsonLines, currentLine)
+			current.Reset()
+			currentLine = 0
+			text = after
+		}
+	}
+
+	switch v := tok.(type) {
+	case string:
+		appendText(v, lineAt(dec.InputOffset()))
+	case json.Delim:
+		if v != '[' {
+			return fmt.Errorf("unexpected %v in cell source", v)
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			s, ok := tok.(string)
+			if !ok {
+				return fmt.Errorf("unexpected %v in cell source", tok)
+			}
+			appendText(s, lineAt(dec.InputOffset()))
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unexpected %v in cell source", tok)
+	}
+
+	if currentLine != 0 {
+		cell.lines = append(cell.lines, strings.TrimSuffix(current.String(), "\r"))
+		cell.jsonLines = append(cell.jsonLines, currentLine)
+	}
+	return nil
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter.
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+// decodeKey reads the next object key.
+func decodeKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+// newLineIndex returns a function that converts a byte offset in content to
+// a 1-based line number. The offset reported by json.Decoder points just past
+// a token; JSON strings cannot contain raw newlines, so the line of the end
+// of a string is the line of the whole string.
+func newLineIndex(content []byte) func(int64) int {
+	var newlines []int64
+	for i, b := range content {
+		if b == '\n' {
+			newlines = append(newlines, int64(i))
+		}
+	}
+	return func(offset int64) int {
+		return sort.Search(len(newlines), func(i int) bool { return newlines[i] >= offset-1 }) + 1
+	}
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+const sampleNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": [
+    "# Analysis\n",
+    "<!-- NOTE: explain the dataset -->"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1,
+   "metadata": {},
+   "outputs": [],
+   "source": [
+    "import pandas as pd\n",
+    "# TODO: load from config\n",
+    "df = pd.read_csv(\"data.csv\")"
+   ]
+  },
+  {
+   "cell_type": "code",
+   "metadata": {},
+   "outputs": [],
+   "source": "x = 1\n# FIXME: single string source"
+  }
+ ],
+ "metadata": {
+  "kernelspec": {
+   "display_name": "Python 3",
+   "language": "python",
+   "name": "python3"
+  }
+ },
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func TestParseNotebook(t *testing.T) {
+	nb, err := parseNotebook([]byte(sampleNotebook))
+	if err != nil {
+		t.Fatalf("parseNotebook() error = %v", err)
+	}
+	if nb.language != "python" {
+		t.Errorf("language = %q, expected %q", nb.language, "python")
+	}
+
+	want := []notebookCell{
+		{cellType: "markdown", lines: []string{"# Analysis", "<!-- NOTE: explain the dataset -->"}, jsonLines: []int{7, 8}},
+		{cellType: "code", lines: []string{"import pandas as pd", "# TODO: load from config", `df = pd.read_csv("data.csv")`}, jsonLines: []int{17, 18, 19}},
+		{cellType: "code", lines: []string{"x = 1", "# FIXME: single string source"}, jsonLines: []int{26, 26}},
+	}
+	if !reflect.DeepEqual(nb.cells, want) {
+		t.Errorf("cells = %+v, expected %+v", nb.cells, want)
+	}
+}
+
+func TestParseNotebookDefaultsLanguage(t *testing.T) {
+	nb, err := parseNotebook([]byte(`{"cells": [], "metadata": {"language_info": {"name": "julia"}}}`))
+	if err != nil {
+		t.Fatalf("parseNotebook() error = %v", err)
+	}
+	if nb.language != "julia" {
+		t.Errorf("language = %q, expected %q", nb.language, "julia")
+	}
+
+	nb, err = parseNotebook([]byte(`{"cells": []}`))
+	if err != nil {
+		t.Fatalf("parseNotebook() error = %v", err)
+	}
+	if nb.language != notebookDefaultLanguage {
+		t.Errorf("language = %q, expected %q", nb.language, notebookDefaultLanguage)
+	}
+}
+
+func TestParseNotebookInvalid(t *testing.T) {
+	for _, input := range []string{"", "[]", `{"cells": {}}`, `{"cells": [{"source": 1}]}`} {
+		if _, err := parseNotebook([]byte(input)); err == nil {
+			t.Errorf("parseNotebook(%q) expected error", input)
+		}
+	}
+}
+
+func TestParseDiffWithContentsNotebook(t *testing.T) {
+	diff := `diff --git a/analysis.ipynb b/analysis.ipynb
+index 1234567..abcdefg 100644
+--- a/analysis.ipynb
++++ b/analysis.ipynb
+@@ -5,14 +5,16 @@
+    "metadata": {},
+    "source": [
+-    "# Analysis"
++    "# Analysis\n",
++    "<!-- NOTE: explain the dataset -->"
+    ]
+   },
+   {
+    "cell_type": "code",
+    "execution_count": 1,
+    "metadata": {},
+    "outputs": [],
+    "source": [
+     "import pandas as pd\n",
++    "# TODO: load from config\n",
+     "df = pd.read_csv(\"data.csv\")"
+    ]
+   },`
+
+	got := ParseDiffWithContents(diff, map[string][]byte{"analysis.ipynb": []byte(sampleNotebook)})
+	want := []types.TODO{
+		{Filename: "analysis.ipynb", Line: 8, Comment: "<!-- NOTE: explain the dataset -->", Type: "NOTE", Notebook: &types.NotebookLocation{Cell: 1, Line: 2}},
+		{Filename: "analysis.ipynb", Line: 18, Comment: "# TODO: load from config", Type: "TODO", Notebook: &types.NotebookLocation{Cell: 2, Line: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiffWithContents() = %+v, expected %+v", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suree33/gh-pr-todo/internal/language"
+	"github.com/fatih/color"
+)
+
+// PrintLanguages lists languages with the parser used for each (Tree-sitter
+// when hasGrammar reports a grammar for the language's extension, regex
+// otherwise), their comment syntax, and their aliases.
+func PrintLanguages(langs []language.Language, hasGrammar func(ext string) bool) {
+	w := tabwriter.NewWriter(color.Output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LANGUAGE\tEXTENSION\tPARSER\tCOMMENTS\tALIASES")
+	for _, l := range langs {
+		parser := "regex"
+		if hasGrammar(l.Extension) {
+			parser = "tree-sitter"
+		}
+		comments := strings.Join(l.Comments, " ")
+		if comments == "" {
+			comments = "-"
+		}
+		aliases := strings.Join(l.Aliases, ", ")
+		if aliases == "" {
+			aliases = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", l.Name, l.Extension, parser, comments, aliases)
+	}
+	w.Flush()
+}
@@ -0,0 +1,25 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/language"
+)
+
+func TestPrintLanguages(t *testing.T) {
+	langs := []language.Language{
+		{Name: "go", Extension: ".go", Aliases: []string{"golang"}, Comments: []string{"//", "/* */"}},
+		{Name: "json", Extension: ".json"},
+		{Name: "python", Extension: ".py", Aliases: []string{"py", "python3"}, Comments: []string{"#"}},
+	}
+	hasGrammar := func(ext string) bool { return ext != ".json" }
+
+	got := captureOutput(t, func() { PrintLanguages(langs, hasGrammar) })
+	want := "LANGUAGE  EXTENSION  PARSER       COMMENTS  ALIASES\n" +
+		"go        .go        tree-sitter  // /* */  golang\n" +
+		"json      .json      regex        -         -\n" +
+		"python    .py        tree-sitter  #         py, python3\n"
+	if got != want {
+		t.Errorf("PrintLanguages() =\n%s\nwant\n%s", got, want)
+	}
+}
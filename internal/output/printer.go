@@ -28,6 +28,70 @@ func PrintTODOs(todos []types.TODO, groupBy types.GroupBy) {
 		printGroupedByFile(todos)
 	case types.GroupByType:
 		printGroupedByType(todos)
+	case types.GroupBySymbol:
+		printGroupedBySymbol(todos)
+	case types.GroupByCommit:
+		printGroupedByCommit(todos)
+	case types.GroupByAuthor:
+		printGroupedByAuthor(todos)
+	}
+}
+
+// PrintStack prints the TODOs of a stack of PRs, PR by PR from the bottom
+// up, each under a heading naming the PR and its branches.
+func PrintStack(stack []types.PullRequest, todos []types.TODO, groupBy types.GroupBy) {
+	for i, pr := range stack {
+		layer := todosOf(&stack[i], todos)
+		fmt.Fprintf(color.Output, "%s %s\n", Bold(fmt.Sprintf("#%d %s", pr.Number, pr.Title)), Blue(pr.BaseRefName+" ← "+pr.HeadRefName))
+		if len(layer) == 0 {
+			fmt.Fprintf(color.Output, "  No TODO-style comments\n\n")
+			continue
+		}
+		fmt.Fprintf(color.Output, "  %d TODO-style comment(s)\n\n", len(layer))
+		PrintTODOs(layer, groupBy)
+	}
+}
+
+// PrintPRs prints the TODOs of several PRs, such as the results of a
+// search, PR by PR, each under a heading naming the PR and linking to it.
+// PRs without TODOs are left out.
+func PrintPRs(prs []types.PullRequest, todos []types.TODO, groupBy types.GroupBy) {
+	for i, pr := range prs {
+		layer := todosOf(&prs[i], todos)
+		if len(layer) == 0 {
+			continue
+		}
+		fmt.Fprintf(color.Output, "%s %s\n", Bold(fmt.Sprintf("%s#%d %s", pr.Repository, pr.Number, pr.Title)), Blue(pr.URL))
+		fmt.Fprintf(color.Output, "  %d TODO-style comment(s)\n\n", len(layer))
+		PrintTODOs(layer, groupBy)
+	}
+}
+
+// todosOf returns the todos found in pr, which must point into the slice
+// of PRs the todos were collected for, as their PR fields do.
+func todosOf(pr *types.PullRequest, todos []types.TODO) []types.TODO {
+	var found []types.TODO
+	for _, todo := range todos {
+		if todo.PR == pr {
+			found = append(found, todo)
+		}
+	}
+	return found
+}
+
+// PrintPRFileNames prints the names of the files with TODOs of several
+// PRs, PR by PR, each prefixed with its PR as OWNER/REPO#N.
+func PrintPRFileNames(prs []types.PullRequest, todos []types.TODO) {
+	for i, pr := range prs {
+		files := make(map[string]struct{})
+		for _, todo := range todosOf(&prs[i], todos) {
+			files[todo.Filename] = struct{}{}
+		}
+		fileNames := slices.Collect(maps.Keys(files))
+		slices.Sort(fileNames)
+		for _, file := range fileNames {
+			fmt.Fprintf(color.Output, "%s#%d %s\n", pr.Repository, pr.Number, file)
+		}
 	}
 }
 
@@ -52,8 +116,31 @@ func PrintCount(todos []types.TODO) {
 
 func printFlat(todos []types.TODO) {
 	for _, todo := range todos {
-		fmt.Fprintf(color.Output, "* %s\n", Blue(todo.Filename+":"+strconv.Itoa(todo.Line)))
-		fmt.Fprintf(color.Output, "  %s\n\n", todo.Comment)
+		fmt.Fprintf(color.Output, "* %s\n", location(todo))
+		fmt.Fprintf(color.Output, "  %s\n", todo.Comment)
+		printContext(todo, "  ")
+		fmt.Fprintln(color.Output)
+	}
+}
+
+// printContext writes the surrounding source lines of a TODO, marking lines
+// added in the diff with "+" like a unified diff.
+func printContext(todo types.TODO, indent string) {
+	if len(todo.Context) == 0 {
+		return
+	}
+	width := len(strconv.Itoa(todo.Context[len(todo.Context)-1].Line))
+	for _, cl := range todo.Context {
+		lineStr := strconv.Itoa(cl.Line)
+		marker := " "
+		if cl.Added {
+			marker = "+"
+		}
+		line := fmt.Sprintf("%s%s %s %s", strings.Repeat(" ", width-len(lineStr)), lineStr, marker, cl.Text)
+		if cl.Added {
+			line = Green(line)
+		}
+		fmt.Fprintf(color.Output, "%s%s\n", indent, line)
 	}
 }
 
@@ -72,12 +159,108 @@ func printGroupedByFile(todos []types.TODO) {
 		fmt.Fprintf(color.Output, "* %s\n", Blue(filename))
 		for _, todo := range files[filename] {
 			lineStr := strconv.Itoa(todo.Line)
-			fmt.Fprintf(color.Output, "  %s%s: %s\n", strings.Repeat(" ", maxLineNumberLen-len(lineStr)), Green(lineStr), todo.Comment)
+			fmt.Fprintf(color.Output, "  %s%s: %s%s\n", strings.Repeat(" ", maxLineNumberLen-len(lineStr)), Green(lineStr), todo.Comment, detailSuffix(todo))
+			printContext(todo, "  "+strings.Repeat(" ", maxLineNumberLen+2))
+		}
+		fmt.Fprintln(color.Output)
+	}
+}
+
+// topLevelSymbol labels TODOs outside any function, method, or class.
+const topLevelSymbol = "(top level)"
+
+func printGroupedBySymbol(todos []types.TODO) {
+	type symbolKey struct {
+		filename string
+		symbol   string
+	}
+	groups := make(map[symbolKey][]types.TODO)
+	maxLineNumberLen := 0
+	for _, todo := range todos {
+		key := symbolKey{filename: todo.Filename, symbol: todo.Symbol}
+		groups[key] = append(groups[key], todo)
+		if n := len(strconv.Itoa(todo.Line)); n > maxLineNumberLen {
+			maxLineNumberLen = n
+		}
+	}
+	keys := slices.Collect(maps.Keys(groups))
+	slices.SortFunc(keys, func(a, b symbolKey) int {
+		if c := strings.Compare(a.filename, b.filename); c != 0 {
+			return c
+		}
+		return strings.Compare(a.symbol, b.symbol)
+	})
+	for _, key := range keys {
+		symbol := key.symbol
+		if symbol == "" {
+			symbol = topLevelSymbol
+		}
+		fmt.Fprintf(color.Output, "* %s %s\n", Blue(key.filename), Magenta(symbol))
+		for _, todo := range groups[key] {
+			lineStr := strconv.Itoa(todo.Line)
+			// The heading names the symbol.
+			todo.Symbol = ""
+			fmt.Fprintf(color.Output, "  %s%s: %s%s\n", strings.Repeat(" ", maxLineNumberLen-len(lineStr)), Green(lineStr), todo.Comment, detailSuffix(todo))
+			printContext(todo, "  "+strings.Repeat(" ", maxLineNumberLen+2))
 		}
 		fmt.Fprintln(color.Output)
 	}
 }
 
+// location renders "file:line", followed by the notebook cell, the
+// enclosing symbol, and the commit if known.
+func location(todo types.TODO) string {
+	loc := Blue(todo.Filename + ":" + strconv.Itoa(todo.Line))
+	if todo.Notebook != nil {
+		loc += " (" + cellLabel(todo.Notebook) + ")"
+	}
+	if todo.Symbol != "" {
+		loc += " in " + Magenta(todo.Symbol)
+	}
+	if todo.Commit != nil {
+		loc += " (" + commitLabel(todo.Commit) + ")"
+	}
+	return loc
+}
+
+// detailSuffix renders the notebook cell, enclosing symbol, and commit for
+// per-line listings.
+func detailSuffix(todo types.TODO) string {
+	var details []string
+	if todo.Notebook != nil {
+		details = append(details, cellLabel(todo.Notebook))
+	}
+	if todo.Symbol != "" {
+		details = append(details, "in "+Magenta(todo.Symbol))
+	}
+	if todo.Commit != nil {
+		details = append(details, commitLabel(todo.Commit))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(details, ", ") + ")"
+}
+
+// commitLabel renders a commit as its abbreviated SHA and author, or only
+// the SHA when the author is unknown.
+func commitLabel(commit *types.Commit) string {
+	if commit.Author == "" {
+		return shortSHA(commit.SHA)
+	}
+	return fmt.Sprintf("%s by %s", shortSHA(commit.SHA), commit.Author)
+}
+
+// shortSHA abbreviates a commit SHA the way git does by default.
+func shortSHA(sha string) string {
+	return sha[:min(len(sha), 7)]
+}
+
+// cellLabel renders a notebook location as "cell N, line M".
+func cellLabel(loc *types.NotebookLocation) string {
+	return fmt.Sprintf("cell %d, line %d", loc.Cell, loc.Line)
+}
+
 func printGroupedByType(todos []types.TODO) {
 	todoTypes := make(map[string][]types.TODO)
 	for _, todo := range todos {
@@ -89,8 +272,84 @@ func printGroupedByType(todos []types.TODO) {
 		todos := todoTypes[todoType]
 		fmt.Fprintf(color.Output, "%s%s%s\n", Bold("["), Bold(Magenta(todoType)), Bold("]"))
 		for _, todo := range todos {
-			fmt.Fprintf(color.Output, "* %s\n", Blue(todo.Filename+":"+strconv.Itoa(todo.Line)))
-			fmt.Fprintf(color.Output, "  %s\n\n", todo.Comment)
+			fmt.Fprintf(color.Output, "* %s\n", location(todo))
+			fmt.Fprintf(color.Output, "  %s\n", todo.Comment)
+			printContext(todo, "  ")
+			fmt.Fprintln(color.Output)
 		}
 	}
 }
+
+// unknownCommit and unknownAuthor label TODOs that could not be attributed
+// to a commit.
+const (
+	unknownCommit = "(unknown commit)"
+	unknownAuthor = "(unknown author)"
+)
+
+// printGroupedByCommit lists TODOs under the commit that introduced them,
+// in the order the commits first appear, with unattributed TODOs last.
+func printGroupedByCommit(todos []types.TODO) {
+	var keys []string
+	headings := make(map[string]string)
+	groups := make(map[string][]types.TODO)
+	for _, todo := range todos {
+		key, heading := "", unknownCommit
+		if todo.Commit != nil {
+			key = todo.Commit.SHA
+			heading = Blue(shortSHA(todo.Commit.SHA)) + " " + todo.Commit.Subject
+			if todo.Commit.Author != "" {
+				heading += " (" + Magenta(todo.Commit.Author) + ")"
+			}
+		}
+		if _, ok := groups[key]; !ok && key != "" {
+			keys = append(keys, key)
+		}
+		headings[key] = heading
+		groups[key] = append(groups[key], todo)
+	}
+	if _, ok := groups[""]; ok {
+		keys = append(keys, "")
+	}
+	printLineGroups(keys, headings, groups, false)
+}
+
+// printGroupedByAuthor lists TODOs under the author of the commit that
+// introduced them, sorted by name, with unattributed TODOs last.
+func printGroupedByAuthor(todos []types.TODO) {
+	headings := make(map[string]string)
+	groups := make(map[string][]types.TODO)
+	for _, todo := range todos {
+		key, heading := "", unknownAuthor
+		if todo.Commit != nil && todo.Commit.Author != "" {
+			key = todo.Commit.Author
+			heading = Magenta(todo.Commit.Author)
+		}
+		headings[key] = heading
+		groups[key] = append(groups[key], todo)
+	}
+	keys := slices.Collect(maps.Keys(groups))
+	slices.SortFunc(keys, func(a, b string) int {
+		if (a == "") != (b == "") {
+			return strings.Compare(b, a)
+		}
+		return strings.Compare(a, b)
+	})
+	printLineGroups(keys, headings, groups, true)
+}
+
+// printLineGroups prints each group of TODOs under its heading, one
+// "file:line: comment" per TODO, with the commit if withCommit is set.
+func printLineGroups(keys []string, headings map[string]string, groups map[string][]types.TODO, withCommit bool) {
+	for _, key := range keys {
+		fmt.Fprintf(color.Output, "* %s\n", headings[key])
+		for _, todo := range groups[key] {
+			if !withCommit {
+				todo.Commit = nil
+			}
+			fmt.Fprintf(color.Output, "  %s: %s%s\n", Blue(todo.Filename+":"+strconv.Itoa(todo.Line)), todo.Comment, detailSuffix(todo))
+			printContext(todo, "    ")
+		}
+		fmt.Fprintln(color.Output)
+	}
+}
@@ -80,6 +80,214 @@ func TestPrintTODOs(t *testing.T) {
 	}
 }
 
+func TestPrintTODOsWithSymbol(t *testing.T) {
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 12, Comment: "// TODO: a", Type: "TODO", Symbol: "(*Client).FetchDiff"},
+		{Filename: "a.go", Line: 3, Comment: "// NOTE: top", Type: "NOTE"},
+		{Filename: "a.go", Line: 15, Comment: "// FIXME: b", Type: "FIXME", Symbol: "(*Client).FetchDiff"},
+		{Filename: "b.py", Line: 7, Comment: "# HACK: c", Type: "HACK", Symbol: "class Foo > def bar"},
+		{Filename: "c.ipynb", Line: 40, Comment: "# TODO: d", Type: "TODO", Symbol: "def f", Notebook: &types.NotebookLocation{Cell: 2, Line: 3}},
+	}
+
+	tests := []struct {
+		name    string
+		groupBy types.GroupBy
+		want    string
+	}{
+		{
+			name:    "GroupByNone",
+			groupBy: types.GroupByNone,
+			want: "* a.go:12 in (*Client).FetchDiff\n  // TODO: a\n\n" +
+				"* a.go:3\n  // NOTE: top\n\n" +
+				"* a.go:15 in (*Client).FetchDiff\n  // FIXME: b\n\n" +
+				"* b.py:7 in class Foo > def bar\n  # HACK: c\n\n" +
+				"* c.ipynb:40 (cell 2, line 3) in def f\n  # TODO: d\n\n",
+		},
+		{
+			name:    "GroupByFile",
+			groupBy: types.GroupByFile,
+			want: "* a.go\n" +
+				"  12: // TODO: a (in (*Client).FetchDiff)\n" +
+				"   3: // NOTE: top\n" +
+				"  15: // FIXME: b (in (*Client).FetchDiff)\n" +
+				"\n" +
+				"* b.py\n" +
+				"   7: # HACK: c (in class Foo > def bar)\n" +
+				"\n" +
+				"* c.ipynb\n" +
+				"  40: # TODO: d (cell 2, line 3, in def f)\n" +
+				"\n",
+		},
+		{
+			name:    "GroupBySymbol",
+			groupBy: types.GroupBySymbol,
+			want: "* a.go (top level)\n" +
+				"   3: // NOTE: top\n" +
+				"\n" +
+				"* a.go (*Client).FetchDiff\n" +
+				"  12: // TODO: a\n" +
+				"  15: // FIXME: b\n" +
+				"\n" +
+				"* b.py class Foo > def bar\n" +
+				"   7: # HACK: c\n" +
+				"\n" +
+				"* c.ipynb def f\n" +
+				"  40: # TODO: d (cell 2, line 3)\n" +
+				"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureOutput(t, func() { PrintTODOs(todos, tt.groupBy) })
+			if got != tt.want {
+				t.Errorf("output mismatch\n--- want ---\n%s\n--- got ---\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrintTODOsWithCommit(t *testing.T) {
+	first := &types.Commit{SHA: "0123456789abcdef", Subject: "Add client", Author: "Bob"}
+	second := &types.Commit{SHA: "fedcba9876543210", Subject: "Fix retries", Author: "Alice"}
+	anonymous := &types.Commit{SHA: "abcdef0123456789", Subject: "Vendor deps"}
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", Commit: second},
+		{Filename: "a.go", Line: 9, Comment: "// FIXME: b", Type: "FIXME", Symbol: "run", Commit: first},
+		{Filename: "b.go", Line: 2, Comment: "// HACK: c", Type: "HACK"},
+		{Filename: "b.go", Line: 7, Comment: "// TODO: d", Type: "TODO", Commit: second},
+		{Filename: "b.go", Line: 11, Comment: "// NOTE: e", Type: "NOTE", Commit: anonymous},
+	}
+
+	tests := []struct {
+		name    string
+		groupBy types.GroupBy
+		want    string
+	}{
+		{
+			name:    "GroupByNone",
+			groupBy: types.GroupByNone,
+			want: "* a.go:5 (fedcba9 by Alice)\n  // TODO: a\n\n" +
+				"* a.go:9 in run (0123456 by Bob)\n  // FIXME: b\n\n" +
+				"* b.go:2\n  // HACK: c\n\n" +
+				"* b.go:7 (fedcba9 by Alice)\n  // TODO: d\n\n" +
+				"* b.go:11 (abcdef0)\n  // NOTE: e\n\n",
+		},
+		{
+			name:    "GroupByCommit",
+			groupBy: types.GroupByCommit,
+			want: "* fedcba9 Fix retries (Alice)\n" +
+				"  a.go:5: // TODO: a\n" +
+				"  b.go:7: // TODO: d\n" +
+				"\n" +
+				"* 0123456 Add client (Bob)\n" +
+				"  a.go:9: // FIXME: b (in run)\n" +
+				"\n" +
+				"* abcdef0 Vendor deps\n" +
+				"  b.go:11: // NOTE: e\n" +
+				"\n" +
+				"* (unknown commit)\n" +
+				"  b.go:2: // HACK: c\n" +
+				"\n",
+		},
+		{
+			name:    "GroupByAuthor",
+			groupBy: types.GroupByAuthor,
+			want: "* Alice\n" +
+				"  a.go:5: // TODO: a (fedcba9 by Alice)\n" +
+				"  b.go:7: // TODO: d (fedcba9 by Alice)\n" +
+				"\n" +
+				"* Bob\n" +
+				"  a.go:9: // FIXME: b (in run, 0123456 by Bob)\n" +
+				"\n" +
+				"* (unknown author)\n" +
+				"  b.go:2: // HACK: c\n" +
+				"  b.go:11: // NOTE: e (abcdef0)\n" +
+				"\n",
+		},
+		{
+			name:    "GroupBySymbol",
+			groupBy: types.GroupBySymbol,
+			want: "* a.go (top level)\n" +
+				"   5: // TODO: a (fedcba9 by Alice)\n" +
+				"\n" +
+				"* a.go run\n" +
+				"   9: // FIXME: b (0123456 by Bob)\n" +
+				"\n" +
+				"* b.go (top level)\n" +
+				"   2: // HACK: c\n" +
+				"   7: // TODO: d (fedcba9 by Alice)\n" +
+				"  11: // NOTE: e (abcdef0)\n" +
+				"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureOutput(t, func() { PrintTODOs(todos, tt.groupBy) })
+			if got != tt.want {
+				t.Errorf("output mismatch\n--- want ---\n%s\n--- got ---\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrintTODOsWithContext(t *testing.T) {
+	todos := []types.TODO{
+		{
+			Filename: "a.go", Line: 9, Comment: "// TODO: a", Type: "TODO",
+			Context: []types.ContextLine{
+				{Line: 8, Text: "func a() {"},
+				{Line: 9, Text: "\t// TODO: a", Added: true},
+				{Line: 10, Text: "}"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		groupBy types.GroupBy
+		want    string
+	}{
+		{
+			name:    "GroupByNone",
+			groupBy: types.GroupByNone,
+			want: "* a.go:9\n  // TODO: a\n" +
+				"   8   func a() {\n" +
+				"   9 + \t// TODO: a\n" +
+				"  10   }\n\n",
+		},
+		{
+			name:    "GroupByFile",
+			groupBy: types.GroupByFile,
+			want: "* a.go\n" +
+				"  9: // TODO: a\n" +
+				"      8   func a() {\n" +
+				"      9 + \t// TODO: a\n" +
+				"     10   }\n" +
+				"\n",
+		},
+		{
+			name:    "GroupByType",
+			groupBy: types.GroupByType,
+			want: "[TODO]\n" +
+				"* a.go:9\n  // TODO: a\n" +
+				"   8   func a() {\n" +
+				"   9 + \t// TODO: a\n" +
+				"  10   }\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := captureOutput(t, func() { PrintTODOs(todos, tt.groupBy) })
+			if got != tt.want {
+				t.Errorf("output mismatch\n--- want ---\n%s\n--- got ---\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestPrintFileNames(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -111,6 +319,21 @@ func TestPrintFileNames(t *testing.T) {
 	}
 }
 
+func TestPrintPRFileNames(t *testing.T) {
+	prs := []types.PullRequest{{Repository: "o/r", Number: 9}, {Repository: "o/other", Number: 10}, {Repository: "o/r", Number: 11}}
+	todos := []types.TODO{
+		{Filename: "b.go", Line: 1, PR: &prs[0]},
+		{Filename: "a.go", Line: 2, PR: &prs[0]},
+		{Filename: "b.go", Line: 3, PR: &prs[0]},
+		{Filename: "a.go", Line: 1, PR: &prs[1]},
+	}
+	want := "o/r#9 a.go\no/r#9 b.go\no/other#10 a.go\n"
+	got := captureOutput(t, func() { PrintPRFileNames(prs, todos) })
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
 func TestPrintCount(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -129,3 +352,57 @@ func TestPrintCount(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintStack(t *testing.T) {
+	stack := []types.PullRequest{
+		{Number: 1, Title: "Add client", BaseRefName: "main", HeadRefName: "client"},
+		{Number: 2, Title: "Add retries", BaseRefName: "client", HeadRefName: "retries"},
+		{Number: 3, Title: "Add docs", BaseRefName: "retries", HeadRefName: "docs"},
+	}
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", PR: &stack[0]},
+		{Filename: "b.go", Line: 20, Comment: "// FIXME: b", Type: "FIXME", PR: &stack[2]},
+		{Filename: "a.go", Line: 9, Comment: "// HACK: c", Type: "HACK", PR: &stack[0]},
+	}
+
+	want := "#1 Add client main ← client\n" +
+		"  2 TODO-style comment(s)\n\n" +
+		"* a.go\n" +
+		"  5: // TODO: a\n" +
+		"  9: // HACK: c\n" +
+		"\n" +
+		"#2 Add retries client ← retries\n" +
+		"  No TODO-style comments\n\n" +
+		"#3 Add docs retries ← docs\n" +
+		"  1 TODO-style comment(s)\n\n" +
+		"* b.go\n" +
+		"  20: // FIXME: b\n" +
+		"\n"
+	got := captureOutput(t, func() { PrintStack(stack, todos, types.GroupByFile) })
+	if got != want {
+		t.Errorf("output mismatch\n--- want ---\n%s\n--- got ---\n%s", want, got)
+	}
+}
+
+func TestPrintPRs(t *testing.T) {
+	prs := []types.PullRequest{
+		{Repository: "o/r", Number: 1, Title: "Add client", URL: "https://github.com/o/r/pull/1"},
+		{Repository: "o/r", Number: 2, Title: "Add retries", URL: "https://github.com/o/r/pull/2"},
+		{Repository: "x/y", Number: 1, Title: "Add docs", URL: "https://github.com/x/y/pull/1"},
+	}
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", PR: &prs[0]},
+		{Filename: "README.md", Line: 3, Comment: "<!-- TODO: b -->", Type: "TODO", PR: &prs[2]},
+	}
+
+	want := "o/r#1 Add client https://github.com/o/r/pull/1\n" +
+		"  1 TODO-style comment(s)\n\n" +
+		"* a.go:5\n  // TODO: a\n\n" +
+		"x/y#1 Add docs https://github.com/x/y/pull/1\n" +
+		"  1 TODO-style comment(s)\n\n" +
+		"* README.md:3\n  <!-- TODO: b -->\n\n"
+	got := captureOutput(t, func() { PrintPRs(prs, todos, types.GroupByNone) })
+	if got != want {
+		t.Errorf("output mismatch\n--- want ---\n%s\n--- got ---\n%s", want, got)
+	}
+}
@@ -0,0 +1,72 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/Suree33/gh-pr-todo/internal/todotype"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+	"github.com/fatih/color"
+)
+
+// sweepSeverities orders the severities of a sweep report, most severe first.
+var sweepSeverities = []todotype.Severity{todotype.SeverityError, todotype.SeverityWarning, todotype.SeverityNotice}
+
+// PrintSweep prints a sweep report repository by repository, listing the
+// TODOs of each under their severity, most severe first, and ends with the
+// totals by severity. Repositories without TODOs are only counted.
+func PrintSweep(report types.SweepReport) {
+	total := make(map[string]int)
+	prs, swept := 0, 0
+	for _, repo := range report.Repositories {
+		if repo.Error != "" {
+			fmt.Fprintf(color.Output, "%s %s\n\n", Bold(repo.Repository), Red("could not be swept: "+repo.Error))
+			continue
+		}
+		swept++
+		prs += len(repo.PRs)
+		for severity, n := range repo.Counts {
+			total[severity] += n
+		}
+		if len(repo.TODOs) == 0 {
+			continue
+		}
+		fmt.Fprintf(color.Output, "%s\n", Bold(repo.Repository))
+		fmt.Fprintf(color.Output, "  %d TODO-style comment(s) in %d open PR(s): %s\n\n", len(repo.TODOs), len(repo.PRs), severityCounts(repo.Counts))
+		for _, severity := range sweepSeverities {
+			if repo.Counts[string(severity)] == 0 {
+				continue
+			}
+			fmt.Fprintf(color.Output, "* %s\n", severity)
+			for _, f := range repo.TODOs {
+				if f.Severity != string(severity) {
+					continue
+				}
+				fmt.Fprintf(color.Output, "  %s %s: %s%s\n", Blue(fmt.Sprintf("#%d", f.PR.Number)), Blue(f.Filename+":"+strconv.Itoa(f.Line)), f.Comment, detailSuffix(f.TODO))
+			}
+			fmt.Fprintln(color.Output)
+		}
+	}
+	fmt.Fprintf(color.Output, "%s\n", Bold(fmt.Sprintf("Swept %d repositories with %d open PR(s): %s", swept, prs, severityCounts(total))))
+}
+
+// severityCounts renders counts by severity as "1 error, 0 warning, 2 notice".
+func severityCounts(counts map[string]int) string {
+	s := ""
+	for i, severity := range sweepSeverities {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf("%d %s", counts[string(severity)], severity)
+	}
+	return s
+}
+
+// WriteSweepJSON writes a sweep report to w as indented JSON.
+func WriteSweepJSON(w io.Writer, report types.SweepReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
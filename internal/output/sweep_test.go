@@ -0,0 +1,76 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func sweepReport() types.SweepReport {
+	api := []types.PullRequest{{Repository: "acme/api", Number: 1}, {Repository: "acme/api", Number: 2}}
+	return types.SweepReport{Org: "acme", Repositories: []types.RepositoryReport{
+		{
+			Repository: "acme/api",
+			PRs:        api,
+			TODOs: []types.Finding{
+				{TODO: types.TODO{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", PR: &api[0]}, Severity: "notice"},
+				{TODO: types.TODO{Filename: "b.go", Line: 9, Comment: "// FIXME: b", Type: "FIXME", PR: &api[1]}, Severity: "error"},
+			},
+			Counts: map[string]int{"notice": 1, "error": 1},
+		},
+		{Repository: "acme/docs", PRs: []types.PullRequest{{Repository: "acme/docs", Number: 3}}, TODOs: []types.Finding{}, Counts: map[string]int{}},
+		{Repository: "acme/gone", PRs: []types.PullRequest{}, TODOs: []types.Finding{}, Counts: map[string]int{}, Error: "not found"},
+	}}
+}
+
+func TestPrintSweep(t *testing.T) {
+	want := "acme/api\n" +
+		"  2 TODO-style comment(s) in 2 open PR(s): 1 error, 0 warning, 1 notice\n\n" +
+		"* error\n  #2 b.go:9: // FIXME: b\n\n" +
+		"* notice\n  #1 a.go:5: // TODO: a\n\n" +
+		"acme/gone could not be swept: not found\n\n" +
+		"Swept 2 repositories with 3 open PR(s): 1 error, 0 warning, 1 notice\n"
+	got := captureOutput(t, func() { PrintSweep(sweepReport()) })
+	if got != want {
+		t.Errorf("output mismatch\n--- want ---\n%s\n--- got ---\n%s", want, got)
+	}
+}
+
+func TestWriteSweepJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSweepJSON(&buf, sweepReport()); err != nil {
+		t.Fatalf("WriteSweepJSON() error = %v", err)
+	}
+	var got struct {
+		Org          string `json:"org"`
+		Repositories []struct {
+			Repository string         `json:"repository"`
+			Counts     map[string]int `json:"counts"`
+			TODOs      []struct {
+				Filename string `json:"filename"`
+				Severity string `json:"severity"`
+				PR       struct {
+					Number int `json:"number"`
+				} `json:"pr"`
+			} `json:"todos"`
+			Error string `json:"error"`
+		} `json:"repositories"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("export is not JSON: %v\n%s", err, buf.String())
+	}
+	if got.Org != "acme" || len(got.Repositories) != 3 {
+		t.Fatalf("export = %+v, expected 3 repositories of acme", got)
+	}
+	api := got.Repositories[0]
+	if !reflect.DeepEqual(api.Counts, map[string]int{"notice": 1, "error": 1}) || len(api.TODOs) != 2 ||
+		api.TODOs[1].Filename != "b.go" || api.TODOs[1].Severity != "error" || api.TODOs[1].PR.Number != 2 {
+		t.Errorf("acme/api = %+v, expected its TODOs with severities and PRs", api)
+	}
+	if got.Repositories[2].Error != "not found" {
+		t.Errorf("acme/gone error = %q, expected %q", got.Repositories[2].Error, "not found")
+	}
+}
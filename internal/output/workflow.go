@@ -22,12 +22,51 @@ func PrintWorkflowCommands(todos []types.TODO, policy todotype.Policy) {
 			workflowCommandFor(todo.Type, policy),
 			escapeWorkflowProperty(todo.Filename),
 			todo.Line,
-			escapeWorkflowProperty(todo.Type),
-			escapeWorkflowMessage(todo.Comment),
+			escapeWorkflowProperty(workflowTitleFor(todo)),
+			escapeWorkflowMessage(workflowMessageFor(todo)),
 		)
 	}
 }
 
+// workflowTitleFor returns the annotation title for a TODO: its type,
+// followed by the enclosing symbol, notebook cell, and introducing PR and
+// commit if known.
+func workflowTitleFor(todo types.TODO) string {
+	title := todo.Type
+	if todo.Symbol != "" {
+		title += " in " + todo.Symbol
+	}
+	if todo.Notebook != nil {
+		title += fmt.Sprintf(" (cell %d, line %d)", todo.Notebook.Cell, todo.Notebook.Line)
+	}
+	if todo.PR != nil {
+		title += fmt.Sprintf(" (#%d)", todo.PR.Number)
+	}
+	if todo.Commit != nil {
+		title += " (" + commitLabel(todo.Commit) + ")"
+	}
+	return title
+}
+
+// workflowMessageFor returns the annotation message for a TODO: the comment,
+// followed by its surrounding source lines when context was requested.
+func workflowMessageFor(todo types.TODO) string {
+	if len(todo.Context) == 0 {
+		return todo.Comment
+	}
+	var b strings.Builder
+	b.WriteString(todo.Comment)
+	b.WriteString("\n")
+	for _, cl := range todo.Context {
+		marker := " "
+		if cl.Added {
+			marker = "+"
+		}
+		fmt.Fprintf(&b, "\n%d %s %s", cl.Line, marker, cl.Text)
+	}
+	return b.String()
+}
+
 func workflowCommandFor(todoType string, policy todotype.Policy) string {
 	switch policy.SeverityFor(todoType) {
 	case todotype.SeverityWarning:
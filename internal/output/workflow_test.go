@@ -162,3 +162,87 @@ func TestWorkflowCommandFor(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintWorkflowCommandsIncludesContext(t *testing.T) {
+	todos := []types.TODO{
+		{
+			Filename: "a.go",
+			Line:     5,
+			Comment:  "// TODO: a",
+			Type:     "TODO",
+			Context: []types.ContextLine{
+				{Line: 4, Text: "func a() {"},
+				{Line: 5, Text: "\t// TODO: a", Added: true},
+			},
+		},
+	}
+
+	want := "::notice file=a.go,line=5,title=TODO::// TODO: a%0A%0A4   func a() {%0A5 + \t// TODO: a\n"
+
+	got := captureOutput(t, func() {
+		PrintWorkflowCommands(todos, todotype.DefaultPolicy())
+	})
+	if got != want {
+		t.Fatalf("PrintWorkflowCommands() with context mismatch\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestPrintWorkflowCommandsIncludesSymbol(t *testing.T) {
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", Symbol: "(*Client).FetchDiff"},
+	}
+
+	want := "::notice file=a.go,line=5,title=TODO in (*Client).FetchDiff::// TODO: a\n"
+
+	got := captureOutput(t, func() {
+		PrintWorkflowCommands(todos, todotype.DefaultPolicy())
+	})
+	if got != want {
+		t.Fatalf("PrintWorkflowCommands() with symbol mismatch\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestPrintWorkflowCommandsIncludesNotebookCell(t *testing.T) {
+	todos := []types.TODO{
+		{Filename: "nb.ipynb", Line: 31, Comment: "# TODO: a", Type: "TODO", Notebook: &types.NotebookLocation{Cell: 3, Line: 2}},
+	}
+
+	want := "::notice file=nb.ipynb,line=31,title=TODO (cell 3%2C line 2)::# TODO: a\n"
+
+	got := captureOutput(t, func() {
+		PrintWorkflowCommands(todos, todotype.DefaultPolicy())
+	})
+	if got != want {
+		t.Fatalf("PrintWorkflowCommands() with notebook cell mismatch\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestPrintWorkflowCommandsIncludesPR(t *testing.T) {
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", Symbol: "run", PR: &types.PullRequest{Number: 12}},
+	}
+
+	want := "::notice file=a.go,line=5,title=TODO in run (#12)::// TODO: a\n"
+
+	got := captureOutput(t, func() {
+		PrintWorkflowCommands(todos, todotype.DefaultPolicy())
+	})
+	if got != want {
+		t.Fatalf("PrintWorkflowCommands() with PR mismatch\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestPrintWorkflowCommandsIncludesCommit(t *testing.T) {
+	todos := []types.TODO{
+		{Filename: "a.go", Line: 5, Comment: "// TODO: a", Type: "TODO", Commit: &types.Commit{SHA: "0123456789abcdef", Subject: "Add a", Author: "Alice"}},
+	}
+
+	want := "::notice file=a.go,line=5,title=TODO (0123456 by Alice)::// TODO: a\n"
+
+	got := captureOutput(t, func() {
+		PrintWorkflowCommands(todos, todotype.DefaultPolicy())
+	})
+	if got != want {
+		t.Fatalf("PrintWorkflowCommands() with commit mismatch\ngot:  %q\nwant: %q", got, want)
+	}
+}
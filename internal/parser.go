@@ -2,13 +2,16 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
 	"path"
 	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 
+	"github.com/Suree33/gh-pr-todo/internal/charset"
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/internal/language"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 	"github.com/Suree33/gh-pr-todo/pkg/types"
 	"github.com/odvcencio/gotreesitter"
@@ -16,8 +19,6 @@ import (
 )
 
 var (
-	hunkRegex = regexp.MustCompile(`^@@\s+\-\d+(?:,\d+)?\s+\+(\d+)(?:,\d+)?\s+@@`)
-
 	commentNodeTypes = map[string]bool{
 		"comment":               true,
 		"line_comment":          true,
@@ -26,10 +27,60 @@ var (
 	}
 )
 
+// commentLeaders are the comment openings matched in files of any language.
+var commentLeaders = []string{"//", "#", "<!--", ";", "/*"}
+
 // compileTODORegex builds a case-insensitive regex that matches TODO-style
 // comments for the given marker types. Marker names are escaped for literal
 // matching. The result is sorted for deterministic regex construction.
 func compileTODORegex(types []string) *regexp.Regexp {
+	return compileLeaderRegex(types, commentLeaders)
+}
+
+// compileLeaderRegex is like compileTODORegex, but matches comments that
+// start with any of leaders.
+func compileLeaderRegex(types, leaders []string) *regexp.Regexp {
+	markers, ok := markerAlternation(types)
+	if !ok {
+		return regexp.MustCompile(`a^`)
+	}
+	quoted := make([]string, len(leaders))
+	for i, l := range leaders {
+		quoted[i] = regexp.QuoteMeta(l)
+	}
+	pattern := fmt.Sprintf(`(?i)((?:%s)\s*(%s)(?:$|[^[:alnum:]_].*))`, strings.Join(quoted, "|"), markers)
+	return regexp.MustCompile(pattern)
+}
+
+// compileCommentBodyRegex builds a case-insensitive regex that matches a TODO
+// marker at the start of comment text whose delimiters have already been
+// removed, such as a continuation line of a multi-line comment.
+func compileCommentBodyRegex(types []string) *regexp.Regexp {
+	markers, ok := markerAlternation(types)
+	if !ok {
+		return regexp.MustCompile(`a^`)
+	}
+	pattern := fmt.Sprintf(`(?i)^\s*((%s)(?:$|[^[:alnum:]_].*))`, markers)
+	return regexp.MustCompile(pattern)
+}
+
+// compileParagraphRegex builds a case-sensitive regex that matches plain-text
+// lines such as "TODO: write this", optionally inside a list item or block
+// quote. Markers must be uppercase and followed by a colon (with an optional
+// "(owner)") so that ordinary prose like "Note that..." is not matched.
+func compileParagraphRegex(types []string) *regexp.Regexp {
+	markers, ok := markerAlternation(types)
+	if !ok {
+		return regexp.MustCompile(`a^`)
+	}
+	pattern := fmt.Sprintf(`^\s*(?:>\s*)*(?:[-*+]\s+|\d+[.)]\s+)?((%s)(?:\([^)]*\))?:.*)`, markers)
+	return regexp.MustCompile(pattern)
+}
+
+// markerAlternation returns the regex alternation of the given marker types,
+// longest first so that overlapping names match greedily. Returns false if
+// there are no non-empty types.
+func markerAlternation(types []string) (string, bool) {
 	sorted := make([]string, 0, len(types))
 	for _, t := range types {
 		if strings.TrimSpace(t) != "" {
@@ -37,7 +88,7 @@ func compileTODORegex(types []string) *regexp.Regexp {
 		}
 	}
 	if len(sorted) == 0 {
-		return regexp.MustCompile(`a^`)
+		return "", false
 	}
 	sort.Slice(sorted, func(i, j int) bool {
 		if len(sorted[i]) != len(sorted[j]) {
@@ -50,9 +101,87 @@ func compileTODORegex(types []string) *regexp.Regexp {
 	for i, t := range sorted {
 		quoted[i] = regexp.QuoteMeta(t)
 	}
+	return strings.Join(quoted, "|"), true
+}
 
-	pattern := fmt.Sprintf(`(?i)((?://|#|<!--|;|/\*)\s*(%s)(?:$|[^[:alnum:]_].*))`, strings.Join(quoted, "|"))
-	return regexp.MustCompile(pattern)
+// markerPatterns holds the regexes used to find TODO markers in different
+// kinds of text.
+type markerPatterns struct {
+	// comment matches a line containing a comment prefix and a marker.
+	comment *regexp.Regexp
+	// languageComments holds, by file extension, the comment regex of each
+	// language whose comments can start with other text than comment's,
+	// such as "--" in Lua.
+	languageComments map[string]*regexp.Regexp
+	// body matches comment text with its delimiters removed.
+	body *regexp.Regexp
+	// paragraph matches a plain-text "MARKER:" line.
+	paragraph *regexp.Regexp
+	// docstring matches documentation string text, or is nil when
+	// documentation strings are not searched.
+	docstring *regexp.Regexp
+	// candidate matches marker text anywhere in a line, regardless of case.
+	// A file without a candidate on an added line has no TODOs to report.
+	candidate *regexp.Regexp
+}
+
+func newMarkerPatterns(types []string) markerPatterns {
+	return markerPatterns{
+		comment:          compileTODORegex(types),
+		languageComments: compileLanguageRegexes(types),
+		body:             compileCommentBodyRegex(types),
+		paragraph:        compileParagraphRegex(types),
+		candidate:        compileCandidateRegex(types),
+	}
+}
+
+// compileLanguageRegexes builds the comment regex of each language whose
+// comment syntax has a leader that compileTODORegex does not match.
+func compileLanguageRegexes(types []string) map[string]*regexp.Regexp {
+	res := make(map[string]*regexp.Regexp)
+	for _, l := range language.All() {
+		var extra []string
+		for _, leader := range l.CommentLeaders() {
+			if !endsWithCommentLeader(leader) {
+				extra = append(extra, leader)
+			}
+		}
+		if len(extra) > 0 {
+			res[l.Extension] = compileLeaderRegex(types, append(extra, commentLeaders...))
+		}
+	}
+	return res
+}
+
+// endsWithCommentLeader reports whether leader ends with one of
+// commentLeaders, as "{/*" does, so that compileTODORegex already matches a
+// marker after it.
+func endsWithCommentLeader(leader string) bool {
+	for _, l := range commentLeaders {
+		if strings.HasSuffix(leader, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// commentFor returns the comment regex for filename, which also matches the
+// comment syntax of the file's language.
+func (p markerPatterns) commentFor(filename string) *regexp.Regexp {
+	if re, ok := p.languageComments[strings.ToLower(path.Ext(filename))]; ok {
+		return re
+	}
+	return p.comment
+}
+
+// compileCandidateRegex builds a case-insensitive regex that matches the
+// name of any of the given marker types, wherever it appears.
+func compileCandidateRegex(types []string) *regexp.Regexp {
+	markers, ok := markerAlternation(types)
+	if !ok {
+		return regexp.MustCompile(`a^`)
+	}
+	return regexp.MustCompile(`(?i)` + markers)
 }
 
 // lineRange represents a 1-based inclusive line range.
@@ -78,103 +207,75 @@ func ParseDiff(diffOutput string) []types.TODO {
 func ParseDiffWithTypes(diffOutput string, todoTypes []string) []types.TODO {
 	re := compileTODORegex(todoTypes)
 	var todos []types.TODO
-	lines := strings.Split(diffOutput, "\n")
-
-	var currentFile string
-	var lineNumber int
-
-	for _, line := range lines {
-		if after, ok := strings.CutPrefix(line, "+++ b/"); ok {
-			currentFile = path.Clean(after)
-		} else if strings.HasPrefix(line, "@@") {
-			if matches := hunkRegex.FindStringSubmatch(line); len(matches) > 1 {
-				if startLine, err := strconv.Atoi(matches[1]); err == nil {
-					lineNumber = startLine - 1
-				}
-			}
-		} else if after, ok := strings.CutPrefix(line, "+"); ok {
-			lineNumber++
-			if matches := re.FindStringSubmatch(after); len(matches) > 2 {
-				todos = append(todos, types.TODO{
-					Filename: currentFile,
-					Line:     lineNumber,
-					Comment:  strings.TrimSpace(matches[1]),
-					Type:     strings.ToUpper(matches[2]),
-				})
-			}
-		} else if strings.HasPrefix(line, " ") {
-			lineNumber++
+	for _, f := range diff.Parse(diffOutput) {
+		if !f.IsDeleted {
+			todos = append(todos, addedLineTODOs(f, re)...)
 		}
 	}
+	return todos
+}
 
+// addedLineTODOs matches re against the added lines of a file's diff.
+func addedLineTODOs(f diff.File, re *regexp.Regexp) []types.TODO {
+	var todos []types.TODO
+	for _, line := range f.AddedLines() {
+		if matches := re.FindStringSubmatch(line.Text); len(matches) > 2 {
+			todos = append(todos, types.TODO{
+				Filename: f.NewPath,
+				Line:     line.NewLine,
+				Comment:  strings.TrimSpace(matches[1]),
+				Type:     strings.ToUpper(matches[2]),
+			})
+		}
+	}
 	return todos
 }
 
+// ExtractChangedPaths returns the paths of files with text changes in the
+// diff, excluding deleted files, in diff order.
 func ExtractChangedPaths(diffOutput string) []string {
 	var paths []string
 	seen := make(map[string]struct{})
-	var inHunk bool
-	for _, line := range strings.Split(diffOutput, "\n") {
-		if strings.HasPrefix(line, "diff --git ") {
-			inHunk = false
-		} else if strings.HasPrefix(line, "@@") {
-			inHunk = true
-		}
-		if after, ok := strings.CutPrefix(line, "+++ b/"); ok && !inHunk {
-			p := path.Clean(after)
-			if _, exists := seen[p]; !exists {
-				seen[p] = struct{}{}
-				paths = append(paths, p)
-			}
+	for _, fc := range extractFileChanges(diffOutput) {
+		if _, exists := seen[fc.path]; !exists {
+			seen[fc.path] = struct{}{}
+			paths = append(paths, fc.path)
 		}
 	}
 	return paths
 }
 
-// extractFileChanges parses unified diff output and returns per-file added line ranges.
+// extractFileChanges parses unified diff output and returns per-file added
+// line ranges for files with text changes. Deleted, binary, and unchanged
+// (for example, pure rename or mode-only) files are skipped.
 func extractFileChanges(diffOutput string) []fileChange {
 	var changes []fileChange
-	lines := strings.Split(diffOutput, "\n")
-
-	var current *fileChange
-	var lineNumber int
-	var inHunk bool
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "diff --git ") {
-			inHunk = false
-		} else if after, ok := strings.CutPrefix(line, "+++ b/"); ok && !inHunk {
-			if current != nil {
-				changes = append(changes, *current)
-			}
-			current = &fileChange{path: path.Clean(after)}
-		} else if strings.HasPrefix(line, "@@") {
-			if matches := hunkRegex.FindStringSubmatch(line); len(matches) > 1 {
-				if startLine, err := strconv.Atoi(matches[1]); err == nil {
-					lineNumber = startLine - 1
-					inHunk = true
-				}
-			}
-		} else if inHunk && strings.HasPrefix(line, "+") {
-			lineNumber++
-			if current != nil {
-				n := len(current.addedRanges)
-				if n > 0 && current.addedRanges[n-1].end == lineNumber-1 {
-					current.addedRanges[n-1].end = lineNumber
-				} else {
-					current.addedRanges = append(current.addedRanges, lineRange{start: lineNumber, end: lineNumber})
-				}
-			}
-		} else if inHunk && strings.HasPrefix(line, " ") {
-			lineNumber++
+	for _, f := range diff.Parse(diffOutput) {
+		if fc, ok := newFileChange(f); ok {
+			changes = append(changes, fc)
 		}
 	}
-	if current != nil {
-		changes = append(changes, *current)
-	}
 	return changes
 }
 
+// newFileChange returns the added line ranges of a file's diff. It returns
+// false for deleted, binary, and unchanged files.
+func newFileChange(f diff.File) (fileChange, bool) {
+	if f.IsDeleted || len(f.Hunks) == 0 {
+		return fileChange{}, false
+	}
+	fc := fileChange{path: f.NewPath}
+	for _, line := range f.AddedLines() {
+		n := len(fc.addedRanges)
+		if n > 0 && fc.addedRanges[n-1].end == line.NewLine-1 {
+			fc.addedRanges[n-1].end = line.NewLine
+		} else {
+			fc.addedRanges = append(fc.addedRanges, lineRange{start: line.NewLine, end: line.NewLine})
+		}
+	}
+	return fc, true
+}
+
 // ParseDiffWithContents extracts TODO comments using Tree-sitter for supported
 // languages, falling back to regex for unsupported files.
 // Uses the default built-in TODO marker types.
@@ -186,48 +287,148 @@ func ParseDiffWithContents(diffOutput string, files map[string][]byte) []types.T
 // supported languages, falling back to regex for unsupported files.
 // todoTypes specifies which marker types to detect.
 func ParseDiffWithContentsAndTypes(diffOutput string, files map[string][]byte, todoTypes []string) []types.TODO {
-	re := compileTODORegex(todoTypes)
-	changes := extractFileChanges(diffOutput)
-	var todos []types.TODO
-	var missingFiles []string
+	return ParseDiffWithOptions(diffOutput, files, ParseOptions{Types: todoTypes})
+}
 
-	for _, fc := range changes {
-		if len(fc.addedRanges) == 0 {
-			continue
-		}
+// ParseOptions controls how ParseDiffWithOptions detects TODO comments.
+type ParseOptions struct {
+	// Types lists the TODO marker types to detect.
+	Types []string
+	// MarkdownParagraphs also reports plain "TODO:" lines in documentation
+	// files, outside of comments and code blocks.
+	MarkdownParagraphs bool
+	// Docstrings also reports "TODO:" lines in documentation strings, such
+	// as Python docstrings and Rust #[doc] attributes.
+	Docstrings bool
+	// Languages assigns languages to files by path pattern, taking
+	// precedence over detection by file name and content.
+	Languages []language.Mapping
+	// Encodings assigns encodings to files by path pattern. It applies to
+	// the diff text of files whose contents are unavailable; file contents
+	// must already be UTF-8 (see charset.DecodeFiles).
+	Encodings []charset.Mapping
+	// Jobs is the number of files parsed concurrently. Zero or less uses
+	// runtime.GOMAXPROCS.
+	Jobs int
+	// ContextLines is the number of lines of file contents attached before
+	// and after each TODO. Zero disables context.
+	ContextLines int
+	// MaxFileSize is the largest diff, in bytes, parsed for a single file.
+	// Larger files are skipped, and files whose contents are larger are
+	// parsed from their hunks. Zero means no limit.
+	MaxFileSize int64
+	// MaxMemory bounds the bytes of diff text held by files waiting to be
+	// parsed. Reading the diff pauses when it is reached. Zero means no
+	// limit.
+	MaxMemory int64
+	// Prefetch, if set, is called with the path of each file that will be
+	// asked of the source, in diff order and while earlier files are still
+	// being parsed, so that the source can fetch several files at once.
+	Prefetch func(path string)
+	// TextDiff, if set, is called for each file the diff reports as binary
+	// whose path suggests source text, such as a UTF-16 C# file, and
+	// returns its hunks computed from its contents, converted to UTF-8, or
+	// false if it is binary after all. The file is then parsed like any
+	// other, with its head contents from the source.
+	TextDiff func(f diff.File) ([]diff.Hunk, bool)
+}
 
-		content, ok := files[fc.path]
-		if !ok {
-			missingFiles = append(missingFiles, fc.path)
-			continue
-		}
+// ParseDiffWithOptions extracts TODO comments using Tree-sitter for supported
+// languages and format-specific rules for notebooks, documentation,
+// templates, and markup with embedded scripts and styles, falling back to
+// regex for unsupported files. Source files missing from files are parsed
+// from the text of their hunks. See ParseDiffStream for how files are
+// scheduled; files skipped for their size are left out.
+func ParseDiffWithOptions(diffOutput string, files map[string][]byte, opts ParseOptions) []types.TODO {
+	todos, _, _ := ParseDiffStream(strings.NewReader(diffOutput), MapSource(files), opts)
+	return todos
+}
 
-		if found := parseTODOsWithTreeSitter(fc, content, re); found != nil {
-			todos = append(todos, found...)
-		} else {
-			todos = append(todos, parseTODOsWithRegex(fc, content, re)...)
+// parseFileTODOs extracts TODO comments from the contents of a changed file,
+// choosing the parser from its language or format.
+func parseFileTODOs(fc fileChange, content []byte, patterns markerPatterns, opts ParseOptions) []types.TODO {
+	if l, ok := detectLanguage(fc.path, content, opts.Languages); ok {
+		return parseRegionTODOs(fc, fileRegion(regionFilename(l.Name), content), patterns)
+	}
+
+	if isNotebook(fc.path) {
+		if found := parseNotebookTODOs(fc, content, patterns, opts); found != nil {
+			return found
 		}
 	}
 
-	if len(missingFiles) > 0 {
-		missing := make(map[string]bool, len(missingFiles))
-		for _, f := range missingFiles {
-			missing[f] = true
+	if isDocumentation(fc.path) {
+		return parseDocumentationTODOs(fc, content, patterns, opts)
+	}
+
+	if delimiters, inner, ok := templateSyntax(fc.path); ok {
+		return parseTemplateTODOs(fc, content, delimiters, inner, patterns)
+	}
+
+	if isEmbeddedDocument(fc.path) {
+		return parseEmbeddedTODOs(fc, content, patterns)
+	}
+
+	if found := parseTODOsWithTreeSitter(fc, content, patterns); found != nil {
+		return found
+	}
+	return parseTODOsWithRegex(fc, content, patterns.commentFor(fc.path))
+}
+
+// hasCandidateMarker reports whether any added line of content contains
+// marker text matched by re.
+func hasCandidateMarker(fc fileChange, content []byte, re *regexp.Regexp) bool {
+	lines := bytes.Split(content, []byte("\n"))
+	for _, r := range fc.addedRanges {
+		for line := r.start; line <= r.end && line <= len(lines); line++ {
+			if re.Match(lines[line-1]) {
+				return true
+			}
 		}
-		for _, t := range ParseDiffWithTypes(diffOutput, todoTypes) {
-			if missing[t.Filename] {
-				todos = append(todos, t)
+	}
+	return false
+}
+
+// regionsHaveCandidateMarker reports whether any region line that maps to an
+// added file line contains marker text matched by re.
+func regionsHaveCandidateMarker(fc fileChange, regions []sourceRegion, re *regexp.Regexp) bool {
+	for _, r := range regions {
+		for i, line := range r.lines {
+			if lineInRanges(r.fileLines[i], fc.addedRanges) && re.MatchString(line) {
+				return true
 			}
 		}
 	}
+	return false
+}
 
-	return todos
+// HasGrammar reports whether Tree-sitter parses files with the given
+// extension, such as ".py".
+func HasGrammar(ext string) bool {
+	return grammars.DetectLanguage("file"+ext) != nil
+}
+
+// detectLanguage returns the language a file is parsed as when its name
+// alone does not decide it: the language of a configured path pattern, or,
+// for files no grammar recognizes by name, the language named by a shebang
+// or editor modeline in content.
+func detectLanguage(filename string, content []byte, mappings []language.Mapping) (language.Language, bool) {
+	if l, ok := language.Match(mappings, filename); ok {
+		return l, true
+	}
+	if needsFileContents(filename) || grammars.DetectLanguage(filename) != nil {
+		return language.Language{}, false
+	}
+	if _, ok := language.ByExtension(path.Ext(filename)); ok {
+		return language.Language{}, false
+	}
+	return language.Detect(content)
 }
 
 // parseTODOsWithTreeSitter uses Tree-sitter to parse the file and extract TODO comments
-// from comment nodes that intersect with added lines. Returns nil if the language
-// is unsupported or parsing fails.
-func parseTODOsWithTreeSitter(fc fileChange, content []byte, re *regexp.Regexp) []types.TODO {
+// from comment nodes, and documentation strings when enabled, that intersect with
+// added lines. Returns nil if the language is unsupported or parsing fails.
+func parseTODOsWithTreeSitter(fc fileChange, content []byte, p markerPatterns) []types.TODO {
 	entry := grammars.DetectLanguage(fc.path)
 	if entry == nil {
 		return nil
@@ -245,22 +446,35 @@ func parseTODOsWithTreeSitter(fc fileChange, content []byte, re *regexp.Regexp)
 	}
 
 	todos := make([]types.TODO, 0)
-	walkTree(root, bt, fc, &todos, re)
+	walkTree(root, bt, fc, nil, &todos, p)
+	if p.docstring != nil {
+		// Docstrings are found when their parent node is visited, which may
+		// be before comments on earlier lines.
+		sort.SliceStable(todos, func(i, j int) bool { return todos[i].Line < todos[j].Line })
+	}
 	return todos
 }
 
 // walkTree recursively walks the AST and collects TODO comments from comment nodes.
-func walkTree(node *gotreesitter.Node, bt *gotreesitter.BoundTree, fc fileChange, todos *[]types.TODO, re *regexp.Regexp) {
+// symbols holds the names of the scopes enclosing node, outermost first.
+func walkTree(node *gotreesitter.Node, bt *gotreesitter.BoundTree, fc fileChange, symbols []string, todos *[]types.TODO, p markerPatterns) {
 	nodeType := bt.NodeType(node)
 	if isCommentNode(nodeType) {
-		extractTODOsFromComment(node, bt, fc, todos, re)
+		extractTODOsFromComment(node, bt, fc, strings.Join(symbols, symbolSeparator), todos, p.commentFor(fc.path))
 		return
 	}
 
+	if symbol := symbolFor(node, bt, fc.path); symbol != "" {
+		symbols = append(symbols[:len(symbols):len(symbols)], symbol)
+	}
+	if p.docstring != nil {
+		extractTODOsFromDocstrings(node, bt, fc, strings.Join(symbols, symbolSeparator), todos, p.docstring)
+	}
+
 	for i := 0; i < node.ChildCount(); i++ {
 		child := node.Child(i)
 		if child != nil {
-			walkTree(child, bt, fc, todos, re)
+			walkTree(child, bt, fc, symbols, todos, p)
 		}
 	}
 }
@@ -274,8 +488,8 @@ func isCommentNode(nodeType string) bool {
 }
 
 // extractTODOsFromComment checks if a comment node intersects with added lines
-// and extracts TODO markers from it.
-func extractTODOsFromComment(node *gotreesitter.Node, bt *gotreesitter.BoundTree, fc fileChange, todos *[]types.TODO, re *regexp.Regexp) {
+// and extracts TODO markers from it, tagging each with the enclosing symbol.
+func extractTODOsFromComment(node *gotreesitter.Node, bt *gotreesitter.BoundTree, fc fileChange, symbol string, todos *[]types.TODO, re *regexp.Regexp) {
 	// Tree-sitter rows are 0-based, our line ranges are 1-based
 	nodeStartLine := int(node.StartPoint().Row) + 1
 
@@ -294,6 +508,7 @@ func extractTODOsFromComment(node *gotreesitter.Node, bt *gotreesitter.BoundTree
 				Line:     fileLine,
 				Comment:  strings.TrimSpace(matches[1]),
 				Type:     strings.ToUpper(matches[2]),
+				Symbol:   symbol,
 			})
 		}
 	}
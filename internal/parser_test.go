@@ -1,10 +1,13 @@
 package internal
 
 import (
+	"fmt"
 	"reflect"
-	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/Suree33/gh-pr-todo/internal/language"
+	"github.com/Suree33/gh-pr-todo/internal/todotype"
 	"github.com/Suree33/gh-pr-todo/pkg/types"
 )
 
@@ -52,31 +55,31 @@ index 1234567..abcdefg 100644
 			expected: []types.TODO{
 				{
 					Filename: "multi.go",
-					Line:     2,
+					Line:     3,
 					Comment:  "// TODO: Go style comment",
 					Type:     "TODO",
 				},
 				{
 					Filename: "multi.go",
-					Line:     3,
+					Line:     4,
 					Comment:  "# TODO: Shell style comment",
 					Type:     "TODO",
 				},
 				{
 					Filename: "multi.go",
-					Line:     4,
+					Line:     5,
 					Comment:  "<!-- TODO: HTML style comment -->",
 					Type:     "TODO",
 				},
 				{
 					Filename: "multi.go",
-					Line:     5,
+					Line:     6,
 					Comment:  "; TODO: Assembly style comment",
 					Type:     "TODO",
 				},
 				{
 					Filename: "multi.go",
-					Line:     6,
+					Line:     7,
 					Comment:  "/* TODO: C style comment",
 					Type:     "TODO",
 				},
@@ -101,37 +104,37 @@ index 1234567..abcdefg 100644
 			expected: []types.TODO{
 				{
 					Filename: "types.go",
-					Line:     2,
+					Line:     3,
 					Comment:  "// TODO: implement feature",
 					Type:     "TODO",
 				},
 				{
 					Filename: "types.go",
-					Line:     3,
+					Line:     4,
 					Comment:  "// FIXME: fix this bug",
 					Type:     "FIXME",
 				},
 				{
 					Filename: "types.go",
-					Line:     4,
+					Line:     5,
 					Comment:  "// HACK: temporary workaround",
 					Type:     "HACK",
 				},
 				{
 					Filename: "types.go",
-					Line:     5,
+					Line:     6,
 					Comment:  "// NOTE: important information",
 					Type:     "NOTE",
 				},
 				{
 					Filename: "types.go",
-					Line:     6,
+					Line:     7,
 					Comment:  "// XXX: dangerous code",
 					Type:     "XXX",
 				},
 				{
 					Filename: "types.go",
-					Line:     7,
+					Line:     8,
 					Comment:  "// BUG: known issue",
 					Type:     "BUG",
 				},
@@ -154,25 +157,25 @@ index 1234567..abcdefg 100644
 			expected: []types.TODO{
 				{
 					Filename: "case.go",
-					Line:     2,
+					Line:     3,
 					Comment:  "// todo: lowercase",
 					Type:     "TODO",
 				},
 				{
 					Filename: "case.go",
-					Line:     3,
+					Line:     4,
 					Comment:  "// TODO: uppercase",
 					Type:     "TODO",
 				},
 				{
 					Filename: "case.go",
-					Line:     4,
+					Line:     5,
 					Comment:  "// Todo: mixed case",
 					Type:     "TODO",
 				},
 				{
 					Filename: "case.go",
-					Line:     5,
+					Line:     6,
 					Comment:  "// tOdO: weird case",
 					Type:     "TODO",
 				},
@@ -193,13 +196,13 @@ index 1234567..abcdefg 100644
 			expected: []types.TODO{
 				{
 					Filename: "nocolon.go",
-					Line:     2,
+					Line:     3,
 					Comment:  "// TODO implement this",
 					Type:     "TODO",
 				},
 				{
 					Filename: "nocolon.go",
-					Line:     3,
+					Line:     4,
 					Comment:  "// FIXME repair the bug",
 					Type:     "FIXME",
 				},
@@ -320,6 +323,35 @@ index 1234567..abcdefg 100644
  func main() {}`,
 			expected: nil,
 		},
+		{
+			name: "Renamed file with quoted path and deleted file",
+			input: `diff --git a/old.go "b/caf\303\251.go"
+similarity index 80%
+rename from old.go
+rename to "caf\303\251.go"
+index 1234567..abcdefg 100644
+--- a/old.go
++++ "b/caf\303\251.go"
+@@ -1,2 +1,3 @@
+ package main
++// TODO: rename helpers
+ func main() {}
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+index 1234567..0000000
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-// TODO: removed with the file`,
+			expected: []types.TODO{
+				{
+					Filename: "café.go",
+					Line:     2,
+					Comment:  "// TODO: rename helpers",
+					Type:     "TODO",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -460,7 +492,7 @@ index 7890123..defghij 100644
 			},
 		},
 		{
-			name: "HTML comment detected in Markdown is ignored (not real code comment)",
+			name: "Comment inside fenced code block in Markdown is detected",
 			diff: `diff --git a/README.md b/README.md
 index 1234567..abcdefg 100644
 --- a/README.md
@@ -476,7 +508,9 @@ index 1234567..abcdefg 100644
 			files: map[string][]byte{
 				"README.md": []byte("# Title\n\n```go\n// TODO: inside code block\n```\n\nSome text\n"),
 			},
-			expected: nil,
+			expected: []types.TODO{
+				{Filename: "README.md", Line: 4, Comment: "// TODO: inside code block", Type: "TODO"},
+			},
 		},
 		{
 			name:     "Empty diff with contents",
@@ -485,7 +519,7 @@ index 1234567..abcdefg 100644
 			expected: nil,
 		},
 		{
-			name: "File in diff but not in contents (falls back to diff regex)",
+			name: "File in diff but not in contents (parsed from hunk text)",
 			diff: `diff --git a/missing.go b/missing.go
 index 1234567..abcdefg 100644
 --- a/missing.go
@@ -512,6 +546,35 @@ index 1234567..abcdefg 100644
 	}
 }
 
+func TestParseDiffWithContentsCommentSyntax(t *testing.T) {
+	for _, l := range language.All() {
+		for _, syntax := range l.Comments {
+			t.Run(l.Name+" "+syntax, func(t *testing.T) {
+				delimiters := strings.Fields(syntax)
+				content := delimiters[0] + " TODO: find me"
+				if len(delimiters) > 1 {
+					// A Ruby "=end" must start a line of its own.
+					separator := " "
+					if strings.HasPrefix(delimiters[1], "=") {
+						separator = "\n"
+					}
+					content += separator + delimiters[1]
+				}
+				content += "\n"
+				filename := "file" + l.Extension
+				lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+				diff := fmt.Sprintf("diff --git a/%s b/%s\nnew file mode 100644\n--- /dev/null\n+++ b/%s\n@@ -0,0 +1,%d @@\n+%s",
+					filename, filename, filename, len(lines), strings.Join(lines, "\n+"))
+
+				result := ParseDiffWithContents(diff, map[string][]byte{filename: []byte(content)})
+				if len(result) != 1 || result[0].Type != "TODO" || result[0].Line != 1 {
+					t.Errorf("ParseDiffWithContents(%q) = %+v, expected one TODO on line 1", content, result)
+				}
+			})
+		}
+	}
+}
+
 func TestExtractFileChanges_DeletedFileAfterModified(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -654,50 +717,6 @@ func TestTodoRegex(t *testing.T) {
 	}
 }
 
-func TestHunkRegex(t *testing.T) {
-	tests := []struct {
-		name      string
-		input     string
-		matches   bool
-		startLine int
-	}{
-		{"Basic hunk", "@@ -1,3 +1,4 @@", true, 1},
-		{"Single line", "@@ -5 +5,2 @@", true, 5},
-		{"Large line numbers", "@@ -100,50 +150,75 @@", true, 150},
-		{"With context", "@@ -10,5 +15,8 @@ func test() {", true, 15},
-		{"Invalid hunk", "not a hunk header", false, 0},
-		{"Partial hunk", "@@ -1,3", false, 0},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			matches := hunkRegex.FindStringSubmatch(tt.input)
-			if tt.matches {
-				if len(matches) < 2 {
-					t.Errorf("Expected regex to match %q, but it didn't", tt.input)
-					return
-				}
-				startLine := 0
-				if len(matches[1]) > 0 {
-					var err error
-					startLine, err = strconv.Atoi(matches[1])
-					if err != nil {
-						t.Errorf("Failed to parse start line %q: %v", matches[1], err)
-						return
-					}
-				}
-				if startLine != tt.startLine {
-					t.Errorf("Expected start line %d, got %d", tt.startLine, startLine)
-				}
-			} else {
-				if len(matches) > 0 {
-					t.Errorf("Expected regex not to match %q, but it matched", tt.input)
-				}
-			}
-		})
-	}
-}
-
 func TestCompileTODORegexTypeBoundaries(t *testing.T) {
 	t.Run("prefers longer type names", func(t *testing.T) {
 		re := compileTODORegex([]string{"TODO", "TODO2"})
@@ -749,3 +768,91 @@ func TestParseDiffWithTypesDetectsCustomType(t *testing.T) {
 		t.Fatalf("ParseDiffWithTypes() = %+v, expected %+v", result, expected)
 	}
 }
+
+func TestDetectLanguage(t *testing.T) {
+	mappings := []language.Mapping{{Pattern: "Jenkinsfile", Language: "groovy"}}
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+		want     string
+	}{
+		{name: "configured pattern", filename: "ci/Jenkinsfile", content: "pipeline {}\n", want: "groovy"},
+		{name: "shebang in extensionless script", filename: "bin/deploy", content: "#!/usr/bin/env python3\n", want: "python"},
+		{name: "modeline in unknown extension", filename: "build.conf", content: "# vim: ft=yaml\n", want: "yaml"},
+		{name: "known extension ignores shebang", filename: "run.py", content: "#!/usr/bin/env ruby\n", want: ""},
+		{name: "template keeps its own rules", filename: "page.html.erb", content: "# vim: ft=ruby\n", want: ""},
+		{name: "no hints", filename: "LICENSE", content: "MIT\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := detectLanguage(tt.filename, []byte(tt.content), mappings)
+			if got.Name != tt.want || ok != (tt.want != "") {
+				t.Errorf("detectLanguage() = %q, %v, want %q", got.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffWithOptionsLanguages(t *testing.T) {
+	content := "#!/usr/bin/env bash\nset -e\n# TODO: retry on failure\n"
+	result := ParseDiffWithOptions(newFileDiff("bin/deploy", content), map[string][]byte{"bin/deploy": []byte(content)}, ParseOptions{
+		Types: todotype.DefaultTypes(),
+	})
+	expected := []types.TODO{
+		{Filename: "bin/deploy", Line: 3, Comment: "# TODO: retry on failure", Type: "TODO"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("ParseDiffWithOptions() = %+v, expected %+v", result, expected)
+	}
+}
+
+func TestHasCandidateMarker(t *testing.T) {
+	re := compileCandidateRegex(todotype.DefaultTypes())
+	content := []byte("package main\n// todo later\nvar x = 1\n")
+	tests := []struct {
+		name   string
+		ranges []lineRange
+		want   bool
+	}{
+		{name: "marker on added line", ranges: []lineRange{{start: 2, end: 2}}, want: true},
+		{name: "marker outside added lines", ranges: []lineRange{{start: 1, end: 1}, {start: 3, end: 3}}, want: false},
+		{name: "range past end of file", ranges: []lineRange{{start: 3, end: 10}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc := fileChange{path: "main.go", addedRanges: tt.ranges}
+			if got := hasCandidateMarker(fc, content, re); got != tt.want {
+				t.Errorf("hasCandidateMarker() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffWithOptionsJobs(t *testing.T) {
+	var diffOutput strings.Builder
+	files := make(map[string][]byte)
+	var expected []types.TODO
+	for i := range 50 {
+		name := fmt.Sprintf("pkg%02d/file.txt", i)
+		content := "first line\n"
+		if i%3 != 0 {
+			content += fmt.Sprintf("# TODO: item %d\n", i)
+			expected = append(expected, types.TODO{Filename: name, Line: 2, Comment: fmt.Sprintf("# TODO: item %d", i), Type: "TODO"})
+		}
+		diffOutput.WriteString(newFileDiff(name, content))
+		files[name] = []byte(content)
+	}
+
+	for _, jobs := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("jobs=%d", jobs), func(t *testing.T) {
+			result := ParseDiffWithOptions(diffOutput.String(), files, ParseOptions{
+				Types: todotype.DefaultTypes(),
+				Jobs:  jobs,
+			})
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("ParseDiffWithOptions() = %+v, expected %+v", result, expected)
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package policyresolve
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"sort"
 	"strings"
 
+	"github.com/Suree33/gh-pr-todo/internal/charset"
 	"github.com/Suree33/gh-pr-todo/internal/config"
+	"github.com/Suree33/gh-pr-todo/internal/language"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 )
 
@@ -51,13 +54,37 @@ func ResolveTarget(repo, pr string) Target {
 	return Target{Repo: repo, PR: parts[3], UseRemote: true}
 }
 
+// Settings is the resolved configuration for a run.
+type Settings struct {
+	Policy todotype.Policy
+	// MarkdownParagraphs reports plain "TODO:" lines in documentation files.
+	MarkdownParagraphs bool
+	// Docstrings reports "TODO:" lines in documentation strings.
+	Docstrings bool
+	// Languages assigns languages to files by path pattern.
+	Languages []language.Mapping
+	// Encodings assigns encodings to files by path pattern.
+	Encodings []charset.Mapping
+}
+
 // Resolve loads configuration from the appropriate source and applies config
 // and CLI overrides on top of the default TODO policy.
-func Resolve(fetcher config.RemoteConfigFetcher, opts Options) (todotype.Policy, error) {
-	cfg, err := loadConfig(fetcher, opts)
+func Resolve(ctx context.Context, fetcher config.RemoteConfigFetcher, opts Options) (todotype.Policy, error) {
+	settings, err := ResolveSettings(ctx, fetcher, opts)
 	if err != nil {
 		return todotype.Policy{}, err
 	}
+	return settings.Policy, nil
+}
+
+// ResolveSettings loads configuration from the appropriate source and returns
+// the TODO policy, with config and CLI overrides applied, together with the
+// remaining configuration settings.
+func ResolveSettings(ctx context.Context, fetcher config.RemoteConfigFetcher, opts Options) (Settings, error) {
+	cfg, err := loadConfig(ctx, fetcher, opts)
+	if err != nil {
+		return Settings{}, err
+	}
 
 	policy := todotype.DefaultPolicy()
 	if len(cfg.Severities) > 0 {
@@ -83,16 +110,22 @@ func Resolve(fetcher config.RemoteConfigFetcher, opts Options) (todotype.Policy,
 		policy = policy.WithIgnoredTypes(ignored)
 	}
 
-	return policy, nil
+	return Settings{
+		Policy:             policy,
+		MarkdownParagraphs: cfg.MarkdownParagraphs,
+		Docstrings:         cfg.Docstrings,
+		Languages:          cfg.Languages,
+		Encodings:          cfg.Encodings,
+	}, nil
 }
 
-func loadConfig(fetcher config.RemoteConfigFetcher, opts Options) (config.Config, error) {
+func loadConfig(ctx context.Context, fetcher config.RemoteConfigFetcher, opts Options) (config.Config, error) {
 	if opts.Target.UseRemote {
 		if fetcher == nil {
 			return config.Config{}, fmt.Errorf("remote config fetcher is required")
 		}
 
-		remoteCfg, err := config.LoadRemote(fetcher, opts.Target.Repo, opts.Target.PR)
+		remoteCfg, err := config.LoadRemote(ctx, fetcher, opts.Target.Repo, opts.Target.PR)
 		if err != nil {
 			return config.Config{}, err
 		}
@@ -1,6 +1,7 @@
 package policyresolve
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -16,11 +17,11 @@ type fakeFetcher struct {
 	fileContents map[string]map[string][]byte
 }
 
-func (f *fakeFetcher) FetchRemoteConfigRefs(repo, pr string) (config.RemoteConfigRefs, error) {
+func (f *fakeFetcher) FetchRemoteConfigRefs(_ context.Context, repo, pr string) (config.RemoteConfigRefs, error) {
 	return f.refs, nil
 }
 
-func (f *fakeFetcher) FetchFileAtRef(repo, path, ref string) ([]byte, bool, error) {
+func (f *fakeFetcher) FetchFileAtRef(_ context.Context, repo, path, ref string) ([]byte, bool, error) {
 	key := repo + ":" + ref
 	if contents, ok := f.fileContents[key]; ok {
 		if data, ok := contents[path]; ok {
@@ -82,7 +83,7 @@ func TestResolve(t *testing.T) {
 			t.Fatalf("WriteFile() error: %v", err)
 		}
 
-		policy, err := Resolve(nil, Options{Target: ResolveTarget("", ""), CWD: repoRoot})
+		policy, err := Resolve(context.Background(), nil, Options{Target: ResolveTarget("", ""), CWD: repoRoot})
 		if err != nil {
 			t.Fatalf("Resolve() unexpected error: %v", err)
 		}
@@ -98,7 +99,7 @@ func TestResolve(t *testing.T) {
 	})
 
 	t.Run("remote config uses PR head precedence", func(t *testing.T) {
-		policy, err := Resolve(&fakeFetcher{
+		policy, err := Resolve(context.Background(), &fakeFetcher{
 			refs: config.RemoteConfigRefs{
 				DefaultBranchRef: "main",
 				DefaultRepo:      "owner/repo",
@@ -143,7 +144,7 @@ func TestResolve(t *testing.T) {
 			t.Fatalf("WriteFile() error: %v", err)
 		}
 
-		policy, err := Resolve(&fakeFetcher{
+		policy, err := Resolve(context.Background(), &fakeFetcher{
 			refs: config.RemoteConfigRefs{
 				DefaultBranchRef: "main",
 				DefaultRepo:      "owner/repo",
@@ -170,7 +171,7 @@ func TestResolve(t *testing.T) {
 			t.Fatalf("WriteFile() error: %v", err)
 		}
 
-		policy, err := Resolve(nil, Options{
+		policy, err := Resolve(context.Background(), nil, Options{
 			Target: ResolveTarget("", ""),
 			CWD:    repoRoot,
 			CLISeverities: map[string]todotype.Severity{
@@ -198,7 +199,7 @@ func TestResolve(t *testing.T) {
 			t.Fatalf("WriteFile() error: %v", err)
 		}
 
-		policy, err := Resolve(nil, Options{
+		policy, err := Resolve(context.Background(), nil, Options{
 			Target: ResolveTarget("", ""),
 			CWD:    repoRoot,
 			CLISeverities: map[string]todotype.Severity{
@@ -220,3 +221,44 @@ func TestResolve(t *testing.T) {
 		}
 	})
 }
+
+func TestResolveSettings(t *testing.T) {
+	t.Run("markdown paragraphs come from config", func(t *testing.T) {
+		repoRoot := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(repoRoot, ".git"), 0755); err != nil {
+			t.Fatalf("MkdirAll() error: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(repoRoot, ".gh-pr-todo.yml"), []byte("markdown:\n  paragraphs: true\ndocstrings: true\nignore:\n  - NOTE\n"), 0644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+
+		settings, err := ResolveSettings(context.Background(), nil, Options{Target: ResolveTarget("", ""), CWD: repoRoot})
+		if err != nil {
+			t.Fatalf("ResolveSettings() unexpected error: %v", err)
+		}
+		if !settings.MarkdownParagraphs {
+			t.Fatal("MarkdownParagraphs = false, want true")
+		}
+		if !settings.Docstrings {
+			t.Fatal("Docstrings = false, want true")
+		}
+		if !settings.Policy.IsIgnored("NOTE") {
+			t.Fatal("NOTE should be ignored")
+		}
+	})
+
+	t.Run("markdown paragraphs are disabled without config", func(t *testing.T) {
+		repoRoot := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(repoRoot, ".git"), 0755); err != nil {
+			t.Fatalf("MkdirAll() error: %v", err)
+		}
+
+		settings, err := ResolveSettings(context.Background(), nil, Options{Target: ResolveTarget("", ""), CWD: repoRoot, UserConfigDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("ResolveSettings() unexpected error: %v", err)
+		}
+		if settings.MarkdownParagraphs {
+			t.Fatal("MarkdownParagraphs = true, want false")
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/language"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// sourceRegion is a block of text embedded in a file, such as a notebook
+// cell, that is parsed on its own and mapped back to file lines.
+type sourceRegion struct {
+	// filename is a synthetic file name whose extension selects the grammar.
+	filename string
+	lines    []string
+	// fileLines maps each region line (by index) to its 1-based line in the
+	// enclosing file.
+	fileLines []int
+	// notebookCell is the 1-based notebook cell the region came from, or 0.
+	notebookCell int
+}
+
+// regionFilename returns a synthetic file name for a region written in the
+// given language. Unknown languages get a name no grammar matches, so the
+// region falls back to regex matching.
+func regionFilename(name string) string {
+	if l, ok := language.Lookup(name); ok {
+		return "region" + l.Extension
+	}
+	return "region.txt"
+}
+
+// fileRegion returns a region covering all of content, with each line mapped
+// to itself.
+func fileRegion(filename string, content []byte) sourceRegion {
+	lines := splitContentLines(content)
+	fileLines := make([]int, len(lines))
+	for i := range lines {
+		fileLines[i] = i + 1
+	}
+	return sourceRegion{filename: filename, lines: lines, fileLines: fileLines}
+}
+
+// parseRegionTODOs extracts TODO comments from a region on lines that map to
+// added file lines, using Tree-sitter when the region's language is supported
+// and regex matching otherwise. Regions that are themselves documents with
+// embedded languages, such as HTML, are split further. Reported lines refer
+// to the enclosing file.
+func parseRegionTODOs(fc fileChange, r sourceRegion, p markerPatterns) []types.TODO {
+	if isEmbeddedDocument(r.filename) {
+		return parseEmbeddedRegionTODOs(fc, r, p)
+	}
+
+	local := regionChange(fc, r)
+	if len(local.addedRanges) == 0 {
+		return nil
+	}
+
+	content := []byte(strings.Join(r.lines, "\n") + "\n")
+	found := parseTODOsWithTreeSitter(local, content, p)
+	if found == nil {
+		found = parseTODOsWithRegex(local, content, p.commentFor(r.filename))
+	}
+	return r.remap(fc.path, found)
+}
+
+// regionChange returns a fileChange in region-local line numbers, marking the
+// region lines whose file lines were added.
+func regionChange(fc fileChange, r sourceRegion) fileChange {
+	local := fileChange{path: r.filename}
+	for i, fileLine := range r.fileLines {
+		if !lineInRanges(fileLine, fc.addedRanges) {
+			continue
+		}
+		line := i + 1
+		n := len(local.addedRanges)
+		if n > 0 && local.addedRanges[n-1].end == line-1 {
+			local.addedRanges[n-1].end = line
+		} else {
+			local.addedRanges = append(local.addedRanges, lineRange{start: line, end: line})
+		}
+	}
+	return local
+}
+
+// remap rewrites TODOs found in region-local coordinates to refer to the
+// enclosing file.
+func (r sourceRegion) remap(filename string, todos []types.TODO) []types.TODO {
+	for i := range todos {
+		localLine := todos[i].Line
+		todos[i].Filename = filename
+		if localLine >= 1 && localLine <= len(r.fileLines) {
+			todos[i].Line = r.fileLines[localLine-1]
+		}
+		if r.notebookCell > 0 {
+			todos[i].Notebook = &types.NotebookLocation{Cell: r.notebookCell, Line: localLine}
+		}
+	}
+	return todos
+}
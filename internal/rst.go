@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/language"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+var (
+	// rstExplicitRegex matches an explicit markup start (".. ") and captures
+	// its indentation and the text after it.
+	rstExplicitRegex = regexp.MustCompile(`^(\s*)\.\.(?:\s+(.*))?$`)
+	// rstDirectiveRegex matches a directive such as "code-block:: python" and
+	// captures its name and argument.
+	rstDirectiveRegex = regexp.MustCompile(`^([\w][\w.+:-]*?)::(?:\s+(.*))?$`)
+	// rstOptionRegex matches a directive option line such as ":linenos:".
+	rstOptionRegex = regexp.MustCompile(`^:[^:]+:`)
+)
+
+// rstCodeDirectives are the directives whose content is source code in the
+// language given by the directive argument.
+var rstCodeDirectives = map[string]bool{
+	"code":       true,
+	"code-block": true,
+	"sourcecode": true,
+}
+
+// rstBlock is an explicit markup block: the ".. " line and the lines
+// indented under it.
+type rstBlock struct {
+	// text is the text after ".. " on the first line.
+	text string
+	// start is the region-local line of the ".. " line.
+	start int
+	// indent is the indentation of the ".. " line.
+	indent int
+	// body holds the indented lines after the first line.
+	body []string
+}
+
+// parseRSTRegionTODOs extracts TODO comments from a reStructuredText region
+// on lines that map to added file lines. Comments (".. " not followed by a
+// directive, target, or substitution) may continue on indented lines; code
+// directives with a known language are parsed with that language's grammar;
+// ".. todo::" directives are reported as TODOs.
+func parseRSTRegionTODOs(fc fileChange, r sourceRegion, p markerPatterns, opts ParseOptions) []types.TODO {
+	local := regionChange(fc, r)
+	if len(local.addedRanges) == 0 {
+		return nil
+	}
+
+	var todos []types.TODO
+	for i := 0; i < len(r.lines); i++ {
+		lineNumber := i + 1
+		m := rstExplicitRegex.FindStringSubmatch(r.lines[i])
+		if m == nil {
+			if opts.MarkdownParagraphs && lineInRanges(lineNumber, local.addedRanges) {
+				todos = append(todos, paragraphTODOs(r.lines[i], lineNumber, p.paragraph)...)
+			}
+			continue
+		}
+
+		block := rstBlock{text: m[2], start: lineNumber, indent: len(m[1])}
+		for i+1 < len(r.lines) && rstIndentedUnder(r.lines[i+1], block.indent) {
+			i++
+			block.body = append(block.body, r.lines[i])
+		}
+		// Trailing blank lines belong to the surrounding document.
+		for len(block.body) > 0 && strings.TrimSpace(block.body[len(block.body)-1]) == "" {
+			block.body = block.body[:len(block.body)-1]
+		}
+		todos = append(todos, block.todos(local, p)...)
+	}
+
+	return r.remap(fc.path, todos)
+}
+
+// rstIndentedUnder reports whether line continues an explicit markup block
+// indented by indent: it is blank or indented further.
+func rstIndentedUnder(line string, indent int) bool {
+	if strings.TrimSpace(line) == "" {
+		return true
+	}
+	return len(line)-len(strings.TrimLeft(line, " \t")) > indent
+}
+
+// todos returns the TODOs in an explicit markup block.
+func (b rstBlock) todos(local fileChange, p markerPatterns) []types.TODO {
+	if m := rstDirectiveRegex.FindStringSubmatch(b.text); m != nil {
+		name := strings.ToLower(m[1])
+		switch {
+		case rstCodeDirectives[name]:
+			return b.codeTODOs(local, fenceLanguage(m[2]), p)
+		case name == "todo" && p.body.MatchString(name):
+			if !lineInRanges(b.start, local.addedRanges) {
+				return nil
+			}
+			return []types.TODO{{
+				Line:    b.start,
+				Comment: strings.TrimSpace(".. " + b.text),
+				Type:    "TODO",
+			}}
+		}
+		return nil
+	}
+
+	// Hyperlink targets, footnotes, citations, and substitution definitions
+	// are not comments.
+	if strings.HasPrefix(b.text, "_") || strings.HasPrefix(b.text, "[") || strings.HasPrefix(b.text, "|") {
+		return nil
+	}
+
+	var todos []types.TODO
+	if lineInRanges(b.start, local.addedRanges) {
+		if matches := p.body.FindStringSubmatch(b.text); len(matches) > 2 {
+			todos = append(todos, types.TODO{
+				Line:    b.start,
+				Comment: strings.TrimSpace(".. " + b.text),
+				Type:    strings.ToUpper(matches[2]),
+			})
+		}
+	}
+	for j, line := range b.body {
+		lineNumber := b.start + j + 1
+		if !lineInRanges(lineNumber, local.addedRanges) {
+			continue
+		}
+		if matches := p.body.FindStringSubmatch(line); len(matches) > 2 {
+			todos = append(todos, types.TODO{
+				Line:    lineNumber,
+				Comment: strings.TrimSpace(matches[1]),
+				Type:    strings.ToUpper(matches[2]),
+			})
+		}
+	}
+	return todos
+}
+
+// codeTODOs parses the content of a code directive, after any option lines,
+// with the grammar of lang.
+func (b rstBlock) codeTODOs(local fileChange, lang string, p markerPatterns) []types.TODO {
+	if _, ok := language.Lookup(lang); !ok {
+		return nil
+	}
+
+	j := 0
+	for j < len(b.body) && rstOptionRegex.MatchString(strings.TrimSpace(b.body[j])) {
+		j++
+	}
+	r := sourceRegion{filename: regionFilename(lang)}
+	indent := -1
+	for ; j < len(b.body); j++ {
+		line := b.body[j]
+		if strings.TrimSpace(line) != "" {
+			lead := len(line) - len(strings.TrimLeft(line, " \t"))
+			if indent < 0 || lead < indent {
+				indent = lead
+			}
+		}
+		r.lines = append(r.lines, line)
+		r.fileLines = append(r.fileLines, b.start+j+1)
+	}
+	for k, line := range r.lines {
+		if len(line) >= indent && indent > 0 {
+			r.lines[k] = line[indent:]
+		} else {
+			r.lines[k] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return parseRegionTODOs(local, r, p)
+}
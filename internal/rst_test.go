@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+const sampleRST = `Title
+=====
+
+.. TODO: expand this section
+
+..
+   FIXME: multi-line comment
+   continues here
+
+.. todo:: Document the cache flags.
+
+.. _target: https://example.com
+.. note:: NOTE: admonitions are not comments
+
+.. code-block:: python
+   :linenos:
+
+   x = 1
+   # HACK: temporary
+
+.. code-block:: text
+
+   # TODO: not code
+
+TODO: paragraph marker
+`
+
+func TestParseDiffWithOptionsRST(t *testing.T) {
+	defaultTypes := []string{"TODO", "FIXME", "HACK", "NOTE", "XXX", "BUG"}
+
+	tests := []struct {
+		name       string
+		types      []string
+		paragraphs bool
+		expected   []types.TODO
+	}{
+		{
+			name:  "comments, todo directives, and code blocks",
+			types: defaultTypes,
+			expected: []types.TODO{
+				{Filename: "docs/index.rst", Line: 4, Comment: ".. TODO: expand this section", Type: "TODO"},
+				{Filename: "docs/index.rst", Line: 7, Comment: "FIXME: multi-line comment", Type: "FIXME"},
+				{Filename: "docs/index.rst", Line: 10, Comment: ".. todo:: Document the cache flags.", Type: "TODO"},
+				{Filename: "docs/index.rst", Line: 19, Comment: "# HACK: temporary", Type: "HACK"},
+			},
+		},
+		{
+			name:       "paragraphs reported when enabled",
+			types:      defaultTypes,
+			paragraphs: true,
+			expected: []types.TODO{
+				{Filename: "docs/index.rst", Line: 4, Comment: ".. TODO: expand this section", Type: "TODO"},
+				{Filename: "docs/index.rst", Line: 7, Comment: "FIXME: multi-line comment", Type: "FIXME"},
+				{Filename: "docs/index.rst", Line: 10, Comment: ".. todo:: Document the cache flags.", Type: "TODO"},
+				{Filename: "docs/index.rst", Line: 19, Comment: "# HACK: temporary", Type: "HACK"},
+				{Filename: "docs/index.rst", Line: 25, Comment: "TODO: paragraph marker", Type: "TODO"},
+			},
+		},
+		{
+			name:  "todo directive follows the configured types",
+			types: []string{"FIXME"},
+			expected: []types.TODO{
+				{Filename: "docs/index.rst", Line: 7, Comment: "FIXME: multi-line comment", Type: "FIXME"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{"docs/index.rst": []byte(sampleRST)}
+			result := ParseDiffWithOptions(newFileDiff("docs/index.rst", sampleRST), files, ParseOptions{
+				Types:              tt.types,
+				MarkdownParagraphs: tt.paragraphs,
+			})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithOptions() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/internal/language"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+	"github.com/odvcencio/gotreesitter/grammars"
+	"golang.org/x/sync/semaphore"
+)
+
+// FileSource returns the head contents of a changed file, already converted
+// to UTF-8. It returns false when the contents are unavailable. A FileSource
+// may be called from several goroutines at once.
+type FileSource func(path string) ([]byte, bool)
+
+// MapSource returns a FileSource serving the contents in files.
+func MapSource(files map[string][]byte) FileSource {
+	return func(path string) ([]byte, bool) {
+		content, ok := files[path]
+		return content, ok
+	}
+}
+
+// readAhead is the number of files read from the diff ahead of being
+// parsed, so that a source told of them by ParseOptions.Prefetch can fetch
+// their contents in batches.
+const readAhead = 100
+
+// pendingFile is a file read from the diff and waiting to be parsed.
+type pendingFile struct {
+	file diff.File
+	fc   fileChange
+	size int64
+}
+
+// ParseDiffStream extracts TODO comments like ParseDiffWithOptions, reading
+// the diff from r one file at a time and asking source for the contents of
+// each changed file as it is reached, so that neither the whole diff nor all
+// changed files are held in memory at once.
+//
+// Files are parsed on opts.Jobs goroutines, skipping those whose added lines
+// never mention a marker, and TODOs are returned in diff order. The paths of
+// files whose diff exceeds opts.MaxFileSize are returned as skipped, along
+// with the error that stopped reading r, if any; TODOs found before the
+// error are still returned.
+func ParseDiffStream(r io.Reader, source FileSource, opts ParseOptions) ([]types.TODO, []string, error) {
+	patterns := newMarkerPatterns(opts.Types)
+	if opts.Docstrings {
+		patterns.docstring = patterns.paragraph
+	}
+
+	var budget *semaphore.Weighted
+	if opts.MaxMemory > 0 {
+		budget = semaphore.NewWeighted(opts.MaxMemory)
+	}
+
+	var skipped []string
+	var readErr error
+	pending := make(chan pendingFile, readAhead)
+	go func() {
+		defer close(pending)
+		reader := diff.NewReader(r, diff.ReaderOptions{MaxFileSize: opts.MaxFileSize})
+		for {
+			f, err := reader.Next()
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+			if f.Oversized {
+				if !f.IsDeleted {
+					skipped = append(skipped, f.Path())
+				}
+				continue
+			}
+			if f.IsBinary && !f.IsDeleted && opts.TextDiff != nil && mayBeText(f.NewPath, opts) {
+				if hunks, ok := opts.TextDiff(f); ok {
+					f.Hunks, f.IsBinary = hunks, false
+				}
+			}
+			fc, ok := newFileChange(f)
+			if !ok || len(fc.addedRanges) == 0 {
+				continue
+			}
+
+			// A file larger than the whole budget waits for all of it.
+			size := diffSize(f)
+			if budget != nil {
+				size = min(size, opts.MaxMemory)
+				// Acquire only fails when its context is done.
+				_ = budget.Acquire(context.Background(), size)
+			}
+			if opts.Prefetch != nil {
+				opts.Prefetch(fc.path)
+			}
+			pending <- pendingFile{file: f, fc: fc, size: size}
+		}
+	}()
+
+	pool := newTaskPool(opts.Jobs)
+	for p := range pending {
+		pool.submit(func() []types.TODO {
+			if budget != nil {
+				defer budget.Release(p.size)
+			}
+			return parseChangedFile(p.file, p.fc, source, patterns, opts)
+		})
+	}
+
+	var todos []types.TODO
+	for _, found := range pool.wait() {
+		todos = append(todos, found...)
+	}
+	for i := range todos {
+		todos[i].Comment = strings.ToValidUTF8(todos[i].Comment, "\uFFFD")
+	}
+	return todos, skipped, readErr
+}
+
+// parseChangedFile extracts the TODO comments of one file in the diff, from
+// its contents when source has them and from the text of its hunks
+// otherwise.
+func parseChangedFile(f diff.File, fc fileChange, source FileSource, patterns markerPatterns, opts ParseOptions) []types.TODO {
+	content, ok := source(fc.path)
+	if ok && opts.MaxFileSize > 0 && int64(len(content)) > opts.MaxFileSize {
+		content, ok = nil, false
+	}
+	if ok {
+		if !hasCandidateMarker(fc, content, patterns.candidate) {
+			return nil
+		}
+		todos := parseFileTODOs(fc, content, patterns, opts)
+		if opts.ContextLines > 0 {
+			lines := splitContentLines(content)
+			for i := range todos {
+				todos[i].Context = contextLines(lines, todos[i].Line, opts.ContextLines, fc.addedRanges)
+			}
+		}
+		return todos
+	}
+
+	mapped, isMapped := language.Match(opts.Languages, fc.path)
+	if !isMapped && needsFileContents(fc.path) {
+		return addedLineTODOs(f, patterns.commentFor(f.NewPath))
+	}
+	regions := hunkRegions(f, opts.Encodings)
+	if isMapped {
+		for i := range regions {
+			regions[i].filename = regionFilename(mapped.Name)
+		}
+	}
+	if !regionsHaveCandidateMarker(fc, regions, patterns.candidate) {
+		return nil
+	}
+	return parseHunkTODOs(fc, regions, patterns)
+}
+
+// mayBeText reports whether a file the diff reports as binary may be source
+// text in an encoding git does not recognize, such as UTF-16, judging by its
+// name, so that images and archives are not fetched to find out.
+func mayBeText(filename string, opts ParseOptions) bool {
+	if _, ok := language.Match(opts.Languages, filename); ok {
+		return true
+	}
+	for _, m := range opts.Encodings {
+		if m.Matches(filename) {
+			return true
+		}
+	}
+	if needsFileContents(filename) || grammars.DetectLanguage(filename) != nil {
+		return true
+	}
+	_, ok := language.ByExtension(path.Ext(filename))
+	return ok
+}
+
+// diffSize returns the number of bytes of hunk text in a file's diff.
+func diffSize(f diff.File) int64 {
+	var n int64
+	for _, h := range f.Hunks {
+		for _, l := range h.Lines {
+			n += int64(len(l.Text)) + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/diff"
+	"github.com/Suree33/gh-pr-todo/internal/todotype"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestParseDiffStream(t *testing.T) {
+	small := "x = 1\n# TODO: small\n"
+	large := "# FIXME: large\n" + strings.Repeat("y = 2\n", 100)
+	diffOutput := newFileDiff("small.txt", small) + newFileDiff("large.txt", large) + newFileDiff("missing.txt", small)
+	files := map[string][]byte{
+		"small.txt": []byte(small),
+		"large.txt": []byte(large),
+	}
+
+	tests := []struct {
+		name        string
+		opts        ParseOptions
+		expected    []types.TODO
+		wantSkipped []string
+	}{
+		{
+			name: "no limits",
+			opts: ParseOptions{},
+			expected: []types.TODO{
+				{Filename: "small.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+				{Filename: "large.txt", Line: 1, Comment: "# FIXME: large", Type: "FIXME"},
+				{Filename: "missing.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+			},
+		},
+		{
+			name: "oversized diff is skipped",
+			opts: ParseOptions{MaxFileSize: 200},
+			expected: []types.TODO{
+				{Filename: "small.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+				{Filename: "missing.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+			},
+			wantSkipped: []string{"large.txt"},
+		},
+		{
+			name: "memory budget smaller than a file",
+			opts: ParseOptions{MaxMemory: 10, Jobs: 2},
+			expected: []types.TODO{
+				{Filename: "small.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+				{Filename: "large.txt", Line: 1, Comment: "# FIXME: large", Type: "FIXME"},
+				{Filename: "missing.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+			},
+		},
+		{
+			name: "context from file contents",
+			opts: ParseOptions{ContextLines: 1, MaxFileSize: 200},
+			expected: []types.TODO{
+				{Filename: "small.txt", Line: 2, Comment: "# TODO: small", Type: "TODO", Context: []types.ContextLine{
+					{Line: 1, Text: "x = 1", Added: true},
+					{Line: 2, Text: "# TODO: small", Added: true},
+				}},
+				{Filename: "missing.txt", Line: 2, Comment: "# TODO: small", Type: "TODO"},
+			},
+			wantSkipped: []string{"large.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Types = todotype.DefaultTypes()
+			todos, skipped, err := ParseDiffStream(strings.NewReader(diffOutput), MapSource(files), tt.opts)
+			if err != nil {
+				t.Fatalf("ParseDiffStream() error: %v", err)
+			}
+			if !reflect.DeepEqual(todos, tt.expected) {
+				t.Errorf("ParseDiffStream() = %+v, expected %+v", todos, tt.expected)
+			}
+			if !reflect.DeepEqual(skipped, tt.wantSkipped) {
+				t.Errorf("ParseDiffStream() skipped = %v, expected %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestParseDiffStreamReadError(t *testing.T) {
+	boom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader(newFileDiff("a.txt", "# TODO: first\n")), errorReader{boom})
+	todos, _, err := ParseDiffStream(r, MapSource(nil), ParseOptions{Types: todotype.DefaultTypes()})
+	if err != boom {
+		t.Fatalf("ParseDiffStream() error = %v, expected %v", err, boom)
+	}
+	expected := []types.TODO{{Filename: "a.txt", Line: 1, Comment: "# TODO: first", Type: "TODO"}}
+	if !reflect.DeepEqual(todos, expected) {
+		t.Errorf("ParseDiffStream() = %+v, expected %+v", todos, expected)
+	}
+}
+
+// errorReader fails every read with err.
+type errorReader struct{ err error }
+
+func (r errorReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestParseDiffStreamPrefetch(t *testing.T) {
+	diffOutput := newFileDiff("a.txt", "# TODO: a\n") + newFileDiff("b.txt", "no marker\n") + newFileDiff("c.txt", "# TODO: c\n")
+
+	// The source holds back a.txt until every file has been announced,
+	// which only happens if the diff is read ahead of parsing.
+	var mu sync.Mutex
+	var prefetched []string
+	allAnnounced := make(chan struct{})
+	source := func(path string) ([]byte, bool) {
+		if path == "a.txt" {
+			<-allAnnounced
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !slices.Contains(prefetched, path) {
+			t.Errorf("%s asked of the source before it was prefetched", path)
+		}
+		return nil, false
+	}
+	opts := ParseOptions{Types: todotype.DefaultTypes(), Jobs: 1, Prefetch: func(path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		prefetched = append(prefetched, path)
+		if len(prefetched) == 3 {
+			close(allAnnounced)
+		}
+	}}
+
+	todos, _, err := ParseDiffStream(strings.NewReader(diffOutput), source, opts)
+	if err != nil {
+		t.Fatalf("ParseDiffStream() error: %v", err)
+	}
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !reflect.DeepEqual(prefetched, want) {
+		t.Errorf("prefetched %v, expected %v", prefetched, want)
+	}
+	if len(todos) != 2 {
+		t.Errorf("ParseDiffStream() = %+v, expected 2 TODOs", todos)
+	}
+}
+
+func TestParseDiffStreamTextDiff(t *testing.T) {
+	diffOutput := "diff --git a/a.ps1 b/a.ps1\nBinary files a/a.ps1 and b/a.ps1 differ\n" +
+		"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n"
+	var asked []string
+	opts := ParseOptions{Types: todotype.DefaultTypes(), Jobs: 1, TextDiff: func(f diff.File) ([]diff.Hunk, bool) {
+		asked = append(asked, f.Path())
+		return diff.Compare([]byte("x = 1\n"), []byte("x = 1\n# TODO: utf16\n"), 3), true
+	}}
+
+	todos, _, err := ParseDiffStream(strings.NewReader(diffOutput), MapSource(nil), opts)
+	if err != nil {
+		t.Fatalf("ParseDiffStream() error: %v", err)
+	}
+	if want := []string{"a.ps1"}; !reflect.DeepEqual(asked, want) {
+		t.Errorf("asked to diff %v, expected %v", asked, want)
+	}
+	want := []types.TODO{{Filename: "a.ps1", Line: 2, Comment: "# TODO: utf16", Type: "TODO"}}
+	if !reflect.DeepEqual(todos, want) {
+		t.Errorf("ParseDiffStream() = %+v, expected %+v", todos, want)
+	}
+}
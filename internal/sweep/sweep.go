@@ -0,0 +1,147 @@
+// Package sweep checks the open PRs of many repositories, such as every
+// repository of an organization, into one report.
+package sweep
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/internal/config"
+	ghclient "github.com/Suree33/gh-pr-todo/internal/github"
+	"github.com/Suree33/gh-pr-todo/internal/policyresolve"
+	"github.com/Suree33/gh-pr-todo/internal/todotype"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// Fetcher is what a sweep needs of the GitHub client: finding the open PRs
+// of a repository, loading its remote config, and collecting TODOs.
+type Fetcher interface {
+	ghclient.PRFetcher
+	config.RemoteConfigFetcher
+	SearchPRs(ctx context.Context, repo, query string) ([]types.PullRequest, error)
+}
+
+// Options configures a sweep.
+type Options struct {
+	// Org is the organization swept, recorded in the report.
+	Org string
+	// UserConfigDir holds the global config, used for repositories
+	// without config of their own.
+	UserConfigDir string
+	// CLISeverities and CLIIgnored override every repository's config.
+	CLISeverities map[string]todotype.Severity
+	CLIIgnored    []string
+	// Collect holds the parse limits. The TODO types and config settings
+	// are set from each repository's config.
+	Collect ghclient.CollectOptions
+	// Progress, if set, is called before the i-th of n repositories is
+	// swept.
+	Progress func(i, n int, repo string)
+}
+
+// Run sweeps the open PRs of each of repos, in order. Each repository is
+// checked with its own config, loaded from its default branch, so that its
+// severities and ignored types apply to its TODOs. A repository that cannot
+// be swept is reported with its error and a warning; only if every
+// repository fails is the first error returned.
+func Run(ctx context.Context, fetcher Fetcher, repos []string, opts Options) (types.SweepReport, error) {
+	report := types.SweepReport{Org: opts.Org, Repositories: make([]types.RepositoryReport, 0, len(repos))}
+	var firstErr error
+	failed := 0
+	for i, repo := range repos {
+		if opts.Progress != nil {
+			opts.Progress(i, len(repos), repo)
+		}
+		r, err := sweepRepo(ctx, fetcher, repo, opts)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return types.SweepReport{}, ctxErr
+		}
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			r.Error = err.Error()
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", repo, err)
+		}
+		report.Repositories = append(report.Repositories, r)
+	}
+	if failed > 0 && failed == len(repos) {
+		return types.SweepReport{}, firstErr
+	}
+	return report, nil
+}
+
+// sweepRepo collects the TODOs of the open PRs of repo, rating each by the
+// severity the repository's config gives its type.
+func sweepRepo(ctx context.Context, fetcher Fetcher, repo string, opts Options) (types.RepositoryReport, error) {
+	r := types.RepositoryReport{Repository: repo, PRs: []types.PullRequest{}, TODOs: []types.Finding{}, Counts: make(map[string]int)}
+	prs, err := fetcher.SearchPRs(ctx, repo, "is:open")
+	if err != nil {
+		return r, err
+	}
+	if len(prs) == 0 {
+		return r, nil
+	}
+	r.PRs = prs
+
+	settings, err := policyresolve.ResolveSettings(ctx, fetcher, policyresolve.Options{
+		Target:        policyresolve.Target{Repo: repo, UseRemote: true},
+		UserConfigDir: opts.UserConfigDir,
+		CLISeverities: opts.CLISeverities,
+		CLIIgnored:    opts.CLIIgnored,
+	})
+	if err != nil {
+		return r, fmt.Errorf("remote config: %w", err)
+	}
+	collectOpts := opts.Collect
+	collectOpts.Types = settings.Policy.Types()
+	collectOpts.MarkdownParagraphs = settings.MarkdownParagraphs
+	collectOpts.Docstrings = settings.Docstrings
+	collectOpts.Languages = settings.Languages
+	collectOpts.Encodings = settings.Encodings
+	todos, err := ghclient.CollectAll(ctx, fetcher, r.PRs, collectOpts)
+	if err != nil {
+		return r, err
+	}
+	for _, todo := range todos {
+		severity := string(settings.Policy.SeverityFor(todo.Type))
+		r.TODOs = append(r.TODOs, types.Finding{TODO: todo, Severity: severity})
+		r.Counts[severity]++
+	}
+	return r, nil
+}
+
+// ReadRepos reads a list of repositories, one per line. Blank lines and
+// text from "#" on are skipped. Repositories may be given as
+// [HOST/]OWNER/REPO, or, if org is set, as REPO alone for a repository of
+// org. Repositories listed twice are swept once.
+func ReadRepos(r io.Reader, org string) ([]string, error) {
+	var repos []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		repo := strings.TrimSpace(line)
+		if repo == "" {
+			continue
+		}
+		if !strings.Contains(repo, "/") {
+			if org == "" {
+				return nil, fmt.Errorf("line %d: %q is not in OWNER/REPO form", n, repo)
+			}
+			repo = org + "/" + repo
+		}
+		if !slices.Contains(repos, repo) {
+			repos = append(repos, repo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
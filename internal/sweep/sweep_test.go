@@ -0,0 +1,168 @@
+package sweep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/internal/config"
+	"github.com/Suree33/gh-pr-todo/internal/todotype"
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+// fakeFetcher serves the open PRs, config files, and PR diffs of a few
+// repositories.
+type fakeFetcher struct {
+	prs     map[string][]types.PullRequest
+	configs map[string]string
+	diffs   map[string]string
+}
+
+func (f fakeFetcher) SearchPRs(_ context.Context, repo, query string) ([]types.PullRequest, error) {
+	if query != "is:open" {
+		return nil, fmt.Errorf("unexpected query %q", query)
+	}
+	prs, ok := f.prs[repo]
+	if !ok {
+		return nil, errors.New("repository not found")
+	}
+	return prs, nil
+}
+
+func (f fakeFetcher) FetchRemoteConfigRefs(_ context.Context, repo, pr string) (config.RemoteConfigRefs, error) {
+	if pr != "" {
+		return config.RemoteConfigRefs{}, fmt.Errorf("unexpected PR %q", pr)
+	}
+	return config.RemoteConfigRefs{DefaultRepo: repo, DefaultBranchRef: "main"}, nil
+}
+
+func (f fakeFetcher) FetchFileAtRef(_ context.Context, repo, path, _ string) ([]byte, bool, error) {
+	data, ok := f.configs[repo]
+	if !ok || path != ".gh-pr-todo.yml" {
+		return nil, false, nil
+	}
+	return []byte(data), true, nil
+}
+
+func (f fakeFetcher) FetchDiff(_ context.Context, _, pr string) (string, error) {
+	return f.diffs[pr], nil
+}
+
+func (f fakeFetcher) FetchChangedFileContents(context.Context, string, string, string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func TestRun(t *testing.T) {
+	api := types.PullRequest{Repository: "acme/api", Number: 1, URL: "https://github.com/acme/api/pull/1"}
+	web := types.PullRequest{Repository: "acme/web", Number: 7, URL: "https://github.com/acme/web/pull/7"}
+	diff := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1,3 @@\n package a\n+// TODO: a\n+// FIXME: b\n"
+	fetcher := fakeFetcher{
+		prs:     map[string][]types.PullRequest{"acme/api": {api}, "acme/web": {web}, "acme/docs": nil},
+		configs: map[string]string{"acme/web": "severity:\n  error: [FIXME]\nignore: [TODO]\n"},
+		diffs:   map[string]string{api.URL: diff, web.URL: diff},
+	}
+
+	var progress []string
+	var report types.SweepReport
+	var err error
+	stderr := captureStderr(t, func() {
+		report, err = Run(context.Background(), fetcher, []string{"acme/api", "acme/web", "acme/docs", "acme/gone"}, Options{
+			Org:           "acme",
+			CLISeverities: map[string]todotype.Severity{"TODO": todotype.SeverityWarning},
+			Progress:      func(i, n int, repo string) { progress = append(progress, fmt.Sprintf("%d/%d %s", i+1, n, repo)) },
+		})
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var got []string
+	for _, r := range report.Repositories {
+		line := fmt.Sprintf("%s %d PRs %v", r.Repository, len(r.PRs), r.Counts)
+		for _, f := range r.TODOs {
+			line += fmt.Sprintf(" [#%d %s %s]", f.PR.Number, f.Type, f.Severity)
+		}
+		if r.Error != "" {
+			line += " error: " + r.Error
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		"acme/api 1 PRs map[warning:2] [#1 TODO warning] [#1 FIXME warning]",
+		"acme/web 1 PRs map[error:1] [#7 FIXME error]",
+		"acme/docs 0 PRs map[]",
+		"acme/gone 0 PRs map[] error: repository not found",
+	}
+	if report.Org != "acme" || !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() = %s %q, expected acme %q", report.Org, got, want)
+	}
+	if want := []string{"1/4 acme/api", "2/4 acme/web", "3/4 acme/docs", "4/4 acme/gone"}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %q, expected %q", progress, want)
+	}
+	if !strings.Contains(stderr, "Warning: skipping acme/gone: repository not found") {
+		t.Errorf("stderr = %q, expected a warning", stderr)
+	}
+
+	captureStderr(t, func() {
+		_, err = Run(context.Background(), fetcher, []string{"acme/gone"}, Options{})
+	})
+	if err == nil || err.Error() != "repository not found" {
+		t.Errorf("Run() error = %v, expected the only repository's error", err)
+	}
+}
+
+func TestReadRepos(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		org     string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "names and comments",
+			input: "# services\nacme/api\n\n  web  # frontend\nother/tool\nacme/web\nghe.example.com/corp/svc\n",
+			org:   "acme",
+			want:  []string{"acme/api", "acme/web", "other/tool", "ghe.example.com/corp/svc"},
+		},
+		{
+			name:    "bare name without an organization",
+			input:   "acme/api\nweb\n",
+			wantErr: `line 2: "web" is not in OWNER/REPO form`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadRepos(strings.NewReader(tt.input), tt.org)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ReadRepos() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadRepos() = %q, %v, expected %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = prev }()
+	fn()
+	_ = w.Close()
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"path"
+	"strings"
+
+	"github.com/odvcencio/gotreesitter"
+)
+
+// symbolSeparator joins nested symbol names, e.g. "class Foo > def bar".
+const symbolSeparator = " > "
+
+// symbolKeywords maps Tree-sitter node types that introduce a named scope to
+// the keyword shown before the scope name. An empty keyword shows the bare
+// name. Languages whose grammars reuse node type names with a different
+// meaning get their own table in languageSymbolKeywords.
+var symbolKeywords = map[string]string{
+	// JavaScript / TypeScript
+	"class_declaration":              "class",
+	"class":                          "class",
+	"function_declaration":           "function",
+	"generator_function_declaration": "function",
+	"method_definition":              "",
+	"interface_declaration":          "interface",
+	"enum_declaration":               "enum",
+	"variable_declarator":            "",
+	// Java / C# / Kotlin / Swift / PHP
+	"method_declaration":      "",
+	"constructor_declaration": "",
+	"record_declaration":      "record",
+	"struct_declaration":      "struct",
+	"object_declaration":      "object",
+	"protocol_declaration":    "protocol",
+	"namespace_declaration":   "namespace",
+	// C / C++
+	"function_definition":  "",
+	"class_specifier":      "class",
+	"struct_specifier":     "struct",
+	"namespace_definition": "namespace",
+}
+
+// languageSymbolKeywords overrides symbolKeywords by file extension.
+var languageSymbolKeywords = map[string]map[string]string{
+	".py":  pythonSymbolKeywords,
+	".pyi": pythonSymbolKeywords,
+	".rb":  rubySymbolKeywords,
+	".rs":  rustSymbolKeywords,
+}
+
+var pythonSymbolKeywords = map[string]string{
+	"class_definition":    "class",
+	"function_definition": "def",
+}
+
+var rubySymbolKeywords = map[string]string{
+	"class":            "class",
+	"module":           "module",
+	"method":           "def",
+	"singleton_method": "def",
+}
+
+var rustSymbolKeywords = map[string]string{
+	"function_item": "fn",
+	"impl_item":     "impl",
+	"struct_item":   "struct",
+	"enum_item":     "enum",
+	"trait_item":    "trait",
+	"mod_item":      "mod",
+}
+
+// functionValueTypes are node types that make a variable declarator (e.g.
+// `const handler = () => {}`) count as a named function scope.
+var functionValueTypes = map[string]bool{
+	"arrow_function":      true,
+	"function":            true,
+	"function_expression": true,
+}
+
+// symbolNameTypes are node types that hold the name of a scope.
+var symbolNameTypes = map[string]bool{
+	"identifier":             true,
+	"field_identifier":       true,
+	"property_identifier":    true,
+	"type_identifier":        true,
+	"constant":               true,
+	"name":                   true,
+	"simple_identifier":      true,
+	"scoped_identifier":      true,
+	"qualified_identifier":   true,
+	"namespace_identifier":   true,
+	"generic_type":           true,
+	"scoped_type_identifier": true,
+}
+
+// symbolFor returns the display name of the scope introduced by node, or ""
+// if node does not introduce a named scope.
+func symbolFor(node *gotreesitter.Node, bt *gotreesitter.BoundTree, filename string) string {
+	nodeType := bt.NodeType(node)
+	ext := strings.ToLower(path.Ext(filename))
+	if ext == ".go" {
+		return goSymbolFor(node, bt, nodeType)
+	}
+
+	keywords, ok := languageSymbolKeywords[ext]
+	if !ok {
+		keywords = symbolKeywords
+	}
+	keyword, ok := keywords[nodeType]
+	if !ok {
+		return ""
+	}
+	if nodeType == "variable_declarator" && !hasChildOfType(node, bt, functionValueTypes) {
+		return ""
+	}
+
+	name := symbolName(node, bt)
+	if name == "" {
+		return ""
+	}
+	if keyword == "" {
+		return name
+	}
+	return keyword + " " + name
+}
+
+// goSymbolFor names Go functions, methods, and types using Go's own
+// notation: "FetchDiff", "(*Client).FetchDiff", "Client".
+func goSymbolFor(node *gotreesitter.Node, bt *gotreesitter.BoundTree, nodeType string) string {
+	switch nodeType {
+	case "function_declaration", "type_spec":
+		return symbolName(node, bt)
+	case "method_declaration":
+		name := symbolName(node, bt)
+		if name == "" {
+			return ""
+		}
+		if receiver := goReceiverType(node, bt); receiver != "" {
+			if strings.HasPrefix(receiver, "*") {
+				return "(" + receiver + ")." + name
+			}
+			return receiver + "." + name
+		}
+		return name
+	}
+	return ""
+}
+
+// goReceiverType returns the receiver type of a Go method declaration, such
+// as "*Client" or "Client", with any type parameters removed.
+func goReceiverType(node *gotreesitter.Node, bt *gotreesitter.BoundTree) string {
+	for i := 0; i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child == nil || bt.NodeType(child) != "parameter_list" {
+			continue
+		}
+		// The first parameter list of a method declaration is the receiver.
+		for j := 0; j < child.ChildCount(); j++ {
+			param := child.Child(j)
+			if param == nil || bt.NodeType(param) != "parameter_declaration" || param.ChildCount() == 0 {
+				continue
+			}
+			typ := strings.TrimSpace(bt.NodeText(param.Child(param.ChildCount() - 1)))
+			if idx := strings.IndexByte(typ, '['); idx >= 0 {
+				typ = typ[:idx]
+			}
+			return typ
+		}
+		return ""
+	}
+	return ""
+}
+
+// symbolName returns the text of the first name-like child of node. For C
+// style declarations the name is nested inside a declarator, so declarator
+// children are searched as well.
+func symbolName(node *gotreesitter.Node, bt *gotreesitter.BoundTree) string {
+	for i := 0; i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child != nil && symbolNameTypes[bt.NodeType(child)] {
+			return strings.TrimSpace(bt.NodeText(child))
+		}
+	}
+	for i := 0; i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child != nil && strings.HasSuffix(bt.NodeType(child), "declarator") {
+			if name := symbolName(child, bt); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// hasChildOfType reports whether any direct child of node has one of the
+// given types.
+func hasChildOfType(node *gotreesitter.Node, bt *gotreesitter.BoundTree, nodeTypes map[string]bool) bool {
+	for i := 0; i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		if child != nil && nodeTypes[bt.NodeType(child)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestParseDiffWithContentsSymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     string
+		files    map[string][]byte
+		expected []types.TODO
+	}{
+		{
+			name: "Go method with pointer receiver",
+			diff: `diff --git a/client.go b/client.go
+index 1234567..abcdefg 100644
+--- a/client.go
++++ b/client.go
+@@ -3,4 +3,5 @@
+ type Client struct{}
+ 
+ func (c *Client) FetchDiff() {
++	// TODO: stream output
+ }`,
+			files: map[string][]byte{
+				"client.go": []byte("package github\n\ntype Client struct{}\n\nfunc (c *Client) FetchDiff() {\n\t// TODO: stream output\n}\n"),
+			},
+			expected: []types.TODO{
+				{Filename: "client.go", Line: 6, Comment: "// TODO: stream output", Type: "TODO", Symbol: "(*Client).FetchDiff"},
+			},
+		},
+		{
+			name: "Go function and top-level comment",
+			diff: `diff --git a/main.go b/main.go
+index 1234567..abcdefg 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,6 @@
+ package main
+ 
++// NOTE: entry point
+ func main() {
++	// FIXME: parse flags
+ }`,
+			files: map[string][]byte{
+				"main.go": []byte("package main\n\n// NOTE: entry point\nfunc main() {\n\t// FIXME: parse flags\n}\n"),
+			},
+			expected: []types.TODO{
+				{Filename: "main.go", Line: 3, Comment: "// NOTE: entry point", Type: "NOTE"},
+				{Filename: "main.go", Line: 5, Comment: "// FIXME: parse flags", Type: "FIXME", Symbol: "main"},
+			},
+		},
+		{
+			name: "Python method inside class",
+			diff: `diff --git a/app.py b/app.py
+index 1234567..abcdefg 100644
+--- a/app.py
++++ b/app.py
+@@ -1,3 +1,4 @@
+ class Foo:
+     def bar(self):
++        # HACK: handle None
+         return 1`,
+			files: map[string][]byte{
+				"app.py": []byte("class Foo:\n    def bar(self):\n        # HACK: handle None\n        return 1\n"),
+			},
+			expected: []types.TODO{
+				{Filename: "app.py", Line: 3, Comment: "# HACK: handle None", Type: "HACK", Symbol: "class Foo > def bar"},
+			},
+		},
+		{
+			name: "TypeScript method inside class",
+			diff: `diff --git a/app.ts b/app.ts
+index 1234567..abcdefg 100644
+--- a/app.ts
++++ b/app.ts
+@@ -1,4 +1,5 @@
+ class Foo {
+   bar() {
++    // TODO: cache result
+     return 1;
+   }`,
+			files: map[string][]byte{
+				"app.ts": []byte("class Foo {\n  bar() {\n    // TODO: cache result\n    return 1;\n  }\n}\n"),
+			},
+			expected: []types.TODO{
+				{Filename: "app.ts", Line: 3, Comment: "// TODO: cache result", Type: "TODO", Symbol: "class Foo > bar"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseDiffWithContents(tt.diff, tt.files)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithContents() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
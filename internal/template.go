@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+var (
+	// jinjaDelimiters are the comment delimiters of Jinja, Nunjucks, and
+	// Twig templates.
+	jinjaDelimiters = []commentDelimiter{
+		{open: "{#", close: "#}"},
+		{open: "{#-", close: "#}"},
+	}
+	// erbDelimiters are the comment delimiters of ERB templates.
+	erbDelimiters = []commentDelimiter{
+		{open: "<%#", close: "%>"},
+		{open: "<%-#", close: "%>"},
+	}
+	// handlebarsDelimiters are the comment delimiters of Handlebars and
+	// Mustache templates.
+	handlebarsDelimiters = []commentDelimiter{
+		{open: "{{!--", close: "--}}"},
+		{open: "{{!", close: "}}"},
+	}
+	// goTemplateDelimiters are the comment delimiters of Go text/template,
+	// including the whitespace-trimming forms used throughout Helm charts.
+	goTemplateDelimiters = []commentDelimiter{
+		{open: "{{/*", close: "*/}}", altCloses: []string{"*/ -}}"}},
+		{open: "{{- /*", close: "*/ -}}", altCloses: []string{"*/}}"}},
+	}
+	// liquidDelimiters are the comment delimiters of Liquid templates: the
+	// comment tag block and the inline comment tag.
+	liquidDelimiters = []commentDelimiter{
+		{open: "{% comment %}", close: "{% endcomment %}", altCloses: []string{"{%- endcomment -%}"}},
+		{open: "{%- comment -%}", close: "{%- endcomment -%}", altCloses: []string{"{% endcomment %}"}},
+		{open: "{% #", close: "%}"},
+		{open: "{%- #", close: "-%}"},
+	}
+)
+
+// templateExtensions maps template file extensions to their comment
+// delimiters.
+var templateExtensions = map[string][]commentDelimiter{
+	".erb":        erbDelimiters,
+	".gotmpl":     goTemplateDelimiters,
+	".handlebars": handlebarsDelimiters,
+	".hbs":        handlebarsDelimiters,
+	".j2":         jinjaDelimiters,
+	".jinja":      jinjaDelimiters,
+	".jinja2":     jinjaDelimiters,
+	".liquid":     liquidDelimiters,
+	".mustache":   handlebarsDelimiters,
+	".njk":        jinjaDelimiters,
+	".tmpl":       goTemplateDelimiters,
+	".tpl":        goTemplateDelimiters,
+	".twig":       jinjaDelimiters,
+}
+
+// templateSyntax returns the comment delimiters of a template file and the
+// name of the file with the template extension removed, whose extension
+// selects the grammar of the surrounding text (for example "index.html" for
+// "index.html.erb"). YAML files in a Helm chart's templates directory are Go
+// templates whose surrounding text is YAML.
+func templateSyntax(filename string) ([]commentDelimiter, string, bool) {
+	ext := strings.ToLower(path.Ext(filename))
+	if delimiters, ok := templateExtensions[ext]; ok {
+		return delimiters, filename[:len(filename)-len(ext)], true
+	}
+	if (ext == ".yaml" || ext == ".yml") && path.Base(path.Dir(filename)) == "templates" {
+		return goTemplateDelimiters, filename, true
+	}
+	return nil, "", false
+}
+
+// parseTemplateTODOs extracts TODO comments from a template file. Template
+// comments may span lines. The text outside template comments is parsed as
+// the file type given by inner, so that comments of the generated language
+// are found too.
+func parseTemplateTODOs(fc fileChange, content []byte, delimiters []commentDelimiter, inner string, p markerPatterns) []types.TODO {
+	r := fileRegion(fc.path, content)
+
+	var todos []types.TODO
+	scanner := delimitedCommentScanner{delimiters: delimiters}
+	outside := sourceRegion{filename: inner, fileLines: r.fileLines}
+	for i, line := range r.lines {
+		segments, masked := scanner.split(line)
+		outside.lines = append(outside.lines, masked)
+		if len(segments) > 0 && lineInRanges(i+1, fc.addedRanges) {
+			todos = append(todos, commentSegmentTODOs(segments, i+1, p.body)...)
+		}
+	}
+	todos = r.remap(fc.path, todos)
+	todos = append(todos, parseRegionTODOs(fc, outside, p)...)
+
+	sort.SliceStable(todos, func(i, j int) bool { return todos[i].Line < todos[j].Line })
+	return todos
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Suree33/gh-pr-todo/pkg/types"
+)
+
+func TestParseDiffWithContentsTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		expected []types.TODO
+	}{
+		{
+			name: "Jinja comments and comments of the generated file",
+			path: "deploy/nginx.conf.j2",
+			content: `server {
+  {# TODO: make port configurable #}
+  listen 80; # FIXME: enable TLS
+  {#-
+    HACK: multi-line
+  -#}
+}
+`,
+			expected: []types.TODO{
+				{Filename: "deploy/nginx.conf.j2", Line: 2, Comment: "{# TODO: make port configurable #}", Type: "TODO"},
+				{Filename: "deploy/nginx.conf.j2", Line: 3, Comment: "# FIXME: enable TLS", Type: "FIXME"},
+				{Filename: "deploy/nginx.conf.j2", Line: 5, Comment: "HACK: multi-line", Type: "HACK"},
+			},
+		},
+		{
+			name: "ERB view with HTML comments",
+			path: "app/views/users/show.html.erb",
+			content: `<%# TODO: paginate %>
+<h1><%= @user.name %></h1>
+<!-- NOTE: markup comment -->
+`,
+			expected: []types.TODO{
+				{Filename: "app/views/users/show.html.erb", Line: 1, Comment: "<%# TODO: paginate %>", Type: "TODO"},
+				{Filename: "app/views/users/show.html.erb", Line: 3, Comment: "<!-- NOTE: markup comment -->", Type: "NOTE"},
+			},
+		},
+		{
+			name: "Handlebars comments",
+			path: "templates/card.hbs",
+			content: `{{!-- FIXME: escape {{title}} --}}
+<div>{{! HACK: quick }}{{title}}</div>
+`,
+			expected: []types.TODO{
+				{Filename: "templates/card.hbs", Line: 1, Comment: "{{!-- FIXME: escape {{title}} --}}", Type: "FIXME"},
+				{Filename: "templates/card.hbs", Line: 2, Comment: "{{! HACK: quick }}", Type: "HACK"},
+			},
+		},
+		{
+			name: "Helm chart template",
+			path: "charts/app/templates/deployment.yaml",
+			content: `{{- /* TODO: support HPA */ -}}
+apiVersion: apps/v1
+kind: Deployment # NOTE: yaml comment
+{{- /*
+XXX: multi-line
+*/}}
+`,
+			expected: []types.TODO{
+				{Filename: "charts/app/templates/deployment.yaml", Line: 1, Comment: "{{- /* TODO: support HPA */ -}}", Type: "TODO"},
+				{Filename: "charts/app/templates/deployment.yaml", Line: 3, Comment: "# NOTE: yaml comment", Type: "NOTE"},
+				{Filename: "charts/app/templates/deployment.yaml", Line: 5, Comment: "XXX: multi-line", Type: "XXX"},
+			},
+		},
+		{
+			name:    "Helm helpers",
+			path:    "charts/app/templates/_helpers.tpl",
+			content: "{{/* FIXME: truncate names */}}\n{{- define \"app.name\" -}}\n",
+			expected: []types.TODO{
+				{Filename: "charts/app/templates/_helpers.tpl", Line: 1, Comment: "{{/* FIXME: truncate names */}}", Type: "FIXME"},
+			},
+		},
+		{
+			name:    "Liquid comment tags",
+			path:    "_includes/date.liquid",
+			content: "{% comment %}TODO: translate{% endcomment %}\n{% # BUG: wrong timezone %}\n",
+			expected: []types.TODO{
+				{Filename: "_includes/date.liquid", Line: 1, Comment: "{% comment %}TODO: translate{% endcomment %}", Type: "TODO"},
+				{Filename: "_includes/date.liquid", Line: 2, Comment: "{% # BUG: wrong timezone %}", Type: "BUG"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{tt.path: []byte(tt.content)}
+			result := ParseDiffWithContents(newFileDiff(tt.path, tt.content), files)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseDiffWithContents() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTemplateSyntax(t *testing.T) {
+	tests := []struct {
+		filename string
+		inner    string
+		ok       bool
+	}{
+		{filename: "app/views/index.html.erb", inner: "app/views/index.html", ok: true},
+		{filename: "roles/web/templates/site.conf.J2", inner: "roles/web/templates/site.conf", ok: true},
+		{filename: "charts/app/templates/service.yml", inner: "charts/app/templates/service.yml", ok: true},
+		{filename: "config/service.yml", ok: false},
+		{filename: "main.go", ok: false},
+	}
+	for _, tt := range tests {
+		_, inner, ok := templateSyntax(tt.filename)
+		if inner != tt.inner || ok != tt.ok {
+			t.Errorf("templateSyntax(%q) = %q, %v, expected %q, %v", tt.filename, inner, ok, tt.inner, tt.ok)
+		}
+	}
+}
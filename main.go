@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Suree33/gh-pr-todo/internal"
+	"github.com/Suree33/gh-pr-todo/internal/cache"
+	"github.com/Suree33/gh-pr-todo/internal/config"
 	ghclient "github.com/Suree33/gh-pr-todo/internal/github"
 	"github.com/Suree33/gh-pr-todo/internal/initcmd"
+	"github.com/Suree33/gh-pr-todo/internal/language"
 	"github.com/Suree33/gh-pr-todo/internal/output"
 	"github.com/Suree33/gh-pr-todo/internal/policyresolve"
+	"github.com/Suree33/gh-pr-todo/internal/sweep"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 	"github.com/Suree33/gh-pr-todo/pkg/types"
 	"github.com/briandowns/spinner"
@@ -19,15 +28,66 @@ import (
 	"github.com/spf13/pflag"
 )
 
-func registerFlags(fs *pflag.FlagSet, repo *string, nameOnly, isCount, isHelp, noCIFail *bool, groupBy *types.GroupBy, sevFlag *severityFlag, ignoreFlag *ignoreFlag) {
-	fs.StringVarP(repo, "repo", "R", "", "Select another repository using the [HOST/]OWNER/REPO format; requires a PR number, URL, or branch argument")
-	fs.BoolVar(nameOnly, "name-only", false, "Display only names of the files containing TODO-style comments; takes precedence over --count")
-	fs.BoolVarP(isCount, "count", "c", false, "Display only the number of TODO-style comments")
-	fs.BoolVarP(isHelp, "help", "h", false, "Display help information")
-	fs.BoolVar(noCIFail, "no-ci-fail", false, "Disable non-zero exit when error-level TODOs are found in CI")
-	fs.Var(groupBy, "group-by", "Group TODO-style comments by: \"file\" or \"type\"")
-	fs.Var(sevFlag, "severity", "Override severity for one or more TODO types. Format: LEVEL=TYPE[,TYPE...] (e.g. --severity warning=TODO,HACK)")
-	fs.Var(ignoreFlag, "ignore", "Ignore specified TODO marker types (comma-separated, repeatable). These types are not detected or reported. Example: --ignore NOTE,HACK")
+// runFlags holds the flags of a check run.
+type runFlags struct {
+	repo            string
+	base            string
+	since           string
+	search          string
+	nameOnly        bool
+	count           bool
+	help            bool
+	noCIFail        bool
+	stack           bool
+	sinceLastReview bool
+	allOpen         bool
+	groupBy         types.GroupBy
+	contextLines    int
+	limits          parseLimits
+	timeout         time.Duration
+	caching         cacheOptions
+	noLocalGit      bool
+	fetch           bool
+	severity        *severityFlag
+	ignore          *ignoreFlag
+}
+
+// newRunFlags returns runFlags holding the defaults of the flags.
+func newRunFlags() *runFlags {
+	return &runFlags{
+		groupBy:  types.GroupByNone,
+		limits:   parseLimits{maxMemory: sizeFlag{bytes: defaultMaxMemory}},
+		caching:  cacheOptions{maxSize: sizeFlag{bytes: cache.DefaultMaxSize}},
+		severity: newSeverityFlag(),
+		ignore:   newIgnoreFlag(),
+	}
+}
+
+// registerFlags registers the flags of a check run on fs, storing them in f.
+func registerFlags(fs *pflag.FlagSet, f *runFlags) {
+	fs.StringVarP(&f.repo, "repo", "R", "", "Select another repository using the [HOST/]OWNER/REPO format; requires a PR number, URL, or branch argument")
+	fs.StringVar(&f.base, "base", "", "Compare a branch without an open PR with BRANCH instead of the default branch")
+	fs.BoolVar(&f.nameOnly, "name-only", false, "Display only names of the files containing TODO-style comments; takes precedence over --count")
+	fs.BoolVarP(&f.count, "count", "c", false, "Display only the number of TODO-style comments")
+	fs.BoolVarP(&f.help, "help", "h", false, "Display help information")
+	fs.BoolVar(&f.noCIFail, "no-ci-fail", false, "Disable non-zero exit when error-level TODOs are found in CI")
+	fs.BoolVar(&f.stack, "stack", false, "Check every PR in the stack of PRs the PR belongs to, attributing each TODO-style comment to the PR that introduced it; the top PR's remote config applies to every PR in the stack")
+	fs.StringVar(&f.since, "since", "", "Show only TODO-style comments added since COMMIT, such as the PR head you last looked at")
+	fs.BoolVar(&f.sinceLastReview, "since-last-review", false, "Show only TODO-style comments added since the PR head you last reviewed")
+	fs.StringVar(&f.search, "search", "", "Check every PR matching a GitHub search QUERY, such as \"is:open author:@me\", grouping TODO-style comments by PR")
+	fs.BoolVar(&f.allOpen, "all-open", false, "Check every open PR of the repository, grouping TODO-style comments by PR")
+	fs.Var(&f.groupBy, "group-by", "Group TODO-style comments by: \"file\", \"type\", \"symbol\" (enclosing function, method, or class), \"commit\", or \"author\"")
+	fs.IntVar(&f.contextLines, "context", 0, "Show N lines of surrounding code before and after each TODO-style comment")
+	fs.IntVarP(&f.limits.jobs, "jobs", "j", 0, "Parse up to N changed files in parallel (default: number of CPUs)")
+	fs.Var(&f.limits.maxFileSize, "max-file-size", "Skip files whose diff is larger than SIZE, such as 10M (default: no limit)")
+	fs.Var(&f.limits.maxMemory, "max-memory", "Pause reading the diff while SIZE of it waits to be parsed (default: 256M)")
+	fs.DurationVar(&f.timeout, "timeout", 0, "Give up on GitHub API requests after DURATION, such as 30s or 2m (default: no limit)")
+	fs.BoolVar(&f.caching.disabled, "no-cache", false, "Fetch everything from GitHub without reading or writing the on-disk cache")
+	fs.Var(&f.caching.maxSize, "cache-size", "Trim the on-disk cache to SIZE after each run, such as 1G; 0 means no limit (default: 512M)")
+	fs.BoolVar(&f.noLocalGit, "no-local-git", false, "Fetch file contents from GitHub even when the PR's commits are in the local git repository")
+	fs.BoolVar(&f.fetch, "fetch", false, "Fetch a PR head missing from the local git repository into it with git fetch, instead of reading its files from GitHub")
+	fs.Var(f.severity, "severity", "Override severity for one or more TODO types. Format: LEVEL=TYPE[,TYPE...] (e.g. --severity warning=TODO,HACK)")
+	fs.Var(f.ignore, "ignore", "Ignore specified TODO marker types (comma-separated, repeatable). These types are not detected or reported. Example: --ignore NOTE,HACK")
 }
 
 func main() {
@@ -43,31 +103,60 @@ func main() {
 			UserConfigDir: os.UserConfigDir,
 		}.Execute(os.Args[2:]))
 	}
+	if len(os.Args) > 1 && os.Args[1] == "languages" {
+		os.Exit(runLanguages(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		os.Exit(runCache(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sweep" {
+		os.Exit(runSweep(os.Args[2:]))
+	}
 
 	// Use ContinueOnError so we can print a clear error and exit code 1
 	// instead of pflag's default ExitOnError (exit code 2).
 	pflag.CommandLine = pflag.NewFlagSet("gh pr-todo", pflag.ContinueOnError)
 	pflag.CommandLine.SetOutput(io.Discard)
 
-	var (
-		repo     string
-		nameOnly bool
-		isCount  bool
-		isHelp   bool
-		noCIFail bool
-		groupBy  = types.GroupByNone
-		sevFlag  = newSeverityFlag()
-		ignFlag  = newIgnoreFlag()
-	)
-	registerFlags(pflag.CommandLine, &repo, &nameOnly, &isCount, &isHelp, &noCIFail, &groupBy, sevFlag, ignFlag)
+	flags := newRunFlags()
+	registerFlags(pflag.CommandLine, flags)
 	pflag.Usage = printUsage
 	if err := pflag.CommandLine.Parse(os.Args[1:]); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	if flags.contextLines < 0 {
+		fmt.Fprintf(os.Stderr, "invalid argument %d for \"--context\" flag: must not be negative\n", flags.contextLines)
+		os.Exit(1)
+	}
+	if flags.limits.jobs < 0 {
+		fmt.Fprintf(os.Stderr, "invalid argument %d for \"--jobs\" flag: must not be negative\n", flags.limits.jobs)
+		os.Exit(1)
+	}
+	if flags.timeout < 0 {
+		fmt.Fprintf(os.Stderr, "invalid argument %s for \"--timeout\" flag: must not be negative\n", flags.timeout)
+		os.Exit(1)
+	}
+	if flags.since != "" && flags.sinceLastReview {
+		fmt.Fprintln(os.Stderr, "--since and --since-last-review cannot be used together")
+		os.Exit(1)
+	}
+	if flags.stack && (flags.since != "" || flags.sinceLastReview) {
+		fmt.Fprintln(os.Stderr, "--stack cannot be used with --since or --since-last-review")
+		os.Exit(1)
+	}
+	searching := flags.search != "" || flags.allOpen
+	if flags.search != "" && flags.allOpen {
+		fmt.Fprintln(os.Stderr, "--search and --all-open cannot be used together")
+		os.Exit(1)
+	}
+	if searching && (flags.stack || flags.since != "" || flags.sinceLastReview || flags.base != "") {
+		fmt.Fprintln(os.Stderr, "--search and --all-open cannot be used with --stack, --since, --since-last-review, or --base")
+		os.Exit(1)
+	}
 	args := pflag.Args()
 
-	if isHelp {
+	if flags.help {
 		pflag.Usage()
 		os.Exit(0)
 	}
@@ -75,13 +164,18 @@ func main() {
 	var pr string
 	switch len(args) {
 	case 0:
-		if repo != "" {
+		if flags.repo != "" && !searching {
 			fmt.Fprintf(color.Output, "%s%s\n", output.Red("✗"), " PR number, branch, or URL required when specifying repository\n")
 			pflag.Usage()
 			os.Exit(1)
 		}
 		pr = ""
 	case 1:
+		if searching {
+			fmt.Fprintf(color.Output, "%s%s\n", output.Red("✗"), " --search and --all-open check several PRs and take no PR argument\n")
+			pflag.Usage()
+			os.Exit(1)
+		}
 		pr = args[0]
 	default:
 		fmt.Fprintf(color.Output, "%s%s\n", output.Red("✗"), " Too many arguments\n")
@@ -94,7 +188,7 @@ func main() {
 		userConfigDir = dir
 	}
 
-	target := policyresolve.ResolveTarget(repo, pr)
+	target := policyresolve.ResolveTarget(flags.repo, pr)
 	cwd := ""
 	if !target.UseRemote {
 		var err error
@@ -105,37 +199,121 @@ func main() {
 		}
 	}
 
+	ctx, cancel := newRunContext(flags.timeout)
 	fetcher := ghclient.NewClient()
-	policy, err := policyresolve.Resolve(fetcher, policyresolve.Options{
+	responseCache := flags.caching.open()
+	fetcher.SetCache(responseCache)
+	fetcher.SetCompareBase(flags.base)
+	if !flags.noLocalGit {
+		fetcher.SetLocalRepo(".", flags.fetch)
+	}
+	var set prSet
+	if flags.stack {
+		var err error
+		if set.prs, err = fetcher.FetchStack(ctx, flags.repo, pr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			printAuthHint(err)
+			cancel()
+			os.Exit(1)
+		}
+		// The top of the stack holds the changes of every PR below it, so
+		// its config applies to all of them.
+		if target.UseRemote {
+			target.PR = strconv.Itoa(set.prs[len(set.prs)-1].Number)
+		}
+	}
+	if searching {
+		if flags.allOpen {
+			flags.search = "is:open"
+		}
+		var err error
+		set.search = true
+		if set.prs, err = fetcher.SearchPRs(ctx, flags.repo, flags.search); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			printAuthHint(err)
+			cancel()
+			os.Exit(1)
+		}
+		// PRs of other repositories are checked with their own config.
+		if ghclient.NamesRepositories(flags.search) {
+			if err := resolveRepoSettings(ctx, fetcher, &set, policyresolve.Options{
+				UserConfigDir: userConfigDir,
+				CLISeverities: flags.severity.assignments,
+				CLIIgnored:    flags.ignore.types,
+			}); err != nil {
+				fmt.Fprintln(os.Stderr, "Remote config error:", err)
+				printAuthHint(err)
+				cancel()
+				os.Exit(1)
+			}
+		}
+	}
+	if flags.sinceLastReview {
+		var err error
+		if flags.since, err = fetcher.FetchLastReviewCommit(ctx, flags.repo, pr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			printAuthHint(err)
+			cancel()
+			os.Exit(1)
+		}
+	}
+	settings, err := policyresolve.ResolveSettings(ctx, fetcher, policyresolve.Options{
 		Target:        target,
 		CWD:           cwd,
 		UserConfigDir: userConfigDir,
-		CLISeverities: sevFlag.assignments,
-		CLIIgnored:    ignFlag.types,
+		CLISeverities: flags.severity.assignments,
+		CLIIgnored:    flags.ignore.types,
 	})
 	if err != nil {
 		if target.UseRemote {
 			fmt.Fprintln(os.Stderr, "Remote config error:", err)
+			printAuthHint(err)
 		} else {
 			fmt.Fprintln(os.Stderr, "Config error:", err)
 		}
+		cancel()
 		os.Exit(1)
 	}
 
 	gha := isGitHubActions()
 	var result runResult
 	switch {
-	case nameOnly:
-		result, err = runNameOnly(fetcher, repo, pr, policy)
-	case isCount:
-		result, err = runCount(fetcher, repo, pr, policy)
+	case flags.nameOnly:
+		result, err = runNameOnly(ctx, fetcher, flags.repo, pr, set, flags.since, settings, flags.limits)
+	case flags.count:
+		result, err = runCount(ctx, fetcher, flags.repo, pr, set, flags.since, settings, flags.limits)
 	default:
-		result, err = runMain(fetcher, repo, pr, groupBy, gha, policy)
+		result, err = runMain(ctx, fetcher, flags.repo, pr, set, flags.since, flags.groupBy, flags.contextLines, gha, settings, flags.limits)
 	}
+	cancel()
+	_ = fetcher.Close()
+	_ = responseCache.Trim()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		printAuthHint(err)
+	}
+	os.Exit(exitCode(err, result.ciFailingCount, isCI(), flags.noCIFail))
+}
+
+// newRunContext returns the context of a run, which is canceled on an
+// interrupt and, if timeout is positive, once it has elapsed.
+func newRunContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// printAuthHint suggests logging in when err is an authentication failure.
+func printAuthHint(err error) {
+	if errors.Is(err, ghclient.ErrAuth) {
+		fmt.Fprintln(os.Stderr, "To authenticate, run `gh auth login` or set the GH_TOKEN environment variable.")
 	}
-	os.Exit(exitCode(err, result.ciFailingCount, isCI(), noCIFail))
 }
 
 // runResult groups the total TODO count and the CI-failing count from a run.
@@ -144,6 +322,71 @@ type runResult struct {
 	ciFailingCount int
 }
 
+// defaultMaxMemory is the default --max-memory.
+const defaultMaxMemory = 256 << 20
+
+// parseLimits holds the flags that bound the work and memory spent parsing.
+type parseLimits struct {
+	jobs        int
+	maxFileSize sizeFlag
+	maxMemory   sizeFlag
+}
+
+// cacheOptions holds the flags that control the on-disk cache.
+type cacheOptions struct {
+	disabled bool
+	maxSize  sizeFlag
+}
+
+// open returns the cache to use, or nil if caching is disabled or there is
+// no user cache directory.
+func (o cacheOptions) open() *cache.Cache {
+	if o.disabled {
+		return nil
+	}
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil
+	}
+	return cache.New(dir, o.maxSize.bytes)
+}
+
+// sizeFlag holds a byte size given as a number with an optional K, M, or G
+// suffix, each a power of 1024, such as "512K" or "64MiB". Zero means no
+// limit.
+type sizeFlag struct {
+	bytes int64
+}
+
+func (f *sizeFlag) String() string {
+	return strconv.FormatInt(f.bytes, 10)
+}
+
+func (f *sizeFlag) Set(val string) error {
+	s := strings.ToUpper(strings.TrimSpace(val))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	shift := 0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		shift = 10
+	case strings.HasSuffix(s, "M"):
+		shift = 20
+	case strings.HasSuffix(s, "G"):
+		shift = 30
+	}
+	if shift > 0 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64>>shift {
+		return fmt.Errorf("expected a size such as 512K, 10M, or 1G")
+	}
+	f.bytes = n << shift
+	return nil
+}
+
+func (f *sizeFlag) Type() string { return "size" }
+
 // severityFlag accumulates --severity LEVEL=TYPE[,TYPE...] flag values.
 // Each flag adds one or more type→severity assignments; later assignments
 // for the same type (case-insensitive) replace earlier ones (last-wins).
@@ -262,6 +505,252 @@ func isCI() bool {
 	return err == nil && ok
 }
 
+// runLanguages lists the supported languages and returns the exit code.
+func runLanguages(args []string) int {
+	fs := pflag.NewFlagSet("gh pr-todo languages", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	help := fs.BoolP("help", "h", false, "Display help information")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *help {
+		fmt.Fprintf(color.Output, "%s\n\n", "List the languages whose TODO-style comments are detected.")
+		fmt.Fprintf(color.Output, "%s\n", output.Bold("USAGE"))
+		fmt.Fprintf(color.Output, "  %s\n\n", "gh pr-todo languages")
+		fmt.Fprintf(color.Output, "  %s\n", "Languages are detected from file extensions, a \"#!\" line, or a Vim or")
+		fmt.Fprintf(color.Output, "  %s\n", "Emacs modeline. Use names or aliases from this list in the \"languages\"")
+		fmt.Fprintf(color.Output, "  %s\n", "config section to map other files, for example \"Jenkinsfile: groovy\".")
+		return 0
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n", fs.Arg(0))
+		return 1
+	}
+	output.PrintLanguages(language.All(), internal.HasGrammar)
+	return 0
+}
+
+// runCache manages the on-disk cache and returns the exit code.
+func runCache(args []string) int {
+	fs := pflag.NewFlagSet("gh pr-todo cache", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	help := fs.BoolP("help", "h", false, "Display help information")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *help || fs.NArg() == 0 {
+		fmt.Fprintf(color.Output, "%s\n\n", "Manage the on-disk cache of diffs, file contents, and remote config.")
+		fmt.Fprintf(color.Output, "%s\n", output.Bold("USAGE"))
+		fmt.Fprintf(color.Output, "  %s\n\n", "gh pr-todo cache clean")
+		fmt.Fprintf(color.Output, "%s\n", output.Bold("COMMANDS"))
+		fmt.Fprintf(color.Output, "  %s\n\n", "clean  Remove every cached entry")
+		fmt.Fprintf(color.Output, "  %s\n", "Entries are kept per commit, so they never go stale; files at branch refs")
+		fmt.Fprintf(color.Output, "  %s\n", "are revalidated with GitHub before use. The least recently used entries")
+		fmt.Fprintf(color.Output, "  %s\n", "are removed once the cache outgrows --cache-size.")
+		if dir, err := cache.DefaultDir(); err == nil {
+			fmt.Fprintf(color.Output, "  %s\n", "Cache directory: "+dir)
+		}
+		if *help {
+			return 0
+		}
+		return 1
+	}
+	if fs.Arg(0) != "clean" || fs.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n", fs.Arg(fs.NArg()-1))
+		return 1
+	}
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error locating the cache directory:", err)
+		return 1
+	}
+	n, size, err := cache.New(dir, 0).Clean()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error cleaning the cache:", err)
+		return 1
+	}
+	fmt.Fprintf(color.Output, "%s Removed %d cached entries (%.1f MiB) from %s\n", output.Green("✔"), n, float64(size)/(1<<20), dir)
+	return 0
+}
+
+// runSweep checks the open PRs of every repository of an organization, or
+// of a list of repositories, and returns the exit code.
+func runSweep(args []string) int {
+	fs := pflag.NewFlagSet("gh pr-todo sweep", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var (
+		org       string
+		reposFile string
+		export    string
+		timeout   time.Duration
+		limits    = parseLimits{maxMemory: sizeFlag{bytes: defaultMaxMemory}}
+		caching   = cacheOptions{maxSize: sizeFlag{bytes: cache.DefaultMaxSize}}
+		sevFlag   = newSeverityFlag()
+		ignFlag   = newIgnoreFlag()
+	)
+	fs.StringVar(&org, "org", "", "Sweep the repositories of the organization [HOST/]ORG")
+	fs.StringVar(&reposFile, "repos-file", "", "Sweep the repositories listed in FILE, one [HOST/]OWNER/REPO per line, or REPO with --org")
+	fs.StringVar(&export, "export", "", "Also write the report as JSON to FILE; \"-\" writes it to standard output instead of the report")
+	fs.IntVarP(&limits.jobs, "jobs", "j", 0, "Parse up to N changed files in parallel (default: number of CPUs)")
+	fs.Var(&limits.maxFileSize, "max-file-size", "Skip files whose diff is larger than SIZE, such as 10M (default: no limit)")
+	fs.Var(&limits.maxMemory, "max-memory", "Pause reading the diff while SIZE of it waits to be parsed (default: 256M)")
+	fs.DurationVar(&timeout, "timeout", 0, "Give up on the sweep after DURATION, such as 10m (default: no limit)")
+	fs.BoolVar(&caching.disabled, "no-cache", false, "Fetch everything from GitHub without reading or writing the on-disk cache")
+	fs.Var(&caching.maxSize, "cache-size", "Trim the on-disk cache to SIZE after the sweep, such as 1G; 0 means no limit (default: 512M)")
+	fs.Var(sevFlag, "severity", "Override severity for one or more TODO types in every repository. Format: LEVEL=TYPE[,TYPE...]")
+	fs.Var(ignFlag, "ignore", "Ignore specified TODO marker types in every repository (comma-separated, repeatable)")
+	help := fs.BoolP("help", "h", false, "Display help information")
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *help {
+		printSweepUsage(fs)
+		return 0
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n", fs.Arg(0))
+		return 1
+	}
+	if org == "" && reposFile == "" {
+		fmt.Fprintln(os.Stderr, "--org or --repos-file is required")
+		return 1
+	}
+	if limits.jobs < 0 {
+		fmt.Fprintf(os.Stderr, "invalid argument %d for \"--jobs\" flag: must not be negative\n", limits.jobs)
+		return 1
+	}
+	if timeout < 0 {
+		fmt.Fprintf(os.Stderr, "invalid argument %s for \"--timeout\" flag: must not be negative\n", timeout)
+		return 1
+	}
+
+	var repos []string
+	if reposFile != "" {
+		var err error
+		if repos, err = readReposFile(reposFile, org); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	ctx, cancel := newRunContext(timeout)
+	defer cancel()
+	fetcher := ghclient.NewClient()
+	responseCache := caching.open()
+	fetcher.SetCache(responseCache)
+	defer func() {
+		_ = fetcher.Close()
+		_ = responseCache.Trim()
+	}()
+	if reposFile == "" {
+		var err error
+		if repos, err = fetcher.FetchOrgRepos(ctx, org); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			printAuthHint(err)
+			return 1
+		}
+	}
+	if len(repos) == 0 {
+		fmt.Fprintln(os.Stderr, "no repositories to sweep")
+		return 1
+	}
+
+	userConfigDir := ""
+	if dir, err := os.UserConfigDir(); err == nil {
+		userConfigDir = dir
+	}
+	opts := sweep.Options{
+		Org:           org,
+		UserConfigDir: userConfigDir,
+		CLISeverities: sevFlag.assignments,
+		CLIIgnored:    ignFlag.types,
+		Collect: ghclient.CollectOptions{
+			Jobs:        limits.jobs,
+			MaxFileSize: limits.maxFileSize.bytes,
+			MaxMemory:   limits.maxMemory.bytes,
+		},
+	}
+	var sp *spinner.Spinner
+	if export != "-" && !isGitHubActions() {
+		sp = spinner.New(spinner.CharSets[14], 40*time.Millisecond)
+		opts.Progress = func(i, n int, repo string) {
+			sp.Lock()
+			sp.Suffix = fmt.Sprintf(" Sweeping %s (%d/%d)...", repo, i+1, n)
+			sp.Unlock()
+		}
+		sp.Start()
+	}
+	report, err := sweep.Run(ctx, fetcher, repos, opts)
+	if sp != nil {
+		sp.Stop()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		printAuthHint(err)
+		return 1
+	}
+
+	if export == "-" {
+		if err := output.WriteSweepJSON(os.Stdout, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing the export:", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(color.Output, "%s Swept the open PRs of %d repositories\n\n", output.Green("✔"), len(repos))
+	output.PrintSweep(report)
+	if export != "" {
+		if err := writeSweepExport(export, report); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing the export:", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// readReposFile reads the list of repositories to sweep from path.
+func readReposFile(path, org string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	repos, err := sweep.ReadRepos(f, org)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return repos, nil
+}
+
+// writeSweepExport writes the JSON export of a sweep report to path.
+func writeSweepExport(path string, report types.SweepReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := output.WriteSweepJSON(f, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func printSweepUsage(fs *pflag.FlagSet) {
+	fmt.Fprintf(color.Output, "%s\n\n", "Check the open PRs of many repositories into one report by repository and severity.")
+	fmt.Fprintf(color.Output, "%s\n", output.Bold("USAGE"))
+	fmt.Fprintf(color.Output, "  %s\n\n", "gh pr-todo sweep (--org ORG | --repos-file FILE) [flags]")
+	fmt.Fprintf(color.Output, "%s\n", output.Bold("FLAGS"))
+	fmt.Fprintln(color.Output, fs.FlagUsages())
+	fmt.Fprintf(color.Output, "  %s\n", "Every non-archived repository of --org is swept, or with --repos-file only")
+	fmt.Fprintf(color.Output, "  %s\n", "those listed. Each repository is checked with its own config from its")
+	fmt.Fprintf(color.Output, "  %s\n", "default branch, or the global config if it has none; --severity and")
+	fmt.Fprintf(color.Output, "  %s\n", "--ignore apply on top. Repositories that cannot be swept are reported")
+	fmt.Fprintf(color.Output, "  %s\n", "and skipped. The exit code does not depend on the TODOs found.")
+}
+
 func isGitHubActions() bool {
 	v := strings.TrimSpace(os.Getenv("GITHUB_ACTIONS"))
 	ok, err := strconv.ParseBool(v)
@@ -272,10 +761,17 @@ func printUsage() {
 	fmt.Fprintf(color.Output, "%s\n\n", "View TODO-style comments in the PR diff.")
 	fmt.Fprintf(color.Output, "%s\n", output.Bold("USAGE"))
 	fmt.Fprintf(color.Output, "  %s\n", "gh pr-todo [<number> | <url> | <branch>] [flags]")
-	fmt.Fprintf(color.Output, "  %s\n\n", "gh pr-todo init [--repo | --global] [--force]")
+	fmt.Fprintf(color.Output, "  %s\n", "gh pr-todo init [--repo | --global] [--force]")
+	fmt.Fprintf(color.Output, "  %s\n", "gh pr-todo languages")
+	fmt.Fprintf(color.Output, "  %s\n", "gh pr-todo sweep (--org ORG | --repos-file FILE) [--export FILE]")
+	fmt.Fprintf(color.Output, "  %s\n\n", "gh pr-todo cache clean")
 	fmt.Fprintf(color.Output, "%s\n", output.Bold("COMMANDS"))
-	fmt.Fprintf(color.Output, "  %s\n", "init    Create a default config file")
-	fmt.Fprintf(color.Output, "  %s\n\n", "        Run 'gh pr-todo init --help' for details.")
+	fmt.Fprintf(color.Output, "  %s\n", "init       Create a default config file")
+	fmt.Fprintf(color.Output, "  %s\n", "           Run 'gh pr-todo init --help' for details.")
+	fmt.Fprintf(color.Output, "  %s\n", "languages  List supported languages and their comment syntax")
+	fmt.Fprintf(color.Output, "  %s\n", "sweep      Check the open PRs of many repositories, such as an organization's")
+	fmt.Fprintf(color.Output, "  %s\n", "           Run 'gh pr-todo sweep --help' for details.")
+	fmt.Fprintf(color.Output, "  %s\n\n", "cache      Manage the on-disk cache; 'gh pr-todo cache clean' empties it")
 	fmt.Fprintf(color.Output, "%s\n", output.Bold("FLAGS"))
 	maxLen := 0
 	pflag.VisitAll(func(f *pflag.Flag) {
@@ -302,7 +798,11 @@ func printUsage() {
 	fmt.Fprintf(color.Output, "  %s\n", "                 Use --no-ci-fail to disable even if error-level types exist.")
 	fmt.Fprintf(color.Output, "  %s\n", "GITHUB_ACTIONS   When truthy, emits GitHub Actions workflow annotations.")
 	fmt.Fprintf(color.Output, "  %s\n", "                 Implies CI=true; --no-ci-fail suppresses error-level exits.")
-	fmt.Fprintf(color.Output, "  %s\n\n", "                 Only emitted in the default mode; --count and --name-only stay machine-readable.")
+	fmt.Fprintf(color.Output, "  %s\n", "                 Only emitted in the default mode; --count and --name-only stay machine-readable.")
+	fmt.Fprintf(color.Output, "  %s\n", "GH_HOST          Host of repositories given as OWNER/REPO (default: github.com).")
+	fmt.Fprintf(color.Output, "  %s\n", "GH_REPO          Repository to use when --repo is not given, instead of git remotes.")
+	fmt.Fprintf(color.Output, "  %s\n", "GH_TOKEN         Token for github.com, and GH_ENTERPRISE_TOKEN for other hosts.")
+	fmt.Fprintf(color.Output, "  %s\n\n", "                 Defaults to the token gh is logged in with.")
 	fmt.Fprintf(color.Output, "%s\n", output.Bold("OUTPUT MODES"))
 	fmt.Fprintf(color.Output, "  %s\n\n", "If --name-only and --count are both specified, --name-only takes precedence.")
 	fmt.Fprintf(color.Output, "%s\n", output.Bold("SEVERITY OVERRIDES"))
@@ -322,6 +822,13 @@ func printUsage() {
 	fmt.Fprintf(color.Output, "  %s\n", "    notice|warning|error: [TYPE...]")
 	fmt.Fprintf(color.Output, "  %s\n", "  ignore:")
 	fmt.Fprintf(color.Output, "  %s\n", "    - TYPE")
+	fmt.Fprintf(color.Output, "  %s\n", "  markdown:")
+	fmt.Fprintf(color.Output, "  %s\n", "    paragraphs: true|false  # also report plain \"TODO:\" lines in .md/.mdx/.rst")
+	fmt.Fprintf(color.Output, "  %s\n", "  docstrings: true|false    # also report \"TODO:\" lines in docstrings")
+	fmt.Fprintf(color.Output, "  %s\n", "  languages:")
+	fmt.Fprintf(color.Output, "  %s\n", "    GLOB: LANGUAGE          # e.g. Jenkinsfile: groovy (first match wins)")
+	fmt.Fprintf(color.Output, "  %s\n", "  encodings:")
+	fmt.Fprintf(color.Output, "  %s\n", "    GLOB: ENCODING          # e.g. \"legacy/*.cpp\": shift_jis (default: detected)")
 	fmt.Fprintf(color.Output, "  %s\n", "Empty lists are allowed and ignored; a type may not appear under multiple severity levels.")
 	fmt.Fprintf(color.Output, "  %s\n", "Config file paths and precedence (each existing file replaces earlier ones):")
 	fmt.Fprintf(color.Output, "  %s\n", "  1. user config dir/gh-pr-todo/config.yml (global)")
@@ -345,8 +852,15 @@ func printUsage() {
 	fmt.Fprintf(color.Output, "  %s\n\n", "  - NOTE")
 }
 
-func runMain(fetcher ghclient.PRFetcher, repo, pr string, groupBy types.GroupBy, gha bool, policy todotype.Policy) (runResult, error) {
+func runMain(ctx context.Context, fetcher ghclient.PRFetcher, repo, pr string, set prSet, since string, groupBy types.GroupBy, contextLines int, gha bool, settings policyresolve.Settings, limits parseLimits) (runResult, error) {
+	policy := settings.Policy
 	fetchingMsg := " Fetching PR diff..."
+	switch {
+	case set.search:
+		fetchingMsg = fmt.Sprintf(" Fetching diffs of %d PRs...", len(set.prs))
+	case len(set.prs) > 0:
+		fetchingMsg = fmt.Sprintf(" Fetching diffs of %d stacked PRs...", len(set.prs))
+	}
 	var sp *spinner.Spinner
 	if !gha {
 		sp = spinner.New(spinner.CharSets[14], 40*time.Millisecond)
@@ -354,7 +868,16 @@ func runMain(fetcher ghclient.PRFetcher, repo, pr string, groupBy types.GroupBy,
 		sp.Start()
 	}
 
-	todos, err := ghclient.CollectTODOs(fetcher, repo, pr, policy.Types())
+	todos, result, err := collect(ctx, fetcher, repo, pr, set, settings, ghclient.CollectOptions{
+		ContextLines: contextLines,
+		Jobs:         limits.jobs,
+		MaxFileSize:  limits.maxFileSize.bytes,
+		MaxMemory:    limits.maxMemory.bytes,
+		Since:        since,
+		// Blaming costs a request or a git run per file, so it is done only
+		// when its commits are grouped by or shown in annotations.
+		Attribute: groupBy == types.GroupByCommit || groupBy == types.GroupByAuthor || (gha && !set.search),
+	})
 	if sp != nil {
 		sp.Stop()
 	}
@@ -371,27 +894,196 @@ func runMain(fetcher ghclient.PRFetcher, repo, pr string, groupBy types.GroupBy,
 	}
 
 	fmt.Fprintf(color.Output, output.Bold("\nFound %d TODO-style comment(s)\n\n"), len(todos))
-	output.PrintTODOs(todos, groupBy)
-	if gha {
+	switch {
+	case set.search:
+		output.PrintPRs(set.prs, todos, groupBy)
+	case len(set.prs) > 0:
+		output.PrintStack(set.prs, todos, groupBy)
+	default:
+		output.PrintTODOs(todos, groupBy)
+	}
+	// Annotations point at files of the checked-out PR, which the files of
+	// searched PRs are not.
+	if gha && !set.search {
 		output.PrintWorkflowCommands(todos, policy)
 	}
-	return newRunResult(todos, policy), nil
+	return result, nil
+}
+
+// prSet is the PRs a run checks instead of the PR given as an argument:
+// the stack of PRs it belongs to with --stack, or the PRs matching
+// --search or --all-open, of which there may be none.
+type prSet struct {
+	prs    []types.PullRequest
+	search bool
+	// repoSettings holds the settings of each repository of searched PRs,
+	// by prRepository, when they are checked with their own; otherwise
+	// the run's settings apply to every PR.
+	repoSettings map[string]policyresolve.Settings
+}
+
+// prRepository returns the repository of pr in [HOST/]OWNER/REPO form.
+func prRepository(pr types.PullRequest) string {
+	if t := policyresolve.ResolveTarget("", pr.URL); t.Repo != "" {
+		return t.Repo
+	}
+	return pr.Repository
 }
 
-func runCount(fetcher ghclient.PRFetcher, repo, pr string, policy todotype.Policy) (runResult, error) {
-	todos, err := ghclient.CollectTODOs(fetcher, repo, pr, policy.Types())
+// resolveRepoSettings resolves the settings of each repository of the PRs
+// of set once, from the repository's default branch as a sweep does, with
+// the user config and CLI overrides of opts. The PRs of repositories whose
+// config cannot be loaded are left out with a warning; only if no
+// repository's can is the first error returned.
+func resolveRepoSettings(ctx context.Context, fetcher config.RemoteConfigFetcher, set *prSet, opts policyresolve.Options) error {
+	set.repoSettings = make(map[string]policyresolve.Settings)
+	failed := make(map[string]bool)
+	var firstErr error
+	var prs []types.PullRequest
+	for _, pr := range set.prs {
+		repo := prRepository(pr)
+		if _, ok := set.repoSettings[repo]; !ok && !failed[repo] {
+			opts.Target = policyresolve.Target{Repo: repo, UseRemote: true}
+			settings, err := policyresolve.ResolveSettings(ctx, fetcher, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: skipping the PRs of %s: remote config: %v\n", repo, err)
+				failed[repo] = true
+				if firstErr == nil {
+					firstErr = err
+				}
+			} else {
+				set.repoSettings[repo] = settings
+			}
+		}
+		if !failed[repo] {
+			prs = append(prs, pr)
+		}
+	}
+	if len(prs) == 0 && firstErr != nil {
+		return firstErr
+	}
+	set.prs = prs
+	return nil
+}
+
+// withSettings returns opts with the TODO types and config settings of
+// settings.
+func withSettings(opts ghclient.CollectOptions, settings policyresolve.Settings) ghclient.CollectOptions {
+	opts.Types = settings.Policy.Types()
+	opts.MarkdownParagraphs = settings.MarkdownParagraphs
+	opts.Docstrings = settings.Docstrings
+	opts.Languages = settings.Languages
+	opts.Encodings = settings.Encodings
+	return opts
+}
+
+// collect collects the TODOs of the PR, or of each PR of set, with
+// settings, and counts them.
+func collect(ctx context.Context, fetcher ghclient.PRFetcher, repo, pr string, set prSet, settings policyresolve.Settings, opts ghclient.CollectOptions) ([]types.TODO, runResult, error) {
+	opts = withSettings(opts, settings)
+	var todos []types.TODO
+	var err error
+	switch {
+	case set.search && set.repoSettings != nil:
+		return collectByRepo(ctx, fetcher, set, opts)
+	case set.search:
+		todos, err = ghclient.CollectAll(ctx, fetcher, set.prs, opts)
+	case len(set.prs) > 0:
+		todos, err = ghclient.CollectStack(ctx, fetcher, set.prs, opts)
+	default:
+		todos, err = ghclient.Collect(ctx, fetcher, repo, pr, opts)
+	}
+	if err != nil {
+		return nil, runResult{}, err
+	}
+	return todos, newRunResult(todos, settings.Policy), nil
+}
+
+// collectByRepo collects the TODOs of the searched PRs of set repository
+// by repository, each with the settings of its repository, and counts
+// them by its policy. Only if every repository fails is the first error
+// returned; CollectAll has warned about each PR skipped.
+func collectByRepo(ctx context.Context, fetcher ghclient.PRFetcher, set prSet, opts ghclient.CollectOptions) ([]types.TODO, runResult, error) {
+	var repos []string
+	byRepo := make(map[string][]int)
+	for i, pr := range set.prs {
+		repo := prRepository(pr)
+		if _, ok := byRepo[repo]; !ok {
+			repos = append(repos, repo)
+		}
+		byRepo[repo] = append(byRepo[repo], i)
+	}
+
+	var todos []types.TODO
+	var result runResult
+	var firstErr error
+	failed := 0
+	for _, repo := range repos {
+		indexes := byRepo[repo]
+		prs := make([]types.PullRequest, len(indexes))
+		for j, i := range indexes {
+			prs[j] = set.prs[i]
+		}
+		settings := set.repoSettings[repo]
+		found, err := ghclient.CollectAll(ctx, fetcher, prs, withSettings(opts, settings))
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, runResult{}, ctxErr
+		}
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		// Point the TODOs at the PRs of set, which output matches them by.
+		for k := range found {
+			for j := range prs {
+				if found[k].PR == &prs[j] {
+					found[k].PR = &set.prs[indexes[j]]
+					break
+				}
+			}
+		}
+		todos = append(todos, found...)
+		r := newRunResult(found, settings.Policy)
+		result.totalCount += r.totalCount
+		result.ciFailingCount += r.ciFailingCount
+	}
+	if failed > 0 && failed == len(repos) {
+		return nil, runResult{}, firstErr
+	}
+	return todos, result, nil
+}
+
+func runCount(ctx context.Context, fetcher ghclient.PRFetcher, repo, pr string, set prSet, since string, settings policyresolve.Settings, limits parseLimits) (runResult, error) {
+	todos, result, err := collect(ctx, fetcher, repo, pr, set, settings, ghclient.CollectOptions{
+		Jobs:        limits.jobs,
+		MaxFileSize: limits.maxFileSize.bytes,
+		MaxMemory:   limits.maxMemory.bytes,
+		Since:       since,
+	})
 	if err != nil {
 		return runResult{}, err
 	}
 	output.PrintCount(todos)
-	return newRunResult(todos, policy), nil
+	return result, nil
 }
 
-func runNameOnly(fetcher ghclient.PRFetcher, repo, pr string, policy todotype.Policy) (runResult, error) {
-	todos, err := ghclient.CollectTODOs(fetcher, repo, pr, policy.Types())
+func runNameOnly(ctx context.Context, fetcher ghclient.PRFetcher, repo, pr string, set prSet, since string, settings policyresolve.Settings, limits parseLimits) (runResult, error) {
+	todos, result, err := collect(ctx, fetcher, repo, pr, set, settings, ghclient.CollectOptions{
+		Jobs:        limits.jobs,
+		MaxFileSize: limits.maxFileSize.bytes,
+		MaxMemory:   limits.maxMemory.bytes,
+		Since:       since,
+	})
 	if err != nil {
 		return runResult{}, err
 	}
-	output.PrintFileNames(todos)
-	return newRunResult(todos, policy), nil
+	if set.search {
+		output.PrintPRFileNames(set.prs, todos)
+	} else {
+		output.PrintFileNames(todos)
+	}
+	return result, nil
 }
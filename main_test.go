@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"os"
@@ -10,6 +11,8 @@ import (
 	"testing"
 
 	"github.com/Suree33/gh-pr-todo/internal/config"
+	ghclient "github.com/Suree33/gh-pr-todo/internal/github"
+	"github.com/Suree33/gh-pr-todo/internal/policyresolve"
 	"github.com/Suree33/gh-pr-todo/internal/todotype"
 	"github.com/Suree33/gh-pr-todo/pkg/types"
 	"github.com/fatih/color"
@@ -26,16 +29,32 @@ type stubFetcher struct {
 	gotDiffFC string
 }
 
-func (s *stubFetcher) FetchDiff(repo, pr string) (string, error) {
+func (s *stubFetcher) FetchDiff(_ context.Context, repo, pr string) (string, error) {
 	s.gotRepo, s.gotPR = repo, pr
 	return s.diff, s.diffErr
 }
 
-func (s *stubFetcher) FetchChangedFileContents(repo, pr, diff string) (map[string][]byte, error) {
+func (s *stubFetcher) FetchChangedFileContents(_ context.Context, repo, pr, diff string) (map[string][]byte, error) {
 	s.gotDiffFC = diff
 	return s.files, s.filesErr
 }
 
+// blamingFetcher is a stubFetcher that attributes every line to one commit
+// and counts the files it was asked to blame.
+type blamingFetcher struct {
+	*stubFetcher
+	blamed int
+}
+
+func (b *blamingFetcher) Blame(_ context.Context, _, _ string, paths []string) (map[string][]ghclient.BlameRange, error) {
+	b.blamed += len(paths)
+	blame := make(map[string][]ghclient.BlameRange)
+	for _, path := range paths {
+		blame[path] = []ghclient.BlameRange{{Start: 1, End: 100, Commit: types.Commit{SHA: "0123456789abcdef", Subject: "Add bar", Author: "Ada"}}}
+	}
+	return blame, nil
+}
+
 // captureColorOutput redirects color.Output while fn runs and returns whatever
 // was written there. It mutates the global color.Output and color.NoColor, so
 // callers must not use t.Parallel().
@@ -126,6 +145,7 @@ func TestRunMain(t *testing.T) {
 		name        string
 		fetcher     *stubFetcher
 		groupBy     types.GroupBy
+		context     int
 		wantErr     string
 		wantContain []string
 		wantStderr  string
@@ -162,6 +182,20 @@ func TestRunMain(t *testing.T) {
 				"// TODO: add bar",
 			},
 		},
+		{
+			name: "TODOs printed with context",
+			fetcher: &stubFetcher{
+				diff:  sampleDiff,
+				files: map[string][]byte{"foo.go": []byte("package foo\n// TODO: add bar\n")},
+			},
+			context: 1,
+			wantContain: []string{
+				"Found 1 TODO-style comment(s)",
+				"foo.go:2",
+				"1   package foo",
+				"2 + // TODO: add bar",
+			},
+		},
 		{
 			name: "TODOs grouped by file",
 			fetcher: &stubFetcher{
@@ -206,29 +240,29 @@ func TestRunMain(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			var gotErr error
 			out, stdout, gotStderr := captureAll(t, func() {
-				_, gotErr = runMain(tt.fetcher, "o/r", "1", tt.groupBy, false, todotype.DefaultPolicy())
+				_, gotErr = runMain(context.Background(), tt.fetcher, "o/r", "1", prSet{}, "", tt.groupBy, tt.context, false, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 			})
 
 			if tt.wantErr != "" {
 				if gotErr == nil || gotErr.Error() != tt.wantErr {
-					t.Fatalf("runMain() error = %v, expected %q", gotErr, tt.wantErr)
+					t.Fatalf("runMain(context.Background(), ) error = %v, expected %q", gotErr, tt.wantErr)
 				}
 			} else if gotErr != nil {
-				t.Fatalf("runMain() unexpected error = %v", gotErr)
+				t.Fatalf("runMain(context.Background(), ) unexpected error = %v", gotErr)
 			}
 			for _, want := range tt.wantContain {
 				if !strings.Contains(out, want) {
-					t.Fatalf("runMain() output = %q, expected to contain %q", out, want)
+					t.Fatalf("runMain(context.Background(), ) output = %q, expected to contain %q", out, want)
 				}
 			}
 			if tt.wantStderr == "" && gotStderr != "" {
-				t.Fatalf("runMain() unexpected stderr = %q", gotStderr)
+				t.Fatalf("runMain(context.Background(), ) unexpected stderr = %q", gotStderr)
 			}
 			if tt.wantStderr != "" && !strings.Contains(gotStderr, tt.wantStderr) {
-				t.Fatalf("runMain() stderr = %q, expected to contain %q", gotStderr, tt.wantStderr)
+				t.Fatalf("runMain(context.Background(), ) stderr = %q, expected to contain %q", gotStderr, tt.wantStderr)
 			}
 			if stdout != "" {
-				t.Fatalf("runMain() unexpected os.Stdout write = %q", stdout)
+				t.Fatalf("runMain(context.Background(), ) unexpected os.Stdout write = %q", stdout)
 			}
 			if tt.fetcher.gotRepo != "o/r" || tt.fetcher.gotPR != "1" {
 				t.Fatalf("fetcher received repo=%q pr=%q, expected o/r and 1", tt.fetcher.gotRepo, tt.fetcher.gotPR)
@@ -247,19 +281,124 @@ func assertSilentChannels(t *testing.T, label, stdout, stderr string) {
 	}
 }
 
+func TestRunMainAttribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		groupBy     types.GroupBy
+		gha         bool
+		wantBlamed  bool
+		wantContain string
+	}{
+		{name: "default", groupBy: types.GroupByNone},
+		{name: "grouped by file", groupBy: types.GroupByFile},
+		{name: "grouped by commit", groupBy: types.GroupByCommit, wantBlamed: true, wantContain: "Add bar (Ada)"},
+		{name: "grouped by author", groupBy: types.GroupByAuthor, wantBlamed: true, wantContain: "Ada"},
+		{name: "annotations", groupBy: types.GroupByNone, gha: true, wantBlamed: true, wantContain: "title=TODO (0123456 by Ada)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &blamingFetcher{stubFetcher: &stubFetcher{
+				diff:  sampleDiff,
+				files: map[string][]byte{"foo.go": []byte("package foo\n// TODO: add bar\n")},
+			}}
+			var err error
+			out, _, _ := captureAll(t, func() {
+				_, err = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", tt.groupBy, 0, tt.gha, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
+			})
+			if err != nil {
+				t.Fatalf("runMain() error = %v", err)
+			}
+			if got := fetcher.blamed > 0; got != tt.wantBlamed {
+				t.Errorf("blamed %d files, expected blaming = %v", fetcher.blamed, tt.wantBlamed)
+			}
+			if tt.wantContain != "" && !strings.Contains(out, tt.wantContain) {
+				t.Errorf("runMain() output = %q, expected to contain %q", out, tt.wantContain)
+			}
+		})
+	}
+}
+
+// repoConfigFetcher serves the .gh-pr-todo.yml of each repository it has,
+// failing for others.
+type repoConfigFetcher map[string]string
+
+func (f repoConfigFetcher) FetchRemoteConfigRefs(_ context.Context, repo, _ string) (config.RemoteConfigRefs, error) {
+	if _, ok := f[repo]; !ok {
+		return config.RemoteConfigRefs{}, errors.New("repository not found")
+	}
+	return config.RemoteConfigRefs{DefaultRepo: repo, DefaultBranchRef: "main"}, nil
+}
+
+func (f repoConfigFetcher) FetchFileAtRef(_ context.Context, repo, path, _ string) ([]byte, bool, error) {
+	if path != ".gh-pr-todo.yml" || f[repo] == "" {
+		return nil, false, nil
+	}
+	return []byte(f[repo]), true, nil
+}
+
+func TestSearchRepoSettings(t *testing.T) {
+	set := prSet{search: true, prs: []types.PullRequest{
+		{Repository: "o/r", Number: 1, URL: "https://github.com/o/r/pull/1"},
+		{Repository: "x/y", Number: 2, URL: "https://github.com/x/y/pull/2"},
+		{Repository: "x/gone", Number: 3, URL: "https://github.com/x/gone/pull/3"},
+		{Repository: "y", Number: 4, URL: "https://ghe.example.com/x/y/pull/4"},
+	}}
+	configs := repoConfigFetcher{"o/r": "", "x/y": "severity:\n  error: [TODO]\n", "ghe.example.com/x/y": "ignore: [TODO]\n"}
+	var err error
+	stderr := captureStderr(t, func() {
+		err = resolveRepoSettings(context.Background(), configs, &set, policyresolve.Options{})
+	})
+	if err != nil {
+		t.Fatalf("resolveRepoSettings() error = %v", err)
+	}
+	if len(set.prs) != 3 || set.prs[2].Number != 4 {
+		t.Errorf("PRs = %+v, expected the PR of x/gone left out", set.prs)
+	}
+	if !strings.Contains(stderr, "Warning: skipping the PRs of x/gone") {
+		t.Errorf("stderr = %q, expected a warning about x/gone", stderr)
+	}
+
+	fetcher := &stubFetcher{
+		diff:  sampleDiff,
+		files: map[string][]byte{"foo.go": []byte("package foo\n// TODO: add bar\n")},
+	}
+	var result runResult
+	out, _, _ := captureAll(t, func() {
+		result, err = runNameOnly(context.Background(), fetcher, "", "", set, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
+	})
+	if err != nil {
+		t.Fatalf("runNameOnly() error = %v", err)
+	}
+	if want := "o/r#1 foo.go\nx/y#2 foo.go\n"; out != want {
+		t.Errorf("runNameOnly() output = %q, expected %q", out, want)
+	}
+	if result.totalCount != 2 || result.ciFailingCount != 1 {
+		t.Errorf("runNameOnly() = %+v, expected 2 TODOs, one error-level in x/y", result)
+	}
+
+	configs = repoConfigFetcher{}
+	set.repoSettings = nil
+	captureStderr(t, func() {
+		err = resolveRepoSettings(context.Background(), configs, &set, policyresolve.Options{})
+	})
+	if err == nil || err.Error() != "fetching remote refs: repository not found" {
+		t.Errorf("resolveRepoSettings() error = %v, expected the first repository's", err)
+	}
+}
+
 func TestRunCount(t *testing.T) {
 	t.Run("fetch error returned", func(t *testing.T) {
 		fetcher := &stubFetcher{diffErr: errors.New("boom")}
 		var err error
 		out, stdout, stderr := captureAll(t, func() {
-			_, err = runCount(fetcher, "", "", todotype.DefaultPolicy())
+			_, err = runCount(context.Background(), fetcher, "", "", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if err == nil || err.Error() != "boom" {
-			t.Fatalf("runCount() error = %v, expected boom", err)
+			t.Fatalf("runCount(context.Background(), ) error = %v, expected boom", err)
 		}
-		assertSilentChannels(t, "runCount()", stdout, stderr)
+		assertSilentChannels(t, "runCount(context.Background(), )", stdout, stderr)
 		if out != "" {
-			t.Fatalf("runCount() unexpected color.Output = %q", out)
+			t.Fatalf("runCount(context.Background(), ) unexpected color.Output = %q", out)
 		}
 	})
 
@@ -270,14 +409,14 @@ func TestRunCount(t *testing.T) {
 		}
 		var err error
 		out, stdout, stderr := captureAll(t, func() {
-			_, err = runCount(fetcher, "o/r", "1", todotype.DefaultPolicy())
+			_, err = runCount(context.Background(), fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runCount() unexpected error = %v", err)
+			t.Fatalf("runCount(context.Background(), ) unexpected error = %v", err)
 		}
-		assertSilentChannels(t, "runCount()", stdout, stderr)
+		assertSilentChannels(t, "runCount(context.Background(), )", stdout, stderr)
 		if strings.TrimSpace(out) != "1" {
-			t.Fatalf("runCount() output = %q, expected %q", out, "1")
+			t.Fatalf("runCount(context.Background(), ) output = %q, expected %q", out, "1")
 		}
 		if fetcher.gotRepo != "o/r" || fetcher.gotPR != "1" {
 			t.Fatalf("fetcher received repo=%q pr=%q, expected o/r and 1", fetcher.gotRepo, fetcher.gotPR)
@@ -290,14 +429,14 @@ func TestRunNameOnly(t *testing.T) {
 		fetcher := &stubFetcher{diffErr: errors.New("boom")}
 		var err error
 		out, stdout, stderr := captureAll(t, func() {
-			_, err = runNameOnly(fetcher, "", "", todotype.DefaultPolicy())
+			_, err = runNameOnly(context.Background(), fetcher, "", "", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if err == nil || err.Error() != "boom" {
-			t.Fatalf("runNameOnly() error = %v, expected boom", err)
+			t.Fatalf("runNameOnly(context.Background(), ) error = %v, expected boom", err)
 		}
-		assertSilentChannels(t, "runNameOnly()", stdout, stderr)
+		assertSilentChannels(t, "runNameOnly(context.Background(), )", stdout, stderr)
 		if out != "" {
-			t.Fatalf("runNameOnly() unexpected color.Output = %q", out)
+			t.Fatalf("runNameOnly(context.Background(), ) unexpected color.Output = %q", out)
 		}
 	})
 
@@ -308,32 +447,58 @@ func TestRunNameOnly(t *testing.T) {
 		}
 		var err error
 		out, stdout, stderr := captureAll(t, func() {
-			_, err = runNameOnly(fetcher, "o/r", "1", todotype.DefaultPolicy())
+			_, err = runNameOnly(context.Background(), fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runNameOnly() unexpected error = %v", err)
+			t.Fatalf("runNameOnly(context.Background(), ) unexpected error = %v", err)
 		}
-		assertSilentChannels(t, "runNameOnly()", stdout, stderr)
+		assertSilentChannels(t, "runNameOnly(context.Background(), )", stdout, stderr)
 		if strings.TrimSpace(out) != "foo.go" {
-			t.Fatalf("runNameOnly() output = %q, expected %q", out, "foo.go")
+			t.Fatalf("runNameOnly(context.Background(), ) output = %q, expected %q", out, "foo.go")
 		}
 		if fetcher.gotRepo != "o/r" || fetcher.gotPR != "1" {
 			t.Fatalf("fetcher received repo=%q pr=%q, expected o/r and 1", fetcher.gotRepo, fetcher.gotPR)
 		}
 	})
 
+	t.Run("search prints names by PR", func(t *testing.T) {
+		fetcher := &stubFetcher{
+			diff:  sampleDiff,
+			files: map[string][]byte{"foo.go": []byte("package foo\n// TODO: add bar\n")},
+		}
+		set := prSet{search: true, prs: []types.PullRequest{
+			{Repository: "o/r", Number: 1, URL: "https://github.com/o/r/pull/1"},
+			{Repository: "o/other", Number: 2, URL: "https://github.com/o/other/pull/2"},
+		}}
+		policy := todotype.DefaultPolicy().WithSeverity("TODO", todotype.SeverityError)
+		var result runResult
+		var err error
+		out, _, _ := captureAll(t, func() {
+			result, err = runNameOnly(context.Background(), fetcher, "", "", set, "", policyresolve.Settings{Policy: policy}, parseLimits{})
+		})
+		if err != nil {
+			t.Fatalf("runNameOnly(context.Background(), search) unexpected error = %v", err)
+		}
+		if want := "o/r#1 foo.go\no/other#2 foo.go\n"; out != want {
+			t.Fatalf("runNameOnly(context.Background(), search) output = %q, expected %q", out, want)
+		}
+		if result.totalCount != 2 || result.ciFailingCount != 2 {
+			t.Fatalf("runNameOnly(context.Background(), search) = %+v, expected 2 error-level TODOs", result)
+		}
+	})
+
 	t.Run("no TODOs prints nothing", func(t *testing.T) {
 		fetcher := &stubFetcher{diff: "", files: map[string][]byte{}}
 		var err error
 		out, stdout, stderr := captureAll(t, func() {
-			_, err = runNameOnly(fetcher, "", "", todotype.DefaultPolicy())
+			_, err = runNameOnly(context.Background(), fetcher, "", "", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runNameOnly() unexpected error = %v", err)
+			t.Fatalf("runNameOnly(context.Background(), ) unexpected error = %v", err)
 		}
-		assertSilentChannels(t, "runNameOnly()", stdout, stderr)
+		assertSilentChannels(t, "runNameOnly(context.Background(), )", stdout, stderr)
 		if out != "" {
-			t.Fatalf("runNameOnly() output = %q, expected empty", out)
+			t.Fatalf("runNameOnly(context.Background(), ) output = %q, expected empty", out)
 		}
 	})
 }
@@ -452,26 +617,45 @@ func TestRunFunctionsEmitWorkflowCommands(t *testing.T) {
 
 	t.Run("runMain emits when gha=true", func(t *testing.T) {
 		out, _, _ := captureAll(t, func() {
-			_, _ = runMain(fetcher, "o/r", "1", types.GroupByNone, true, todotype.DefaultPolicy())
+			_, _ = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, true, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if !strings.Contains(out, wantLine) {
-			t.Fatalf("runMain(gha=true) output = %q, expected to contain %q", out, wantLine)
+			t.Fatalf("runMain(context.Background(), gha=true) output = %q, expected to contain %q", out, wantLine)
 		}
 	})
 
 	t.Run("runMain does not emit when gha=false", func(t *testing.T) {
 		out, _, _ := captureAll(t, func() {
-			_, _ = runMain(fetcher, "o/r", "1", types.GroupByNone, false, todotype.DefaultPolicy())
+			_, _ = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if strings.Contains(out, "::notice ") || strings.Contains(out, "::warning ") || strings.Contains(out, "::error ") {
-			t.Fatalf("runMain(gha=false) unexpectedly emitted workflow command: %q", out)
+			t.Fatalf("runMain(context.Background(), gha=false) unexpectedly emitted workflow command: %q", out)
+		}
+	})
+
+	t.Run("runMain groups searched PRs without emitting", func(t *testing.T) {
+		set := prSet{search: true, prs: []types.PullRequest{
+			{Repository: "o/r", Number: 1, Title: "Add foo", URL: "https://github.com/o/r/pull/1"},
+		}}
+		var result runResult
+		out, _, _ := captureAll(t, func() {
+			result, _ = runMain(context.Background(), fetcher, "", "", set, "", types.GroupByNone, 0, true, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
+		})
+		if want := "o/r#1 Add foo https://github.com/o/r/pull/1\n  1 TODO-style comment(s)\n\n* foo.go:2\n"; !strings.Contains(out, want) {
+			t.Fatalf("runMain(context.Background(), search) output = %q, expected to contain %q", out, want)
+		}
+		if strings.Contains(out, "::notice ") {
+			t.Fatalf("runMain(context.Background(), search) emitted workflow commands for other PRs: %q", out)
+		}
+		if result.totalCount != 1 {
+			t.Fatalf("runMain(context.Background(), search) totalCount = %d, expected 1", result.totalCount)
 		}
 	})
 
 	t.Run("runCount stdout stays plain", func(t *testing.T) {
 		t.Setenv("GITHUB_ACTIONS", "true")
 		out, _, _ := captureAll(t, func() {
-			_, _ = runCount(fetcher, "o/r", "1", todotype.DefaultPolicy())
+			_, _ = runCount(context.Background(), fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if strings.Contains(out, "::notice") || strings.Contains(out, "::warning") || strings.Contains(out, "::error") {
 			t.Fatalf("runCount must not emit workflow commands; got %q", out)
@@ -481,7 +665,7 @@ func TestRunFunctionsEmitWorkflowCommands(t *testing.T) {
 	t.Run("runNameOnly stdout stays plain", func(t *testing.T) {
 		t.Setenv("GITHUB_ACTIONS", "true")
 		out, _, _ := captureAll(t, func() {
-			_, _ = runNameOnly(fetcher, "o/r", "1", todotype.DefaultPolicy())
+			_, _ = runNameOnly(context.Background(), fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if strings.Contains(out, "::notice") || strings.Contains(out, "::warning") || strings.Contains(out, "::error") {
 			t.Fatalf("runNameOnly must not emit workflow commands; got %q", out)
@@ -575,16 +759,16 @@ index 0000000..1111111 100644
 			var result runResult
 			var gotErr error
 			_, _, _ = captureAll(t, func() {
-				result, gotErr = runMain(tt.fetcher, "o/r", "1", types.GroupByNone, false, todotype.DefaultPolicy())
+				result, gotErr = runMain(context.Background(), tt.fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 			})
 			if gotErr != nil {
-				t.Fatalf("runMain() unexpected error = %v", gotErr)
+				t.Fatalf("runMain(context.Background(), ) unexpected error = %v", gotErr)
 			}
 			if result.totalCount != tt.wantTotal {
-				t.Fatalf("runMain() totalCount = %d, expected %d (ciFailingCount=%d)", result.totalCount, tt.wantTotal, result.ciFailingCount)
+				t.Fatalf("runMain(context.Background(), ) totalCount = %d, expected %d (ciFailingCount=%d)", result.totalCount, tt.wantTotal, result.ciFailingCount)
 			}
 			if result.ciFailingCount != tt.wantCIFailing {
-				t.Fatalf("runMain() ciFailingCount = %d, expected %d", result.ciFailingCount, tt.wantCIFailing)
+				t.Fatalf("runMain(context.Background(), ) ciFailingCount = %d, expected %d", result.ciFailingCount, tt.wantCIFailing)
 			}
 		})
 
@@ -592,16 +776,16 @@ index 0000000..1111111 100644
 			var result runResult
 			var gotErr error
 			_, _, _ = captureAll(t, func() {
-				result, gotErr = runCount(tt.fetcher, "o/r", "1", todotype.DefaultPolicy())
+				result, gotErr = runCount(context.Background(), tt.fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 			})
 			if gotErr != nil {
-				t.Fatalf("runCount() unexpected error = %v", gotErr)
+				t.Fatalf("runCount(context.Background(), ) unexpected error = %v", gotErr)
 			}
 			if result.totalCount != tt.wantTotal {
-				t.Fatalf("runCount() totalCount = %d, expected %d (ciFailingCount=%d)", result.totalCount, tt.wantTotal, result.ciFailingCount)
+				t.Fatalf("runCount(context.Background(), ) totalCount = %d, expected %d (ciFailingCount=%d)", result.totalCount, tt.wantTotal, result.ciFailingCount)
 			}
 			if result.ciFailingCount != tt.wantCIFailing {
-				t.Fatalf("runCount() ciFailingCount = %d, expected %d", result.ciFailingCount, tt.wantCIFailing)
+				t.Fatalf("runCount(context.Background(), ) ciFailingCount = %d, expected %d", result.ciFailingCount, tt.wantCIFailing)
 			}
 		})
 
@@ -609,16 +793,16 @@ index 0000000..1111111 100644
 			var result runResult
 			var gotErr error
 			_, _, _ = captureAll(t, func() {
-				result, gotErr = runNameOnly(tt.fetcher, "o/r", "1", todotype.DefaultPolicy())
+				result, gotErr = runNameOnly(context.Background(), tt.fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 			})
 			if gotErr != nil {
-				t.Fatalf("runNameOnly() unexpected error = %v", gotErr)
+				t.Fatalf("runNameOnly(context.Background(), ) unexpected error = %v", gotErr)
 			}
 			if result.totalCount != tt.wantTotal {
-				t.Fatalf("runNameOnly() totalCount = %d, expected %d (ciFailingCount=%d)", result.totalCount, tt.wantTotal, result.ciFailingCount)
+				t.Fatalf("runNameOnly(context.Background(), ) totalCount = %d, expected %d (ciFailingCount=%d)", result.totalCount, tt.wantTotal, result.ciFailingCount)
 			}
 			if result.ciFailingCount != tt.wantCIFailing {
-				t.Fatalf("runNameOnly() ciFailingCount = %d, expected %d", result.ciFailingCount, tt.wantCIFailing)
+				t.Fatalf("runNameOnly(context.Background(), ) ciFailingCount = %d, expected %d", result.ciFailingCount, tt.wantCIFailing)
 			}
 		})
 	}
@@ -640,12 +824,12 @@ func TestRunFunctionsReturnZeroOnError(t *testing.T) {
 		var result runResult
 		var gotErr error
 		_, _, _ = captureAll(t, func() {
-			result, gotErr = runMain(fetcher, "", "", types.GroupByNone, false, todotype.DefaultPolicy())
+			result, gotErr = runMain(context.Background(), fetcher, "", "", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if gotErr == nil {
-			t.Fatalf("runMain() expected error, got nil")
+			t.Fatalf("runMain(context.Background(), ) expected error, got nil")
 		}
-		checkZero(t, "runMain()", result)
+		checkZero(t, "runMain(context.Background(), )", result)
 	})
 
 	t.Run("runCount", func(t *testing.T) {
@@ -653,12 +837,12 @@ func TestRunFunctionsReturnZeroOnError(t *testing.T) {
 		var result runResult
 		var gotErr error
 		_, _, _ = captureAll(t, func() {
-			result, gotErr = runCount(fetcher, "", "", todotype.DefaultPolicy())
+			result, gotErr = runCount(context.Background(), fetcher, "", "", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if gotErr == nil {
-			t.Fatalf("runCount() expected error, got nil")
+			t.Fatalf("runCount(context.Background(), ) expected error, got nil")
 		}
-		checkZero(t, "runCount()", result)
+		checkZero(t, "runCount(context.Background(), )", result)
 	})
 
 	t.Run("runNameOnly", func(t *testing.T) {
@@ -666,12 +850,12 @@ func TestRunFunctionsReturnZeroOnError(t *testing.T) {
 		var result runResult
 		var gotErr error
 		_, _, _ = captureAll(t, func() {
-			result, gotErr = runNameOnly(fetcher, "", "", todotype.DefaultPolicy())
+			result, gotErr = runNameOnly(context.Background(), fetcher, "", "", prSet{}, "", policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if gotErr == nil {
-			t.Fatalf("runNameOnly() expected error, got nil")
+			t.Fatalf("runNameOnly(context.Background(), ) expected error, got nil")
 		}
-		checkZero(t, "runNameOnly()", result)
+		checkZero(t, "runNameOnly(context.Background(), )", result)
 	})
 }
 
@@ -680,17 +864,7 @@ func TestPrintUsage(t *testing.T) {
 	pflag.CommandLine = pflag.NewFlagSet("gh pr-todo", pflag.ContinueOnError)
 	t.Cleanup(func() { pflag.CommandLine = originalCommandLine })
 
-	var (
-		repo     string
-		nameOnly bool
-		isCount  bool
-		isHelp   bool
-		noCIFail bool
-		groupBy  = types.GroupByNone
-		sevFlag  = newSeverityFlag()
-		ignFlag  = newIgnoreFlag()
-	)
-	registerFlags(pflag.CommandLine, &repo, &nameOnly, &isCount, &isHelp, &noCIFail, &groupBy, sevFlag, ignFlag)
+	registerFlags(pflag.CommandLine, newRunFlags())
 
 	var out string
 	stdout := captureStdout(t, func() {
@@ -721,12 +895,33 @@ func TestPrintUsage(t *testing.T) {
 		"--count",
 		"--help",
 		"--group-by",
+		"--context",
+		"lines of surrounding code",
+		"--jobs",
+		"in parallel",
+		"--max-file-size",
+		"--max-memory",
+		"--base",
+		"--stack",
+		"--since",
+		"--since-last-review",
+		"--search",
+		"--all-open",
+		"--timeout",
+		"--no-cache",
+		"--cache-size",
+		"--no-local-git",
+		"--fetch",
+		"gh pr-todo cache clean",
+		"gh pr-todo sweep (--org ORG | --repos-file FILE) [--export FILE]",
 		"--severity",
 		"--ignore",
 		"--no-ci-fail",
 		"ENVIRONMENT",
 		"CI",
 		"GITHUB_ACTIONS",
+		"GH_HOST",
+		"GH_ENTERPRISE_TOKEN",
 		"error-level TODO is found.",
 		"By default, no built-in",
 		"keyword maps to error-level",
@@ -750,7 +945,7 @@ func TestPrintUsage(t *testing.T) {
 		".github/gh-pr-todo.yml",
 		"remote config replaces global config when found",
 		"--group-by",
-		"Group TODO-style comments by: \"file\" or \"type\"",
+		"Group TODO-style comments by: \"file\", \"type\", \"symbol\" (enclosing function, method, or class), \"commit\", or \"author\"",
 		"remote default branch config",
 		"remote PR base branch config",
 		"remote PR head branch config",
@@ -796,6 +991,33 @@ func TestPrintInitUsage(t *testing.T) {
 	}
 }
 
+func TestRunSweepArguments(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantOutput string
+		wantStderr string
+	}{
+		{name: "help", args: []string{"--help"}, wantCode: 0, wantOutput: "gh pr-todo sweep (--org ORG | --repos-file FILE) [flags]"},
+		{name: "no repositories", args: nil, wantCode: 1, wantStderr: "--org or --repos-file is required"},
+		{name: "unexpected argument", args: []string{"--org", "acme", "api"}, wantCode: 1, wantStderr: `unexpected argument "api"`},
+		{name: "missing repos file", args: []string{"--repos-file", filepath.Join(t.TempDir(), "repos.txt")}, wantCode: 1, wantStderr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			out, _, stderr := captureAll(t, func() { code = runSweep(tt.args) })
+			if code != tt.wantCode {
+				t.Errorf("runSweep() = %d, expected %d", code, tt.wantCode)
+			}
+			if !strings.Contains(out, tt.wantOutput) || !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("runSweep() output = %q, stderr = %q, expected %q and %q", out, stderr, tt.wantOutput, tt.wantStderr)
+			}
+		})
+	}
+}
+
 func TestSeverityFlagInvalid(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -951,10 +1173,10 @@ func TestSeverityOverrideAffectsCIFailCount(t *testing.T) {
 		var result runResult
 		var err error
 		_, _, _ = captureAll(t, func() {
-			result, err = runMain(fetcher, "o/r", "1", types.GroupByNone, false, todotype.DefaultPolicy())
+			result, err = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: todotype.DefaultPolicy()}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error = %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error = %v", err)
 		}
 		if result.ciFailingCount != 0 {
 			t.Fatalf("ciFailingCount = %d, want 0", result.ciFailingCount)
@@ -967,46 +1189,46 @@ func TestSeverityOverrideAffectsCIFailCount(t *testing.T) {
 		var result runResult
 		var err error
 		_, _, _ = captureAll(t, func() {
-			result, err = runMain(fetcher, "o/r", "1", types.GroupByNone, false, policy)
+			result, err = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error = %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error = %v", err)
 		}
 		if result.ciFailingCount != 1 {
-			t.Fatalf("runMain() ciFailingCount = %d, want 1 (TODO overridden to error)", result.ciFailingCount)
+			t.Fatalf("runMain(context.Background(), ) ciFailingCount = %d, want 1 (TODO overridden to error)", result.ciFailingCount)
 		}
 		if result.totalCount != 1 {
-			t.Fatalf("runMain() totalCount = %d, want 1", result.totalCount)
+			t.Fatalf("runMain(context.Background(), ) totalCount = %d, want 1", result.totalCount)
 		}
 
 		var countResult runResult
 		countOut, countStdout, countStderr := captureAll(t, func() {
-			countResult, err = runCount(fetcher, "o/r", "1", policy)
+			countResult, err = runCount(context.Background(), fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runCount() unexpected error = %v", err)
+			t.Fatalf("runCount(context.Background(), ) unexpected error = %v", err)
 		}
-		assertSilentChannels(t, "runCount()", countStdout, countStderr)
+		assertSilentChannels(t, "runCount(context.Background(), )", countStdout, countStderr)
 		if strings.TrimSpace(countOut) != "1" {
-			t.Fatalf("runCount() output = %q, want %q", countOut, "1")
+			t.Fatalf("runCount(context.Background(), ) output = %q, want %q", countOut, "1")
 		}
 		if countResult.ciFailingCount != 1 {
-			t.Fatalf("runCount() ciFailingCount = %d, want 1 (TODO overridden to error)", countResult.ciFailingCount)
+			t.Fatalf("runCount(context.Background(), ) ciFailingCount = %d, want 1 (TODO overridden to error)", countResult.ciFailingCount)
 		}
 
 		var nameOnlyResult runResult
 		nameOnlyOut, nameOnlyStdout, nameOnlyStderr := captureAll(t, func() {
-			nameOnlyResult, err = runNameOnly(fetcher, "o/r", "1", policy)
+			nameOnlyResult, err = runNameOnly(context.Background(), fetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runNameOnly() unexpected error = %v", err)
+			t.Fatalf("runNameOnly(context.Background(), ) unexpected error = %v", err)
 		}
-		assertSilentChannels(t, "runNameOnly()", nameOnlyStdout, nameOnlyStderr)
+		assertSilentChannels(t, "runNameOnly(context.Background(), )", nameOnlyStdout, nameOnlyStderr)
 		if strings.TrimSpace(nameOnlyOut) != "foo.go" {
-			t.Fatalf("runNameOnly() output = %q, want %q", nameOnlyOut, "foo.go")
+			t.Fatalf("runNameOnly(context.Background(), ) output = %q, want %q", nameOnlyOut, "foo.go")
 		}
 		if nameOnlyResult.ciFailingCount != 1 {
-			t.Fatalf("runNameOnly() ciFailingCount = %d, want 1 (TODO overridden to error)", nameOnlyResult.ciFailingCount)
+			t.Fatalf("runNameOnly(context.Background(), ) ciFailingCount = %d, want 1 (TODO overridden to error)", nameOnlyResult.ciFailingCount)
 		}
 	})
 
@@ -1015,10 +1237,10 @@ func TestSeverityOverrideAffectsCIFailCount(t *testing.T) {
 		var result runResult
 		var err error
 		_, _, _ = captureAll(t, func() {
-			result, err = runMain(fetcher, "o/r", "1", types.GroupByNone, false, policy)
+			result, err = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error = %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error = %v", err)
 		}
 		if result.ciFailingCount != 0 {
 			t.Fatalf("ciFailingCount = %d, want 0 (warning should not fail CI)", result.ciFailingCount)
@@ -1036,10 +1258,10 @@ func TestSeverityOverrideAffectsCIFailCount(t *testing.T) {
 		var result runResult
 		var err error
 		_, _, _ = captureAll(t, func() {
-			result, err = runMain(fetcher, "o/r", "1", types.GroupByNone, false, policy)
+			result, err = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error = %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error = %v", err)
 		}
 		if result.ciFailingCount != 1 {
 			t.Fatalf("ciFailingCount = %d, want 1 (NOTE overridden to error)", result.ciFailingCount)
@@ -1059,7 +1281,7 @@ func TestSeverityOverrideAffectsWorkflowAnnotation(t *testing.T) {
 	t.Run("TODO overridden to warning → ::warning annotation", func(t *testing.T) {
 		policy := todotype.DefaultPolicy().WithSeverity("TODO", todotype.SeverityWarning)
 		out, _, _ := captureAll(t, func() {
-			_, _ = runMain(fetcher, "o/r", "1", types.GroupByNone, true, policy)
+			_, _ = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, true, policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		wantLine := "::warning file=foo.go,line=2,title=TODO::// TODO: add bar"
 		if !strings.Contains(out, wantLine) {
@@ -1073,7 +1295,7 @@ func TestSeverityOverrideAffectsWorkflowAnnotation(t *testing.T) {
 	t.Run("TODO overridden to error → ::error annotation", func(t *testing.T) {
 		policy := todotype.DefaultPolicy().WithSeverity("TODO", todotype.SeverityError)
 		out, _, _ := captureAll(t, func() {
-			_, _ = runMain(fetcher, "o/r", "1", types.GroupByNone, true, policy)
+			_, _ = runMain(context.Background(), fetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, true, policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		wantLine := "::error file=foo.go,line=2,title=TODO::// TODO: add bar"
 		if !strings.Contains(out, wantLine) {
@@ -1171,6 +1393,44 @@ func TestIgnoreFlagParsing(t *testing.T) {
 	})
 }
 
+func TestSizeFlagParsing(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "0", want: 0},
+		{value: "4096", want: 4096},
+		{value: "512K", want: 512 << 10},
+		{value: "10mb", want: 10 << 20},
+		{value: " 2GiB ", want: 2 << 30},
+		{value: "100B", want: 100},
+		{value: "", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "1.5M", wantErr: true},
+		{value: "10T", wantErr: true},
+		{value: "9999999999999G", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var f sizeFlag
+			err := f.Set(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("sizeFlag.Set(%q) expected error, got %d", tt.value, f.bytes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sizeFlag.Set(%q) unexpected error: %v", tt.value, err)
+			}
+			if f.bytes != tt.want {
+				t.Fatalf("sizeFlag.Set(%q) = %d, want %d", tt.value, f.bytes, tt.want)
+			}
+		})
+	}
+}
+
 func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 	mixedFetcher := &stubFetcher{
 		diff: mixedDiff,
@@ -1185,10 +1445,10 @@ func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 		var result runResult
 		var err error
 		out, _, _ := captureAll(t, func() {
-			result, err = runMain(mixedFetcher, "o/r", "1", types.GroupByNone, false, ignoreNOTE)
+			result, err = runMain(context.Background(), mixedFetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: ignoreNOTE}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error: %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error: %v", err)
 		}
 		if strings.Contains(out, "NOTE") {
 			t.Fatalf("output should not contain NOTE when ignored: %q", out)
@@ -1205,13 +1465,13 @@ func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 		var result runResult
 		var err error
 		out, _, _ := captureAll(t, func() {
-			result, err = runCount(mixedFetcher, "o/r", "1", ignoreNOTE)
+			result, err = runCount(context.Background(), mixedFetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: ignoreNOTE}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runCount() unexpected error: %v", err)
+			t.Fatalf("runCount(context.Background(), ) unexpected error: %v", err)
 		}
 		if strings.TrimSpace(out) != "1" {
-			t.Fatalf("runCount() output = %q, want %q", out, "1")
+			t.Fatalf("runCount(context.Background(), ) output = %q, want %q", out, "1")
 		}
 		if result.totalCount != 1 {
 			t.Fatalf("totalCount = %d, want 1", result.totalCount)
@@ -1222,13 +1482,13 @@ func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 		// Both markers are in foo.go, so file should still appear
 		var err error
 		out, _, _ := captureAll(t, func() {
-			_, err = runNameOnly(mixedFetcher, "o/r", "1", ignoreNOTE)
+			_, err = runNameOnly(context.Background(), mixedFetcher, "o/r", "1", prSet{}, "", policyresolve.Settings{Policy: ignoreNOTE}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runNameOnly() unexpected error: %v", err)
+			t.Fatalf("runNameOnly(context.Background(), ) unexpected error: %v", err)
 		}
 		if strings.TrimSpace(out) != "foo.go" {
-			t.Fatalf("runNameOnly() output = %q, want %q", out, "foo.go")
+			t.Fatalf("runNameOnly(context.Background(), ) output = %q, want %q", out, "foo.go")
 		}
 	})
 
@@ -1236,10 +1496,10 @@ func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 		var result runResult
 		var err error
 		out, _, _ := captureAll(t, func() {
-			result, err = runMain(mixedFetcher, "o/r", "1", types.GroupByType, false, ignoreNOTE)
+			result, err = runMain(context.Background(), mixedFetcher, "o/r", "1", prSet{}, "", types.GroupByType, 0, false, policyresolve.Settings{Policy: ignoreNOTE}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error: %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error: %v", err)
 		}
 		if strings.Contains(out, "[NOTE]") {
 			t.Fatalf("group-by output should not contain [NOTE] section: %q", out)
@@ -1255,10 +1515,10 @@ func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 	t.Run("workflow annotations exclude ignored NOTE", func(t *testing.T) {
 		var err error
 		out, _, _ := captureAll(t, func() {
-			_, err = runMain(mixedFetcher, "o/r", "1", types.GroupByNone, true, ignoreNOTE)
+			_, err = runMain(context.Background(), mixedFetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, true, policyresolve.Settings{Policy: ignoreNOTE}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error: %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error: %v", err)
 		}
 		if strings.Contains(out, "::notice file=foo.go,line=3,title=NOTE") {
 			t.Fatalf("workflow output should not contain NOTE annotation: %q", out)
@@ -1274,10 +1534,10 @@ func TestIgnoredTypesExcludeFromOutput(t *testing.T) {
 		var result runResult
 		var err error
 		_, _, _ = captureAll(t, func() {
-			result, err = runMain(mixedFetcher, "o/r", "1", types.GroupByNone, false, policy)
+			result, err = runMain(context.Background(), mixedFetcher, "o/r", "1", prSet{}, "", types.GroupByNone, 0, false, policyresolve.Settings{Policy: policy}, parseLimits{})
 		})
 		if err != nil {
-			t.Fatalf("runMain() unexpected error: %v", err)
+			t.Fatalf("runMain(context.Background(), ) unexpected error: %v", err)
 		}
 		if result.ciFailingCount != 0 {
 			t.Fatalf("ciFailingCount = %d, want 0 (NOTE ignored overrides error severity)", result.ciFailingCount)
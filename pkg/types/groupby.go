@@ -8,9 +8,12 @@ import (
 type GroupBy string
 
 const (
-	GroupByNone GroupBy = ""
-	GroupByFile GroupBy = "file"
-	GroupByType GroupBy = "type"
+	GroupByNone   GroupBy = ""
+	GroupByFile   GroupBy = "file"
+	GroupByType   GroupBy = "type"
+	GroupBySymbol GroupBy = "symbol"
+	GroupByCommit GroupBy = "commit"
+	GroupByAuthor GroupBy = "author"
 )
 
 func (g *GroupBy) Set(s string) error {
@@ -21,8 +24,17 @@ func (g *GroupBy) Set(s string) error {
 	case string(GroupByType):
 		*g = GroupByType
 		return nil
+	case string(GroupBySymbol):
+		*g = GroupBySymbol
+		return nil
+	case string(GroupByCommit):
+		*g = GroupByCommit
+		return nil
+	case string(GroupByAuthor):
+		*g = GroupByAuthor
+		return nil
 	default:
-		return fmt.Errorf("invalid value %q for --group-by (allowed: \"file\", \"type\")", s)
+		return fmt.Errorf("invalid value %q for --group-by (allowed: \"file\", \"type\", \"symbol\", \"commit\", \"author\")", s)
 	}
 }
 
@@ -18,7 +18,11 @@ func TestGroupBy_Set(t *testing.T) {
 		{name: "type lowercase", input: "type", want: GroupByType},
 		{name: "file mixed case", input: "FILE", want: GroupByFile},
 		{name: "type mixed case", input: "Type", want: GroupByType},
-		{name: "invalid", input: "bogus", wantErr: true, wantErrParts: []string{"bogus", "--group-by", `"file"`, `"type"`}},
+		{name: "symbol lowercase", input: "symbol", want: GroupBySymbol},
+		{name: "symbol mixed case", input: "Symbol", want: GroupBySymbol},
+		{name: "commit lowercase", input: "commit", want: GroupByCommit},
+		{name: "author mixed case", input: "Author", want: GroupByAuthor},
+		{name: "invalid", input: "bogus", wantErr: true, wantErrParts: []string{"bogus", "--group-by", `"file"`, `"type"`, `"symbol"`, `"commit"`, `"author"`}},
 		{name: "empty", input: "", wantErr: true, wantErrParts: []string{`""`, "--group-by", `"file"`, `"type"`}},
 		{name: "invalid does not mutate existing value", initial: GroupByFile, input: "bogus", want: GroupByFile, wantErr: true, wantErrParts: []string{"bogus", "--group-by", `"file"`, `"type"`}},
 	}
@@ -58,6 +62,7 @@ func TestGroupBy_String(t *testing.T) {
 		{name: "none", g: GroupByNone, want: ""},
 		{name: "file", g: GroupByFile, want: "file"},
 		{name: "type", g: GroupByType, want: "type"},
+		{name: "symbol", g: GroupBySymbol, want: "symbol"},
 	}
 
 	for _, tt := range tests {
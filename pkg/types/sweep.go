@@ -0,0 +1,31 @@
+package types
+
+// SweepReport is the report of a sweep of the open PRs of many
+// repositories, as exported by "gh pr-todo sweep --export".
+type SweepReport struct {
+	// The organization swept (empty when the repositories were listed in a file)
+	Org string `json:"org,omitempty"`
+	// The repositories swept, in the order they were given
+	Repositories []RepositoryReport `json:"repositories"`
+}
+
+// RepositoryReport is the part of a SweepReport for one repository.
+type RepositoryReport struct {
+	// The repository in [HOST/]OWNER/REPO form
+	Repository string `json:"repository"`
+	// The open PRs checked
+	PRs []PullRequest `json:"prs"`
+	// TODO-style comments found, each with its PR, in the order of PRs
+	TODOs []Finding `json:"todos"`
+	// Number of TODO-style comments by severity
+	Counts map[string]int `json:"counts"`
+	// Why the repository could not be swept (empty if it was)
+	Error string `json:"error,omitempty"`
+}
+
+// Finding is a TODO with the severity the repository's config gives its type.
+type Finding struct {
+	TODO
+	// notice, warning, or error
+	Severity string `json:"severity"`
+}
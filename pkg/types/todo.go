@@ -3,11 +3,61 @@ package types
 
 // TODO represents a TODO comment found in a diff
 type TODO struct {
-	Filename string
+	Filename string `json:"filename"`
 	// The line number in the file
-	Line int
+	Line int `json:"line"`
 	// The whole comment line
-	Comment string
+	Comment string `json:"comment"`
 	// TODO, FIXME, HACK, NOTE, etc.
-	Type string
+	Type string `json:"type"`
+	// Enclosing function, method, or class, e.g. "(*Client).FetchDiff" (empty if unknown)
+	Symbol string `json:"symbol,omitempty"`
+	// Location within a Jupyter notebook cell (nil for other files); Line is then the best-effort notebook JSON line
+	Notebook *NotebookLocation `json:"notebook,omitempty"`
+	// Surrounding source lines, including the TODO line itself (empty unless requested)
+	Context []ContextLine `json:"context,omitempty"`
+	// PR the TODO was found in when checking several PRs, such as a stack of PRs (nil otherwise)
+	PR *PullRequest `json:"pr,omitempty"`
+	// Commit that last changed the TODO line at the PR head (nil if unknown)
+	Commit *Commit `json:"commit,omitempty"`
+}
+
+// Commit identifies the commit a TODO is attributed to.
+type Commit struct {
+	// The full commit SHA
+	SHA string `json:"sha"`
+	// The first line of the commit message
+	Subject string `json:"subject"`
+	// The commit author's name
+	Author string `json:"author"`
+}
+
+// ContextLine is a single line of head file content surrounding a TODO.
+type ContextLine struct {
+	// The line number in the file
+	Line int `json:"line"`
+	// The line text without its trailing newline
+	Text string `json:"text"`
+	// True if the line was added in the diff
+	Added bool `json:"added"`
+}
+
+// NotebookLocation locates a TODO inside a Jupyter notebook.
+type NotebookLocation struct {
+	// The 1-based position of the cell in the notebook
+	Cell int `json:"cell"`
+	// The 1-based line within the cell source
+	Line int `json:"line"`
+}
+
+// PullRequest identifies a pull request by number, with its title, URL, and
+// base and head branches.
+type PullRequest struct {
+	// The repository in OWNER/REPO form (empty when implied)
+	Repository  string `json:"repository,omitempty"`
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	BaseRefName string `json:"baseRefName"`
+	HeadRefName string `json:"headRefName"`
 }